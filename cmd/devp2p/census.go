@@ -0,0 +1,454 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// censusSet is the census.json file format. It holds the result of probing
+// each node as a JSON object.
+type censusSet map[enode.ID]censusNode
+
+type censusNode struct {
+	N         *enode.Node `json:"record"`
+	Reachable bool        `json:"reachable"`
+	Error     string      `json:"error,omitempty"`
+
+	// These are reported by the node in the devp2p handshake.
+	Name    string   `json:"name,omitempty"`
+	Client  string   `json:"client,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Caps    []string `json:"caps,omitempty"`
+
+	// These are reported by the node in the eth status handshake.
+	NetworkID uint64 `json:"networkId,omitempty"`
+	ForkHash  string `json:"forkHash,omitempty"`
+	ForkNext  uint64 `json:"forkNext,omitempty"`
+
+	LastCheck time.Time `json:"lastCheck"`
+}
+
+func loadCensusJSON(file string) censusSet {
+	var set censusSet
+	if err := common.LoadJSON(file, &set); err != nil {
+		exit(err)
+	}
+	return set
+}
+
+func writeCensusJSON(file string, set censusSet) {
+	data, err := json.MarshalIndent(set, "", jsonIndent)
+	if err != nil {
+		exit(err)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		exit(err)
+	}
+}
+
+// ethVersion returns the highest eth protocol version advertised by the node,
+// or zero if it doesn't support eth.
+func (n *censusNode) ethVersion() uint64 {
+	var version uint64
+	for _, c := range n.Caps {
+		if !strings.HasPrefix(c, eth.ProtocolName+"/") {
+			continue
+		}
+		if v, err := strconv.ParseUint(c[len(eth.ProtocolName)+1:], 10, 64); err == nil && v > version {
+			version = v
+		}
+	}
+	return version
+}
+
+// parseClientName splits a devp2p client identifier like
+// "Geth/v1.10.21-stable-67109427/linux-amd64/go1.18.4" into the client name and
+// version. The version is the first component starting with 'v' and a digit, so
+// custom identities like "Geth/mynode/v1.10.21/..." are handled as well.
+func parseClientName(name string) (client, version string) {
+	parts := strings.Split(name, "/")
+	client = parts[0]
+	for _, p := range parts[1:] {
+		if len(p) > 1 && p[0] == 'v' && p[1] >= '0' && p[1] <= '9' {
+			version = p
+			break
+		}
+	}
+	return client, version
+}
+
+// censusProber performs the devp2p and eth handshakes with nodes and records
+// what they report about themselves.
+type censusProber struct {
+	key     *ecdsa.PrivateKey
+	dialer  p2p.NodeDialer
+	timeout time.Duration
+}
+
+// tcpNodeDialer dials the TCP endpoint of nodes.
+type tcpNodeDialer struct{}
+
+func (tcpNodeDialer) Dial(ctx context.Context, n *enode.Node) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()))
+}
+
+// probeAll probes the given nodes, running up to parallel probes at once.
+func (p *censusProber) probeAll(nodes []*enode.Node, parallel int) censusSet {
+	var (
+		result = make(censusSet, len(nodes))
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		slots  = make(chan struct{}, parallel)
+	)
+	for _, n := range nodes {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(n *enode.Node) {
+			defer func() { <-slots; wg.Done() }()
+			cn := p.probe(n)
+			log.Debug("Probed node", "id", n.ID(), "reachable", cn.Reachable, "name", cn.Name, "err", cn.Error)
+			mu.Lock()
+			result[n.ID()] = cn
+			mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	return result
+}
+
+// probe dials a single node.
+func (p *censusProber) probe(n *enode.Node) censusNode {
+	result := censusNode{N: n, LastCheck: truncNow()}
+	if err := p.handshake(n, &result); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (p *censusProber) handshake(n *enode.Node, result *censusNode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	fd, err := p.dialer.Dial(ctx, n)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	fd.SetDeadline(time.Now().Add(p.timeout))
+
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	if _, err := conn.Handshake(p.key); err != nil {
+		return err
+	}
+	result.Reachable = true
+
+	// Exchange the devp2p handshake. Writing happens concurrently because the
+	// remote end writes its own handshake at the same time.
+	our := ethtest.Hello{
+		Version: 5,
+		Name:    "devp2p-census",
+		ID:      crypto.FromECDSAPub(&p.key.PublicKey)[1:],
+	}
+	for _, v := range eth.ProtocolVersions {
+		our.Caps = append(our.Caps, p2p.Cap{Name: eth.ProtocolName, Version: v})
+	}
+	werr := make(chan error, 1)
+	go func() {
+		payload, _ := rlp.EncodeToBytes(&our)
+		_, err := conn.Write(0x00, payload)
+		werr <- err
+	}()
+	var their ethtest.Hello
+	if err := readCensusMsg(conn, 0x00, &their); err != nil {
+		return err
+	}
+	if err := <-werr; err != nil {
+		return err
+	}
+	result.Name = their.Name
+	result.Client, result.Version = parseClientName(their.Name)
+	for _, c := range their.Caps {
+		result.Caps = append(result.Caps, c.String())
+	}
+	conn.SetSnappy(their.Version >= 5)
+
+	// If the node speaks eth, read its status. All our capabilities are eth,
+	// so the negotiated eth protocol starts right after the base protocol.
+	if result.ethVersion() >= eth.ETH66 {
+		var status eth.StatusPacket
+		if err := readCensusMsg(conn, 0x10+eth.StatusMsg, &status); err != nil {
+			return fmt.Errorf("eth status: %v", err)
+		}
+		result.NetworkID = status.NetworkID
+		result.ForkHash = fmt.Sprintf("%#x", status.ForkID.Hash)
+		result.ForkNext = status.ForkID.Next
+	}
+	payload, _ := rlp.EncodeToBytes([]p2p.DiscReason{p2p.DiscRequested})
+	conn.Write(0x01, payload)
+	return nil
+}
+
+// readCensusMsg reads messages until one with the wanted code arrives, replying
+// to pings on the way.
+func readCensusMsg(conn *rlpx.Conn, want uint64, val interface{}) error {
+	for {
+		code, data, _, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		switch {
+		case code == want:
+			return rlp.DecodeBytes(data, val)
+		case code == 0x01:
+			var reason []p2p.DiscReason
+			if rlp.DecodeBytes(data, &reason); len(reason) > 0 {
+				return fmt.Errorf("disconnected: %v", reason[0])
+			}
+			return errors.New("disconnected")
+		case code == 0x02:
+			conn.Write(0x03, []byte{0xc0})
+		}
+	}
+}
+
+// censusReport is the aggregated view of a census.
+type censusReport struct {
+	Time          time.Time      `json:"time"`
+	Nodes         int            `json:"nodes"`
+	Reachable     int            `json:"reachable"`
+	Responsive    int            `json:"responsive"`
+	Clients       []censusShare  `json:"clients"`
+	Versions      []censusShare  `json:"versions"`
+	EthVersions   []censusShare  `json:"ethVersions"`
+	Networks      []censusShare  `json:"networks"`
+	ForkIDs       []censusShare  `json:"forkIds"`
+	ForkReadiness []censusShare  `json:"forkReadiness"`
+	Changes       *censusChanges `json:"changes,omitempty"`
+}
+
+// censusShare is the number of responsive nodes with a certain property.
+type censusShare struct {
+	Name  string  `json:"name"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// censusChanges summarizes the differences to a previous census.
+type censusChanges struct {
+	Added        int            `json:"added"`
+	Removed      int            `json:"removed"`
+	NowReachable int            `json:"nowReachable"`
+	Unreachable  int            `json:"unreachable"`
+	Updates      []censusUpdate `json:"updates,omitempty"`
+	Clients      []censusDelta  `json:"clients,omitempty"`
+}
+
+// censusUpdate is a node which changed its client software or version.
+type censusUpdate struct {
+	ID   enode.ID `json:"id"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+}
+
+// censusDelta is the change in the number of nodes running a client.
+type censusDelta struct {
+	Name  string `json:"name"`
+	Delta int    `json:"delta"`
+}
+
+// makeCensusReport aggregates the census. If prev is non-nil, the changes since
+// the previous census are included as well.
+func makeCensusReport(set, prev censusSet) *censusReport {
+	var (
+		report        = &censusReport{Time: truncNow(), Nodes: len(set)}
+		clients       = make(map[string]int)
+		versions      = make(map[string]int)
+		ethVersions   = make(map[string]int)
+		networks      = make(map[string]int)
+		forkIDs       = make(map[string]int)
+		forkReadiness = make(map[string]int)
+	)
+	for _, n := range set {
+		if n.Reachable {
+			report.Reachable++
+		}
+		if n.Name == "" {
+			continue
+		}
+		report.Responsive++
+		clients[n.Client]++
+		versions[n.Client+"/"+n.Version]++
+		if v := n.ethVersion(); v > 0 {
+			ethVersions[fmt.Sprintf("%s/%d", eth.ProtocolName, v)]++
+		} else {
+			ethVersions["none"]++
+		}
+		if n.ForkHash != "" {
+			networks[strconv.FormatUint(n.NetworkID, 10)]++
+			forkIDs[fmt.Sprintf("%s/%d", n.ForkHash, n.ForkNext)]++
+			if n.ForkNext == 0 {
+				forkReadiness["no fork scheduled"]++
+			} else {
+				forkReadiness[fmt.Sprintf("next fork at %d", n.ForkNext)]++
+			}
+		}
+	}
+	report.Clients = makeShares(clients, report.Responsive)
+	report.Versions = makeShares(versions, report.Responsive)
+	report.EthVersions = makeShares(ethVersions, report.Responsive)
+	report.Networks = makeShares(networks, report.Responsive)
+	report.ForkIDs = makeShares(forkIDs, report.Responsive)
+	report.ForkReadiness = makeShares(forkReadiness, report.Responsive)
+	if prev != nil {
+		report.Changes = diffCensus(prev, set)
+	}
+	return report
+}
+
+// makeShares turns a histogram into a list of shares, sorted by count.
+func makeShares(counts map[string]int, total int) []censusShare {
+	shares := make([]censusShare, 0, len(counts))
+	for name, count := range counts {
+		shares = append(shares, censusShare{Name: name, Count: count, Share: float64(count) / float64(total)})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Count != shares[j].Count {
+			return shares[i].Count > shares[j].Count
+		}
+		return shares[i].Name < shares[j].Name
+	})
+	return shares
+}
+
+// diffCensus computes the changes between two census runs.
+func diffCensus(prev, next censusSet) *censusChanges {
+	var (
+		changes = new(censusChanges)
+		clients = make(map[string]int)
+	)
+	for id, n := range next {
+		if n.Name != "" {
+			clients[n.Client]++
+		}
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			changes.Added++
+		case n.Reachable && !old.Reachable:
+			changes.NowReachable++
+		case !n.Reachable && old.Reachable:
+			changes.Unreachable++
+		}
+		if ok && old.Name != "" && n.Name != "" && (old.Client != n.Client || old.Version != n.Version) {
+			changes.Updates = append(changes.Updates, censusUpdate{
+				ID:   id,
+				From: old.Client + "/" + old.Version,
+				To:   n.Client + "/" + n.Version,
+			})
+		}
+	}
+	for id, n := range prev {
+		if n.Name != "" {
+			clients[n.Client]--
+		}
+		if _, ok := next[id]; !ok {
+			changes.Removed++
+		}
+	}
+	for name, delta := range clients {
+		if delta != 0 {
+			changes.Clients = append(changes.Clients, censusDelta{Name: name, Delta: delta})
+		}
+	}
+	sort.Slice(changes.Updates, func(i, j int) bool {
+		return bytes.Compare(changes.Updates[i].ID[:], changes.Updates[j].ID[:]) < 0
+	})
+	sort.Slice(changes.Clients, func(i, j int) bool { return changes.Clients[i].Name < changes.Clients[j].Name })
+	return changes
+}
+
+// writeJSON writes the report as JSON.
+func (r *censusReport) writeJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeCSV writes the report as CSV, one row per share with the columns
+// category, name, count and share.
+func (r *censusReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"category", "name", "count", "share"})
+	cw.Write([]string{"summary", "nodes", strconv.Itoa(r.Nodes), ""})
+	cw.Write([]string{"summary", "reachable", strconv.Itoa(r.Reachable), ""})
+	cw.Write([]string{"summary", "responsive", strconv.Itoa(r.Responsive), ""})
+	for _, list := range []struct {
+		category string
+		shares   []censusShare
+	}{
+		{"client", r.Clients},
+		{"version", r.Versions},
+		{"ethVersion", r.EthVersions},
+		{"network", r.Networks},
+		{"forkId", r.ForkIDs},
+		{"forkReadiness", r.ForkReadiness},
+	} {
+		for _, s := range list.shares {
+			cw.Write([]string{list.category, s.Name, strconv.Itoa(s.Count), strconv.FormatFloat(s.Share, 'f', 4, 64)})
+		}
+	}
+	if c := r.Changes; c != nil {
+		cw.Write([]string{"changes", "added", strconv.Itoa(c.Added), ""})
+		cw.Write([]string{"changes", "removed", strconv.Itoa(c.Removed), ""})
+		cw.Write([]string{"changes", "nowReachable", strconv.Itoa(c.NowReachable), ""})
+		cw.Write([]string{"changes", "unreachable", strconv.Itoa(c.Unreachable), ""})
+		cw.Write([]string{"changes", "updated", strconv.Itoa(len(c.Updates)), ""})
+		for _, d := range c.Clients {
+			cw.Write([]string{"clientDelta", d.Name, strconv.Itoa(d.Delta), ""})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
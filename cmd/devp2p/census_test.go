@@ -0,0 +1,211 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// statusService is a simulation service which runs a fake eth protocol. It
+// sends its status and then waits for the remote side to disconnect.
+type statusService struct {
+	status *eth.StatusPacket
+}
+
+func (s *statusService) Start() error { return nil }
+func (s *statusService) Stop() error  { return nil }
+
+func (s *statusService) protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    eth.ProtocolName,
+		Version: eth.ETH66,
+		Length:  17,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if err := p2p.Send(rw, eth.StatusMsg, s.status); err != nil {
+				return err
+			}
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+			}
+		},
+	}}
+}
+
+func TestCensusProbeSimulation(t *testing.T) {
+	// Nodes with an even index run on network 1, odd ones on network 5.
+	var index int
+	services := adapters.LifecycleConstructors{
+		"status": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			networkID := uint64(1 + 4*(index%2))
+			index++
+			svc := &statusService{status: &eth.StatusPacket{
+				ProtocolVersion: eth.ETH66,
+				NetworkID:       networkID,
+				TD:              big.NewInt(1),
+				ForkID:          forkid.ID{Hash: [4]byte{byte(networkID), 2, 3, 4}, Next: 100},
+			}}
+			stack.RegisterProtocols(svc.protocols())
+			return svc, nil
+		},
+	}
+	adapter := adapters.NewSimAdapter(services)
+	network := simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: "status"})
+	defer network.Shutdown()
+
+	var nodes []*enode.Node
+	for i := 0; i < 4; i++ {
+		n, err := network.NewNodeWithConfig(adapters.RandomNodeConfig())
+		if err != nil {
+			t.Fatal("can't create node:", err)
+		}
+		if err := network.Start(n.ID()); err != nil {
+			t.Fatal("can't start node:", err)
+		}
+		nodes = append(nodes, n.Config.Node())
+	}
+
+	key, _ := crypto.GenerateKey()
+	prober := &censusProber{key: key, dialer: adapter, timeout: 5 * time.Second}
+	result := prober.probeAll(nodes, 2)
+	if len(result) != len(nodes) {
+		t.Fatalf("wrong number of results: got %d, want %d", len(result), len(nodes))
+	}
+	networks := make(map[uint64]int)
+	for i, n := range nodes {
+		res := result[n.ID()]
+		if !res.Reachable || res.Error != "" {
+			t.Fatalf("node %d not probed: reachable=%t, error %q", i, res.Reachable, res.Error)
+		}
+		sim, _ := adapter.GetNode(n.ID())
+		if want := sim.Server().Name; res.Name != want {
+			t.Errorf("node %d: wrong name %q, want %q", i, res.Name, want)
+		}
+		if client, _ := parseClientName(res.Name); res.Client != client {
+			t.Errorf("node %d: wrong client %q, want %q", i, res.Client, client)
+		}
+		if len(res.Caps) != 1 || res.Caps[0] != "eth/66" {
+			t.Errorf("node %d: wrong caps %v", i, res.Caps)
+		}
+		if want := fmt.Sprintf("0x%02x020304", res.NetworkID); res.ForkHash != want || res.ForkNext != 100 {
+			t.Errorf("node %d: wrong fork ID %s/%d, want %s/100", i, res.ForkHash, res.ForkNext, want)
+		}
+		networks[res.NetworkID]++
+	}
+	if networks[1] != 2 || networks[5] != 2 {
+		t.Errorf("wrong network IDs: %v", networks)
+	}
+}
+
+func TestParseClientName(t *testing.T) {
+	tests := []struct {
+		name, client, version string
+	}{
+		{"Geth/v1.10.21-stable-67109427/linux-amd64/go1.18.4", "Geth", "v1.10.21-stable-67109427"},
+		{"Geth/mynode/v1.10.20-stable/linux-amd64/go1.18.1", "Geth", "v1.10.20-stable"},
+		{"erigon/v2022.07.4-stable-3d6e0a1b/linux-amd64/go1.18.4", "erigon", "v2022.07.4-stable-3d6e0a1b"},
+		{"Nethermind", "Nethermind", ""},
+	}
+	for _, test := range tests {
+		client, version := parseClientName(test.name)
+		if client != test.client || version != test.version {
+			t.Errorf("%q: got %q %q, want %q %q", test.name, client, version, test.client, test.version)
+		}
+	}
+}
+
+func TestCensusReport(t *testing.T) {
+	var (
+		id1 = enode.ID{1}
+		id2 = enode.ID{2}
+		id3 = enode.ID{3}
+		id4 = enode.ID{4}
+	)
+	prev := censusSet{
+		id1: {Reachable: true, Name: "Geth/v1.10.20", Client: "Geth", Version: "v1.10.20", Caps: []string{"eth/66"}},
+		id2: {Reachable: false},
+		id3: {Reachable: true, Name: "erigon/v2022.07.1", Client: "erigon", Version: "v2022.07.1", Caps: []string{"eth/66"}},
+	}
+	next := censusSet{
+		id1: {Reachable: true, Name: "Geth/v1.10.21", Client: "Geth", Version: "v1.10.21", Caps: []string{"eth/66", "eth/67"}, NetworkID: 1, ForkHash: "0x01020304", ForkNext: 15050000},
+		id2: {Reachable: true, Name: "Geth/v1.10.21", Client: "Geth", Version: "v1.10.21", Caps: []string{"eth/66", "eth/67"}, NetworkID: 1, ForkHash: "0x01020304"},
+		id4: {Reachable: false},
+	}
+	report := makeCensusReport(next, prev)
+
+	if report.Nodes != 3 || report.Reachable != 2 || report.Responsive != 2 {
+		t.Errorf("wrong summary: nodes %d, reachable %d, responsive %d", report.Nodes, report.Reachable, report.Responsive)
+	}
+	if len(report.Clients) != 1 || report.Clients[0] != (censusShare{"Geth", 2, 1}) {
+		t.Errorf("wrong clients: %v", report.Clients)
+	}
+	if len(report.EthVersions) != 1 || report.EthVersions[0].Name != "eth/67" {
+		t.Errorf("wrong eth versions: %v", report.EthVersions)
+	}
+	wantReadiness := []censusShare{{"next fork at 15050000", 1, 0.5}, {"no fork scheduled", 1, 0.5}}
+	if fmt.Sprint(report.ForkReadiness) != fmt.Sprint(wantReadiness) {
+		t.Errorf("wrong fork readiness: %v", report.ForkReadiness)
+	}
+
+	c := report.Changes
+	if c == nil {
+		t.Fatal("no changes in report")
+	}
+	if c.Added != 1 || c.Removed != 1 || c.NowReachable != 1 || c.Unreachable != 0 {
+		t.Errorf("wrong changes: %+v", c)
+	}
+	if len(c.Updates) != 1 || c.Updates[0] != (censusUpdate{id1, "Geth/v1.10.20", "Geth/v1.10.21"}) {
+		t.Errorf("wrong updates: %v", c.Updates)
+	}
+	wantDeltas := []censusDelta{{"Geth", 1}, {"erigon", -1}}
+	if fmt.Sprint(c.Clients) != fmt.Sprint(wantDeltas) {
+		t.Errorf("wrong client deltas: %v", c.Clients)
+	}
+
+	var buf bytes.Buffer
+	if err := report.writeCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"category,name,count,share",
+		"summary,responsive,2,",
+		"client,Geth,2,1.0000",
+		"forkReadiness,no fork scheduled,1,0.5000",
+		"clientDelta,erigon,-1,",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("CSV output is missing line %q:\n%s", line, buf.String())
+		}
+	}
+}
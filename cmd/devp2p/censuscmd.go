@@ -0,0 +1,194 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	censusCommand = &cli.Command{
+		Name:  "census",
+		Usage: "Network census tools",
+		Subcommands: []*cli.Command{
+			censusRunCommand,
+			censusReportCommand,
+		},
+	}
+	censusRunCommand = &cli.Command{
+		Name:      "run",
+		Usage:     "Crawls the network and records the client software of reachable nodes",
+		ArgsUsage: "<census.json>",
+		Action:    censusRun,
+		Flags: flags.Merge(censusNodeFlags(), []cli.Flag{
+			crawlTimeoutFlag,
+			censusNodesFlag,
+			censusV5Flag,
+			censusDialTimeoutFlag,
+			censusParallelFlag,
+			censusReportFlag,
+		}),
+	}
+	censusReportCommand = &cli.Command{
+		Name:      "report",
+		Usage:     "Creates a report from a census.json file, optionally comparing it to a previous one",
+		ArgsUsage: "<census.json> [<previous census.json>]",
+		Action:    censusMakeReport,
+		Flags:     []cli.Flag{censusReportFlag},
+	}
+)
+
+var (
+	censusNodesFlag = &cli.StringFlag{
+		Name:  "nodes",
+		Usage: "Probe the nodes of this nodes.json file instead of crawling",
+	}
+	censusV5Flag = &cli.BoolFlag{
+		Name:  "v5",
+		Usage: "Crawl using discovery v5 instead of v4",
+	}
+	censusDialTimeoutFlag = &cli.DurationFlag{
+		Name:  "dial-timeout",
+		Usage: "Time limit for probing a single node",
+		Value: 10 * time.Second,
+	}
+	censusParallelFlag = &cli.IntFlag{
+		Name:  "parallel",
+		Usage: "Number of nodes to probe at the same time",
+		Value: 32,
+	}
+	censusReportFlag = &cli.StringFlag{
+		Name:  "report",
+		Usage: "Write the census report to this file, as CSV if it ends in .csv and as JSON otherwise (default: stdout)",
+	}
+)
+
+// censusNodeFlags returns the flags of the local discovery node, which are the
+// union of the discv4 and discv5 node flags as the census crawls either DHT.
+func censusNodeFlags() []cli.Flag {
+	var (
+		list []cli.Flag
+		seen = make(map[cli.Flag]bool)
+	)
+	for _, f := range flags.Merge(v4NodeFlags, v5NodeFlags) {
+		if !seen[f] {
+			seen[f] = true
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+func censusRun(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need census file as argument")
+	}
+	censusFile := ctx.Args().First()
+
+	// Gather the nodes to probe, either from a node set or by crawling.
+	var nodes nodeSet
+	if file := ctx.String(censusNodesFlag.Name); file != "" {
+		nodes = loadNodesJSON(file)
+	} else if ctx.Bool(censusV5Flag.Name) {
+		disc := startV5(ctx)
+		c := newCrawler(nil, disc, disc.RandomNodes())
+		nodes = c.run(ctx.Duration(crawlTimeoutFlag.Name))
+		disc.Close()
+	} else {
+		disc := startV4(ctx)
+		c := newCrawler(nil, disc, disc.RandomNodes())
+		nodes = c.run(ctx.Duration(crawlTimeoutFlag.Name))
+		disc.Close()
+	}
+	log.Info("Probing nodes", "count", len(nodes))
+
+	key, err := censusKey(ctx)
+	if err != nil {
+		return err
+	}
+	prober := &censusProber{
+		key:     key,
+		dialer:  tcpNodeDialer{},
+		timeout: ctx.Duration(censusDialTimeoutFlag.Name),
+	}
+	parallel := ctx.Int(censusParallelFlag.Name)
+	if parallel < 1 {
+		parallel = 1
+	}
+	result := prober.probeAll(nodes.nodes(), parallel)
+
+	// Compare against the previous run stored in the same file.
+	var prev censusSet
+	if common.FileExist(censusFile) {
+		prev = loadCensusJSON(censusFile)
+	}
+	writeCensusJSON(censusFile, result)
+	return writeCensusReport(ctx.String(censusReportFlag.Name), makeCensusReport(result, prev))
+}
+
+func censusMakeReport(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need census file as argument")
+	}
+	var (
+		set  = loadCensusJSON(ctx.Args().Get(0))
+		prev censusSet
+	)
+	if ctx.NArg() > 1 {
+		prev = loadCensusJSON(ctx.Args().Get(1))
+	}
+	return writeCensusReport(ctx.String(censusReportFlag.Name), makeCensusReport(set, prev))
+}
+
+// censusKey returns the node key used for probing.
+func censusKey(ctx *cli.Context) (*ecdsa.PrivateKey, error) {
+	if !ctx.IsSet(nodekeyFlag.Name) {
+		return crypto.GenerateKey()
+	}
+	key, err := crypto.HexToECDSA(ctx.String(nodekeyFlag.Name))
+	if err != nil {
+		return nil, fmt.Errorf("-%s: %v", nodekeyFlag.Name, err)
+	}
+	return key, nil
+}
+
+// writeCensusReport writes the report to the given file, or to stdout if no file
+// is given. The format is chosen by the file extension.
+func writeCensusReport(file string, report *censusReport) error {
+	if file == "" {
+		return report.writeJSON(os.Stdout)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if filepath.Ext(file) == ".csv" {
+		return report.writeCSV(f)
+	}
+	return report.writeJSON(f)
+}
@@ -18,10 +18,13 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/v5test"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/urfave/cli/v2"
 )
@@ -36,6 +39,7 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5TopicSearchCommand,
 		},
 	}
 	discv5PingCommand = &cli.Command{
@@ -70,12 +74,33 @@ var (
 		Name:   "listen",
 		Usage:  "Runs a node",
 		Action: discv5Listen,
-		Flags: []cli.Flag{
-			bootnodesFlag,
-			nodekeyFlag,
-			nodedbFlag,
-			listenAddrFlag,
-		},
+		Flags:  flags.Merge(v5NodeFlags, []cli.Flag{topicFlag}),
+	}
+	discv5TopicSearchCommand = &cli.Command{
+		Name:      "topic-search",
+		Usage:     "Finds nodes advertising a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicSearch,
+		Flags:     []cli.Flag{bootnodesFlag, topicSearchTimeoutFlag},
+	}
+)
+
+var v5NodeFlags = []cli.Flag{
+	bootnodesFlag,
+	nodekeyFlag,
+	nodedbFlag,
+	listenAddrFlag,
+}
+
+var (
+	topicFlag = &cli.StringSliceFlag{
+		Name:  "topic",
+		Usage: "Advertises the node under the given topic (name or 0x-prefixed hash)",
+	}
+	topicSearchTimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for the search.",
+		Value: time.Minute,
 	}
 )
 
@@ -130,10 +155,52 @@ func discv5Listen(ctx *cli.Context) error {
 	disc := startV5(ctx)
 	defer disc.Close()
 
+	for _, arg := range ctx.StringSlice(topicFlag.Name) {
+		topic, err := parseTopic(arg)
+		if err != nil {
+			return err
+		}
+		disc.RegisterTopic(topic)
+	}
 	fmt.Println(disc.Self())
 	select {}
 }
 
+func discv5TopicSearch(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need topic as argument")
+	}
+	topic, err := parseTopic(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	disc := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicSearch(topic)
+	defer it.Close()
+	time.AfterFunc(ctx.Duration(topicSearchTimeoutFlag.Name), it.Close)
+
+	for it.Next() {
+		fmt.Println(it.Node())
+	}
+	return nil
+}
+
+// parseTopic parses a topic given either by name or as 0x-prefixed hash.
+func parseTopic(arg string) (discover.Topic, error) {
+	if !strings.HasPrefix(arg, "0x") {
+		return discover.NewTopic(arg), nil
+	}
+	var topic discover.Topic
+	b, err := hexutil.Decode(arg)
+	if err != nil || len(b) != len(topic) {
+		return topic, fmt.Errorf("invalid topic hash %q", arg)
+	}
+	copy(topic[:], b)
+	return topic, nil
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) *discover.UDPv5 {
 	ln, config := makeDiscoveryConfig(ctx)
@@ -0,0 +1,354 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/miekg/dns"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// Dynamic updates are sent over TCP, where messages are limited to 64k. Keep
+	// change sets well below that to leave room for the TSIG record.
+	rfc2136ChangeSizeLimit = 32000
+	rfc2136Timeout         = 10 * time.Second
+	rfc2136TSIGFudge       = 300
+)
+
+var (
+	rfc2136ServerFlag = &cli.StringFlag{
+		Name:  "server",
+		Usage: "Address of the primary DNS server (host:port)",
+	}
+	rfc2136ZoneFlag = &cli.StringFlag{
+		Name:  "zone",
+		Usage: "DNS zone containing the tree (default: looked up on the server)",
+	}
+	rfc2136TSIGKeyFlag = &cli.StringFlag{
+		Name:  "tsig-key",
+		Usage: "Name of the TSIG key used to sign updates",
+	}
+	rfc2136TSIGSecretFlag = &cli.StringFlag{
+		Name:    "tsig-secret",
+		Usage:   "Base64-encoded secret of the TSIG key",
+		EnvVars: []string{"TSIG_SECRET"},
+	}
+	rfc2136TSIGAlgorithmFlag = &cli.StringFlag{
+		Name:  "tsig-algorithm",
+		Usage: "TSIG algorithm (hmac-sha1|hmac-sha256|hmac-sha512)",
+		Value: "hmac-sha256",
+	}
+)
+
+// rfc2136Client deploys trees to a DNS server supporting dynamic updates.
+type rfc2136Client struct {
+	server  string
+	zone    string
+	keyName string // TSIG key name, empty if updates are not signed
+	keyAlg  string
+	secrets map[string]string
+}
+
+// rfc2136Record is an existing TXT record set.
+type rfc2136Record struct {
+	value string // concatenated character strings
+	ttl   uint32
+}
+
+// rfc2136Change is an update to the TXT record set of a name.
+type rfc2136Change struct {
+	action string // "CREATE", "UPDATE" or "DELETE"
+	name   string
+	value  string
+	ttl    uint32
+	rrs    []dns.RR // records to remove and add
+}
+
+// newRFC2136Client sets up a dynamic update client from command line flags.
+func newRFC2136Client(ctx *cli.Context) *rfc2136Client {
+	server := ctx.String(rfc2136ServerFlag.Name)
+	if server == "" {
+		exit(fmt.Errorf("need DNS server address to proceed"))
+	}
+	keyName, secret := ctx.String(rfc2136TSIGKeyFlag.Name), ctx.String(rfc2136TSIGSecretFlag.Name)
+	if (keyName == "") != (secret == "") {
+		exit(fmt.Errorf("need both TSIG key name and secret to sign updates"))
+	}
+	c, err := newRFC2136(server, ctx.String(rfc2136ZoneFlag.Name), keyName, secret, ctx.String(rfc2136TSIGAlgorithmFlag.Name))
+	if err != nil {
+		exit(err)
+	}
+	return c
+}
+
+func newRFC2136(server, zone, keyName, secret, algorithm string) (*rfc2136Client, error) {
+	c := &rfc2136Client{server: server}
+	if zone != "" {
+		c.zone = dns.CanonicalName(zone)
+	}
+	if keyName != "" {
+		switch strings.ToLower(strings.TrimSuffix(algorithm, ".")) {
+		case "hmac-sha1":
+			c.keyAlg = dns.HmacSHA1
+		case "hmac-sha256":
+			c.keyAlg = dns.HmacSHA256
+		case "hmac-sha512":
+			c.keyAlg = dns.HmacSHA512
+		default:
+			return nil, fmt.Errorf("unsupported TSIG algorithm %q", algorithm)
+		}
+		c.keyName = dns.CanonicalName(keyName)
+		c.secrets = map[string]string{c.keyName: secret}
+	}
+	return c, nil
+}
+
+// deploy uploads the given tree to the DNS server.
+func (c *rfc2136Client) deploy(name string, t *dnsdisc.Tree) error {
+	if err := c.checkZone(name); err != nil {
+		return err
+	}
+
+	// Compute DNS changes.
+	existing, err := c.collectRecords(name)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Found %d TXT records", len(existing)))
+	records := t.ToTXT(name)
+	changes := c.computeChanges(name, records, existing)
+
+	// Submit to the server.
+	return c.submitChanges(changes)
+}
+
+// checkZone finds the zone containing the given domain.
+func (c *rfc2136Client) checkZone(name string) error {
+	if c.zone != "" {
+		if !isSubdomain(name, c.zone) {
+			return fmt.Errorf("%s is not in zone %s", name, c.zone)
+		}
+		return nil
+	}
+	log.Info(fmt.Sprintf("Finding DNS zone of %s", name))
+	m := new(dns.Msg)
+	m.SetQuestion(dns.CanonicalName(name), dns.TypeSOA)
+	c.sign(m)
+	resp, err := c.exchange(m)
+	if err != nil {
+		return err
+	}
+	// The SOA record is in the answer for the zone apex and in the authority
+	// section for names within the zone.
+	for _, rr := range append(resp.Answer, resp.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			c.zone = dns.CanonicalName(soa.Hdr.Name)
+			log.Info(fmt.Sprintf("Found zone %s", c.zone))
+			return nil
+		}
+	}
+	return errors.New("can't find zone of " + name)
+}
+
+// collectRecords collects all TXT records below the given name using a zone
+// transfer.
+func (c *rfc2136Client) collectRecords(name string) (map[string]rfc2136Record, error) {
+	log.Info("Loading existing TXT records", "name", name, "zone", c.zone)
+	m := new(dns.Msg)
+	m.SetAxfr(c.zone)
+	c.sign(m)
+	tr := &dns.Transfer{
+		DialTimeout:  rfc2136Timeout,
+		ReadTimeout:  rfc2136Timeout,
+		WriteTimeout: rfc2136Timeout,
+		TsigSecret:   c.secrets,
+	}
+	envs, err := tr.In(m, c.server)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]rfc2136Record)
+	for env := range envs {
+		if env.Error != nil {
+			return nil, fmt.Errorf("zone transfer failed: %v", env.Error)
+		}
+		for _, rr := range env.RR {
+			txt, ok := rr.(*dns.TXT)
+			if !ok || !isSubdomain(txt.Hdr.Name, name) {
+				continue
+			}
+			name := strings.TrimSuffix(strings.ToLower(txt.Hdr.Name), ".")
+			// Multiple TXT records with the same name are joined, so that they
+			// are replaced by the update.
+			rec := existing[name]
+			rec.value += strings.Join(txt.Txt, "")
+			rec.ttl = txt.Hdr.Ttl
+			existing[name] = rec
+		}
+	}
+	return existing, nil
+}
+
+// computeChanges creates DNS changes for the given set of DNS discovery records.
+// The 'existing' arg is the set of records that already exist on the server.
+func (c *rfc2136Client) computeChanges(name string, records map[string]string, existing map[string]rfc2136Record) []rfc2136Change {
+	// Convert all names to lowercase.
+	lrecords := make(map[string]string, len(records))
+	for name, r := range records {
+		lrecords[strings.ToLower(name)] = r
+	}
+	records = lrecords
+
+	var changes []rfc2136Change
+	for path, newValue := range records {
+		prev, exists := existing[path]
+
+		// Assign TTL.
+		ttl := uint32(rootTTL)
+		if path != name {
+			ttl = uint32(treeNodeTTL)
+		}
+
+		if !exists {
+			// Entry is unknown, push a new one
+			log.Info(fmt.Sprintf("Creating %s = %q", path, newValue))
+			changes = append(changes, newRFC2136Change("CREATE", path, ttl, newValue))
+		} else if prev.value != newValue || prev.ttl != ttl {
+			// Entry already exists, only change its content.
+			log.Info(fmt.Sprintf("Updating %s from %q to %q", path, prev.value, newValue))
+			changes = append(changes, newRFC2136Change("UPDATE", path, ttl, newValue))
+		} else {
+			log.Debug(fmt.Sprintf("Skipping %s = %q", path, newValue))
+		}
+	}
+
+	// Iterate over the old records and delete anything stale.
+	for path, prev := range existing {
+		if _, ok := records[path]; ok {
+			continue
+		}
+		log.Info(fmt.Sprintf("Deleting %s = %q", path, prev.value))
+		changes = append(changes, newRFC2136Change("DELETE", path, prev.ttl, ""))
+	}
+
+	// Ensure changes are in leaf-added -> root-changed -> leaf-deleted order.
+	score := map[string]int{"CREATE": 1, "UPDATE": 2, "DELETE": 3}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].action == changes[j].action {
+			return changes[i].name < changes[j].name
+		}
+		return score[changes[i].action] < score[changes[j].action]
+	})
+	return changes
+}
+
+// newRFC2136Change creates the update records of a change.
+func newRFC2136Change(action, name string, ttl uint32, value string) rfc2136Change {
+	ch := rfc2136Change{action: action, name: name, ttl: ttl, value: value}
+	hdr := dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl}
+	if action != "CREATE" {
+		// Delete the whole record set.
+		del := hdr
+		del.Class, del.Ttl = dns.ClassANY, 0
+		ch.rrs = append(ch.rrs, &dns.TXT{Hdr: del})
+	}
+	if action != "DELETE" {
+		ch.rrs = append(ch.rrs, &dns.TXT{Hdr: hdr, Txt: splitTXTStrings(value)})
+	}
+	return ch
+}
+
+// splitTXTStrings splits value into the 255-character strings of a TXT record.
+func splitTXTStrings(value string) []string {
+	var strs []string
+	for len(value) > 255 {
+		strs = append(strs, value[:255])
+		value = value[255:]
+	}
+	return append(strs, value)
+}
+
+// submitChanges sends the given changes as dynamic updates. Every update message
+// is applied atomically by the server.
+func (c *rfc2136Client) submitChanges(changes []rfc2136Change) error {
+	if len(changes) == 0 {
+		log.Info("No DNS changes needed")
+		return nil
+	}
+	batches := splitRFC2136Changes(changes, rfc2136ChangeSizeLimit)
+	for i, batch := range batches {
+		log.Info(fmt.Sprintf("Submitting %d changes to %s (%d/%d)", len(batch), c.server, i+1, len(batches)))
+		m := new(dns.Msg)
+		m.SetUpdate(c.zone)
+		for _, ch := range batch {
+			m.Ns = append(m.Ns, ch.rrs...)
+		}
+		c.sign(m)
+		resp, err := c.exchange(m)
+		if err != nil {
+			return err
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			return fmt.Errorf("DNS update failed: %s", dns.RcodeToString[resp.Rcode])
+		}
+	}
+	return nil
+}
+
+// splitRFC2136Changes splits up DNS changes such that each update message is
+// smaller than the given size limit.
+func splitRFC2136Changes(changes []rfc2136Change, sizeLimit int) [][]rfc2136Change {
+	var (
+		batches   [][]rfc2136Change
+		batchSize int
+	)
+	for _, ch := range changes {
+		size := 0
+		for _, rr := range ch.rrs {
+			size += dns.Len(rr)
+		}
+		if len(batches) == 0 || batchSize+size > sizeLimit {
+			batches = append(batches, nil)
+			batchSize = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], ch)
+		batchSize += size
+	}
+	return batches
+}
+
+// sign adds a TSIG record to the message if a key is configured.
+func (c *rfc2136Client) sign(m *dns.Msg) {
+	if c.keyName != "" {
+		m.SetTsig(c.keyName, c.keyAlg, rfc2136TSIGFudge, time.Now().Unix())
+	}
+}
+
+// exchange sends a message to the server over TCP.
+func (c *rfc2136Client) exchange(m *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{Net: "tcp", Timeout: rfc2136Timeout, TsigSecret: c.secrets}
+	resp, _, err := client.Exchange(m, c.server)
+	return resp, err
+}
@@ -0,0 +1,287 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/miekg/dns"
+)
+
+const (
+	testTSIGKey  = "update-key."
+	testDNSZone  = "example.org."
+	testTreeName = "nodes.example.org"
+)
+
+var testTSIGSecret = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+// This test deploys trees to an in-process DNS server and checks that only the
+// changed records are updated.
+func TestRFC2136Deploy(t *testing.T) {
+	srv := newTestDNSServer(t)
+	defer srv.close()
+
+	c, err := newRFC2136(srv.addr, "", testTSIGKey, testTSIGSecret, "hmac-sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	nodes := testNodes(t, 5)
+
+	// Deploy the initial tree. The zone is looked up on the server.
+	tree1 := testSignedTree(t, key, 1, nodes[:3])
+	if err := c.deploy(testTreeName, tree1); err != nil {
+		t.Fatal("deploy failed:", err)
+	}
+	if c.zone != testDNSZone {
+		t.Errorf("wrong zone %q", c.zone)
+	}
+	srv.checkRecords(t, tree1.ToTXT(testTreeName))
+
+	// Redeploying the same tree doesn't send any updates.
+	updates := srv.updateCount()
+	if err := c.deploy(testTreeName, tree1); err != nil {
+		t.Fatal("deploy failed:", err)
+	}
+	if srv.updateCount() != updates {
+		t.Error("updates sent for unchanged tree")
+	}
+
+	// Deploy a modified tree, stale records must be removed.
+	tree2 := testSignedTree(t, key, 2, nodes[2:])
+	if err := c.deploy(testTreeName, tree2); err != nil {
+		t.Fatal("deploy failed:", err)
+	}
+	srv.checkRecords(t, tree2.ToTXT(testTreeName))
+
+	// Unsigned updates are rejected.
+	c, _ = newRFC2136(srv.addr, testDNSZone, "", "", "")
+	if err := c.deploy(testTreeName, tree1); err == nil || !strings.Contains(err.Error(), "REFUSED") {
+		t.Errorf("wrong error for unsigned update: %v", err)
+	}
+}
+
+// This test checks that the changes are created in leaf-added -> root-changed ->
+// leaf-deleted order and split into batches.
+func TestRFC2136ChangeSort(t *testing.T) {
+	c := &rfc2136Client{}
+	existing := map[string]rfc2136Record{
+		"n":   {value: "enrtree-root:v1 old", ttl: rootTTL},
+		"a.n": {value: "enr:a", ttl: treeNodeTTL},
+		"b.n": {value: "enr:b", ttl: treeNodeTTL},
+	}
+	records := map[string]string{
+		"n":   "enrtree-root:v1 new",
+		"A.n": "enr:a",
+		"c.n": "enr:c",
+		"d.n": "enr:d",
+	}
+	changes := c.computeChanges("n", records, existing)
+	var have []string
+	for _, ch := range changes {
+		have = append(have, ch.action+" "+ch.name)
+	}
+	want := []string{"CREATE c.n", "CREATE d.n", "UPDATE n", "DELETE b.n"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("wrong changes:\nhave %v\nwant %v", have, want)
+	}
+
+	batches := splitRFC2136Changes(changes, 2*dns.Len(changes[0].rrs[0]))
+	if len(batches) != 3 || len(batches[0]) != 2 {
+		t.Errorf("wrong batches: %d", len(batches))
+	}
+}
+
+func testNodes(t *testing.T, n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i := range nodes {
+		key, _ := crypto.GenerateKey()
+		var r enr.Record
+		r.Set(enr.IPv4{127, 0, 0, byte(i + 1)})
+		r.Set(enr.UDP(30303))
+		if err := enode.SignV4(&r, key); err != nil {
+			t.Fatal(err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = node
+	}
+	return nodes
+}
+
+func testSignedTree(t *testing.T, key *ecdsa.PrivateKey, seq uint, nodes []*enode.Node) *dnsdisc.Tree {
+	tree, err := dnsdisc.MakeTree(seq, nodes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Sign(key, testTreeName); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// testDNSServer is an authoritative DNS server for a single zone, supporting zone
+// transfers and TSIG-signed dynamic updates.
+type testDNSServer struct {
+	addr string
+	srv  *dns.Server
+
+	mu      sync.Mutex
+	records map[string][]dns.RR
+	updates int
+}
+
+func newTestDNSServer(t *testing.T) *testDNSServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	s := &testDNSServer{addr: l.Addr().String(), records: make(map[string][]dns.RR)}
+	s.srv = &dns.Server{
+		Listener:   l,
+		Handler:    s,
+		TsigSecret: map[string]string{testTSIGKey: testTSIGSecret},
+		// Dynamic updates are rejected by default.
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+	}
+	started := make(chan struct{})
+	s.srv.NotifyStartedFunc = func() { close(started) }
+	go s.srv.ActivateAndServe()
+	<-started
+	return s
+}
+
+func (s *testDNSServer) close() {
+	s.srv.Shutdown()
+}
+
+func (s *testDNSServer) updateCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updates
+}
+
+// checkRecords verifies that the TXT records of the zone match the tree.
+func (s *testDNSServer) checkRecords(t *testing.T, want map[string]string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	have := make(map[string]string)
+	for name, rrs := range s.records {
+		for _, rr := range rrs {
+			have[strings.TrimSuffix(name, ".")] += strings.Join(rr.(*dns.TXT).Txt, "")
+		}
+	}
+	lwant := make(map[string]string)
+	for name, value := range want {
+		lwant[strings.ToLower(name)] = value
+	}
+	if !reflect.DeepEqual(have, lwant) {
+		t.Errorf("wrong zone content:\nhave %v\nwant %v", have, lwant)
+	}
+}
+
+func (s *testDNSServer) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: testDNSZone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Ns:      "ns." + testDNSZone,
+		Mbox:    "admin." + testDNSZone,
+		Serial:  1,
+		Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 60,
+	}
+}
+
+func (s *testDNSServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	signed := req.IsTsig() != nil
+	if signed && w.TsigStatus() != nil {
+		resp.SetRcode(req, dns.RcodeNotAuth)
+		w.WriteMsg(resp)
+		return
+	}
+	if signed {
+		resp.SetTsig(testTSIGKey, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+
+	switch {
+	case req.Opcode == dns.OpcodeUpdate:
+		if !signed {
+			resp.SetRcode(req, dns.RcodeRefused)
+		} else {
+			s.update(req.Ns)
+		}
+	case req.Question[0].Qtype == dns.TypeAXFR:
+		s.mu.Lock()
+		rrs := []dns.RR{s.soa()}
+		for _, set := range s.records {
+			rrs = append(rrs, set...)
+		}
+		rrs = append(rrs, s.soa())
+		s.mu.Unlock()
+
+		ch := make(chan *dns.Envelope, 1)
+		ch <- &dns.Envelope{RR: rrs}
+		close(ch)
+		tr := &dns.Transfer{TsigSecret: map[string]string{testTSIGKey: testTSIGSecret}}
+		tr.Out(w, req, ch)
+		w.Hijack()
+		return
+	case req.Question[0].Qtype == dns.TypeSOA:
+		resp.Authoritative = true
+		if strings.EqualFold(req.Question[0].Name, testDNSZone) {
+			resp.Answer = []dns.RR{s.soa()}
+		} else {
+			resp.Ns = []dns.RR{s.soa()}
+		}
+	default:
+		resp.SetRcode(req, dns.RcodeNotImplemented)
+	}
+	w.WriteMsg(resp)
+}
+
+// update applies the update section of a dynamic update.
+func (s *testDNSServer) update(rrs []dns.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates++
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		switch rr.Header().Class {
+		case dns.ClassANY:
+			delete(s.records, name)
+		case dns.ClassINET:
+			s.records[name] = append(s.records[name], rr)
+		}
+	}
+}
@@ -43,6 +43,7 @@ var (
 			dnsCloudflareCommand,
 			dnsRoute53Command,
 			dnsRoute53NukeCommand,
+			dnsRFC2136Command,
 		},
 	}
 	dnsSyncCommand = &cli.Command{
@@ -96,6 +97,19 @@ var (
 			route53RegionFlag,
 		},
 	}
+	dnsRFC2136Command = &cli.Command{
+		Name:      "to-rfc2136",
+		Usage:     "Deploy DNS TXT records to a DNS server using dynamic updates (RFC 2136)",
+		ArgsUsage: "<tree-directory>",
+		Action:    dnsToRFC2136,
+		Flags: []cli.Flag{
+			rfc2136ServerFlag,
+			rfc2136ZoneFlag,
+			rfc2136TSIGKeyFlag,
+			rfc2136TSIGSecretFlag,
+			rfc2136TSIGAlgorithmFlag,
+		},
+	}
 )
 
 var (
@@ -250,6 +264,19 @@ func dnsNukeRoute53(ctx *cli.Context) error {
 	return client.deleteDomain(ctx.Args().First())
 }
 
+// dnsToRFC2136 performs dnsRFC2136Command.
+func dnsToRFC2136(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	domain, t, err := loadTreeDefinitionForExport(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	client := newRFC2136Client(ctx)
+	return client.deploy(domain, t)
+}
+
 // loadSigningKey loads a private key in Ethereum keystore format.
 func loadSigningKey(keyfile string) *ecdsa.PrivateKey {
 	keyjson, err := os.ReadFile(keyfile)
@@ -58,6 +58,21 @@ func (s *Suite) Is_66(t *utesting.T) {
 	}
 }
 
+// Is_68 checks if the node supports the eth68 protocol version,
+// and if not, exists the test suite
+func (s *Suite) Is_68(t *utesting.T) {
+	conn, err := s.dial68()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if err := conn.handshake(); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if conn.negotiatedProtoVersion < 68 {
+		t.Fail()
+	}
+}
+
 // dial attempts to dial the given node and perform a handshake,
 // returning the created Conn if successful.
 func (s *Suite) dial() (*Conn, error) {
@@ -96,7 +111,20 @@ func (s *Suite) dial66() (*Conn, error) {
 	return conn, nil
 }
 
-// dial66 attempts to dial the given node and perform a handshake,
+// dial68 attempts to dial the given node and perform a handshake,
+// returning the created Conn with additional eth68 capabilities if
+// successful
+func (s *Suite) dial68() (*Conn, error) {
+	conn, err := s.dial66()
+	if err != nil {
+		return nil, err
+	}
+	conn.caps = append(conn.caps, p2p.Cap{Name: "eth", Version: 68})
+	conn.ourHighestProtoVersion = 68
+	return conn, nil
+}
+
+// dialSnap attempts to dial the given node and perform a handshake,
 // returning the created Conn with additional snap/1 capabilities if
 // successful.
 func (s *Suite) dialSnap() (*Conn, error) {
@@ -266,7 +294,7 @@ func (s *Suite) createSendAndRecvConns(isEth66 bool) (*Conn, *Conn, error) {
 }
 
 func (c *Conn) readAndServe(chain *Chain, timeout time.Duration) Message {
-	if c.negotiatedProtoVersion == 66 {
+	if c.negotiatedProtoVersion >= 66 {
 		_, msg := c.readAndServe66(chain, timeout)
 		return msg
 	}
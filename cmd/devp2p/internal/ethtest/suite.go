@@ -125,6 +125,13 @@ func (s *Suite) Eth66Tests() []utesting.Test {
 	}
 }
 
+func (s *Suite) Eth68Tests() []utesting.Test {
+	return []utesting.Test{
+		// only proceed with eth68 test suite if node supports eth 68 protocol
+		{Name: "TestNewPooledTxs68", Fn: s.TestNewPooledTxs68},
+	}
+}
+
 func (s *Suite) SnapTests() []utesting.Test {
 	return []utesting.Test{
 		{Name: "TestSnapStatus", Fn: s.TestSnapStatus},
@@ -791,3 +798,70 @@ func (s *Suite) TestNewPooledTxs66(t *utesting.T) {
 		}
 	}
 }
+
+// TestNewPooledTxs68 tests whether a node will do a GetPooledTransactions
+// request upon receiving an eth/68 NewPooledTransactionHashes announcement
+// carrying the types and sizes of the announced transactions.
+func (s *Suite) TestNewPooledTxs68(t *utesting.T) {
+	// send the next block to ensure the node is no longer syncing and
+	// is able to accept txs
+	if err := s.sendNextBlock(eth66); err != nil {
+		t.Fatalf("failed to send next block: %v", err)
+	}
+
+	// generate 50 txs
+	_, txs, err := generateTxs(s, 50)
+	if err != nil {
+		t.Fatalf("failed to generate transactions: %v", err)
+	}
+
+	// create new pooled tx hashes announcement
+	announce := NewPooledTransactionHashes68{
+		Types:  make([]byte, len(txs)),
+		Sizes:  make([]uint32, len(txs)),
+		Hashes: make([]common.Hash, len(txs)),
+	}
+	for i, tx := range txs {
+		announce.Types[i] = tx.Type()
+		announce.Sizes[i] = uint32(tx.Size())
+		announce.Hashes[i] = tx.Hash()
+	}
+
+	// send announcement
+	conn, err := s.dial68()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err = conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if conn.negotiatedProtoVersion < eth.ETH68 {
+		t.Fatalf("node does not support eth/68, negotiated eth/%d", conn.negotiatedProtoVersion)
+	}
+	if err = conn.Write(announce); err != nil {
+		t.Fatalf("failed to write to connection: %v", err)
+	}
+
+	// wait for GetPooledTxs request
+	for {
+		_, msg := conn.readAndServe66(s.chain, timeout)
+		switch msg := msg.(type) {
+		case GetPooledTransactions:
+			if len(msg) != len(txs) {
+				t.Fatalf("unexpected number of txs requested: wanted %d, got %d", len(txs), len(msg))
+			}
+			return
+		// ignore propagated txs from previous tests
+		case *NewPooledTransactionHashes68:
+			continue
+		// ignore block announcements from previous tests
+		case *NewBlockHashes:
+			continue
+		case *NewBlock:
+			continue
+		default:
+			t.Fatalf("unexpected %s", pretty.Sdump(msg))
+		}
+	}
+}
@@ -112,10 +112,16 @@ type NewBlock eth.NewBlockPacket
 func (nb NewBlock) Code() int { return 23 }
 
 // NewPooledTransactionHashes is the network packet for the tx hash propagation message.
-type NewPooledTransactionHashes eth.NewPooledTransactionHashesPacket
+type NewPooledTransactionHashes eth.NewPooledTransactionHashesPacket66
 
 func (nb NewPooledTransactionHashes) Code() int { return 24 }
 
+// NewPooledTransactionHashes68 is the eth/68 network packet for the tx hash
+// propagation message, carrying the types and sizes of the announced txs.
+type NewPooledTransactionHashes68 eth.NewPooledTransactionHashesPacket68
+
+func (nb NewPooledTransactionHashes68) Code() int { return 24 }
+
 type GetPooledTransactions eth.GetPooledTransactionsPacket
 
 func (gpt GetPooledTransactions) Code() int { return 25 }
@@ -234,7 +240,11 @@ func (c *Conn) Read66() (uint64, Message) {
 	case (Transactions{}).Code():
 		msg = new(Transactions)
 	case (NewPooledTransactionHashes{}).Code():
-		msg = new(NewPooledTransactionHashes)
+		if c.negotiatedProtoVersion >= eth.ETH68 {
+			msg = new(NewPooledTransactionHashes68)
+		} else {
+			msg = new(NewPooledTransactionHashes)
+		}
 	case (GetPooledTransactions{}.Code()):
 		ethMsg := new(eth.GetPooledTransactionsPacket66)
 		if err := rlp.DecodeBytes(rawData, ethMsg); err != nil {
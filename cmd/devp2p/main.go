@@ -58,6 +58,7 @@ func init() {
 	}
 	// Add subcommands.
 	app.Commands = []*cli.Command{
+		censusCommand,
 		enrdumpCommand,
 		keyCommand,
 		discv4Command,
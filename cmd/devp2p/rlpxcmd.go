@@ -115,7 +115,12 @@ func rlpxEthTest(ctx *cli.Context) error {
 	if is66Failed {
 		return runTests(ctx, suite.EthTests())
 	}
-	return runTests(ctx, suite.AllEthTests())
+	// check if given node supports eth68, and if so, run eth68 protocol tests as well
+	is68Failed, _ := utesting.Run(utesting.Test{Name: "Is_68", Fn: suite.Is_68})
+	if is68Failed {
+		return runTests(ctx, suite.AllEthTests())
+	}
+	return runTests(ctx, append(suite.AllEthTests(), suite.Eth68Tests()...))
 }
 
 // rlpxSnapTest runs the snap protocol test suite.
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
@@ -40,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/naoina/toml"
 )
 
@@ -53,6 +56,19 @@ var (
 		Description: `The dumpconfig command shows configuration values.`,
 	}
 
+	dumpOpenRPCCommand = &cli.Command{
+		Action:    dumpOpenRPC,
+		Name:      "dumpopenrpc",
+		Usage:     "Show the OpenRPC description of the RPC API",
+		ArgsUsage: "[<file>]",
+		Flags:     utils.GroupFlags(nodeFlags, rpcFlags),
+		Description: `
+The dumpopenrpc command writes the OpenRPC document describing all RPC methods
+of the node, regardless of the modules enabled on its endpoints. This is the
+document returned by rpc.discover. The node is assembled in memory, so the data
+directory is never opened.`,
+	}
+
 	configFileFlag = &cli.StringFlag{
 		Name:     "config",
 		Usage:    "TOML configuration file",
@@ -120,6 +136,12 @@ func defaultNodeConfig() node.Config {
 
 // makeConfigNode loads geth configuration and creates a blank node instance.
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
+	return newConfigNode(ctx, loadBaseConfig(ctx))
+}
+
+// loadBaseConfig loads the gethConfig based on the given command line
+// parameters and config file.
+func loadBaseConfig(ctx *cli.Context) gethConfig {
 	// Load defaults.
 	cfg := gethConfig{
 		Eth:     ethconfig.Defaults,
@@ -136,6 +158,12 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node)
+	return cfg
+}
+
+// newConfigNode creates the protocol stack for the given configuration, applying
+// the remaining command line parameters to it.
+func newConfigNode(ctx *cli.Context, cfg gethConfig) (*node.Node, gethConfig) {
 	stack, err := node.New(&cfg.Node)
 	if err != nil {
 		utils.Fatalf("Failed to create the protocol stack: %v", err)
@@ -157,6 +185,12 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 // makeFullNode loads geth configuration and creates the Ethereum backend.
 func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	stack, cfg := makeConfigNode(ctx)
+	return registerFullNode(ctx, stack, cfg)
+}
+
+// registerFullNode creates the Ethereum backend and the services configured on
+// the command line on top of the given protocol stack.
+func registerFullNode(ctx *cli.Context, stack *node.Node, cfg gethConfig) (*node.Node, ethapi.Backend) {
 	if ctx.IsSet(utils.OverrideGrayGlacierFlag.Name) {
 		cfg.Eth.OverrideGrayGlacier = new(big.Int).SetUint64(ctx.Uint64(utils.OverrideGrayGlacierFlag.Name))
 	}
@@ -182,6 +216,23 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		}
 	}
 
+	// Drive the chain with a simulated beacon client in developer mode, or
+	// expose the engine API for an external consensus client otherwise.
+	if ctx.IsSet(utils.DeveloperFlag.Name) {
+		if eth == nil {
+			utils.Fatalf("Developer mode requires a full node")
+		}
+		simBeacon, err := catalyst.NewSimulatedBeacon(uint64(ctx.Int(utils.DeveloperPeriodFlag.Name)), eth)
+		if err != nil {
+			utils.Fatalf("Failed to register dev mode catalyst service: %v", err)
+		}
+		catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
+		stack.RegisterLifecycle(simBeacon)
+	} else if eth != nil && eth.BlockChain().Config().TerminalTotalDifficulty != nil {
+		if err := catalyst.Register(stack, eth); err != nil {
+			utils.Fatalf("Failed to register the catalyst service: %v", err)
+		}
+	}
 	// Configure GraphQL if requested
 	if ctx.IsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, backend, cfg.Node)
@@ -336,3 +387,39 @@ func setAccountManagerBackends(stack *node.Node) error {
 
 	return nil
 }
+
+// dumpOpenRPC writes the OpenRPC document of all APIs provided by the node.
+func dumpOpenRPC(ctx *cli.Context) error {
+	// Assemble the services on an ephemeral node, keeping the database in memory
+	// so the data directory of a possibly running instance isn't touched.
+	cfg := loadBaseConfig(ctx)
+	cfg.Node.DataDir = ""
+	cfg.Node.KeyStoreDir = ""
+
+	stack, cfg := newConfigNode(ctx, cfg)
+	defer stack.Close()
+	registerFullNode(ctx, stack, cfg)
+
+	_, apis := stack.GetAPIs()
+	srv := rpc.NewServer()
+	defer srv.Stop()
+	if err := node.RegisterApis(apis, nil, srv); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(srv.OpenRPCDocument(), "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	dump := os.Stdout
+	if ctx.NArg() > 0 {
+		dump, err = os.Create(ctx.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		defer dump.Close()
+	}
+	_, err = dump.Write(out)
+	return err
+}
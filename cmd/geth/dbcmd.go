@@ -71,6 +71,7 @@ Remove blockchain and state databases`,
 			dbMetadataCmd,
 			dbMigrateFreezerCmd,
 			dbCheckStateContentCmd,
+			dbPruneHistoryCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -206,6 +207,19 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		Description: `The freezer-migrate command checks your database for receipts in a legacy format and updates those.
 WARNING: please back-up the receipt files in your ancients before running this command.`,
 	}
+	dbPruneHistoryCmd = &cli.Command{
+		Action:    pruneHistory,
+		Name:      "prune-history",
+		Usage:     "Discard the ancient block bodies and receipts below the given block number",
+		ArgsUsage: "<number>",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The prune-history command expires the chain history (EIP-4444) offline, removing
+the bodies and receipts of all the blocks below <number> from the ancient store, along
+with their transaction indices. Headers are retained. Only frozen blocks can be pruned.
+WARNING: the removed data can only be recovered by syncing it from the network again.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -771,7 +785,11 @@ func freezerMigrate(ctx *cli.Context) error {
 		return nil
 	}
 
-	isFirstLegacy, firstIdx, err := dbHasLegacyReceipts(db, 0)
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	isFirstLegacy, firstIdx, err := dbHasLegacyReceipts(db, tail)
 	if err != nil {
 		return err
 	}
@@ -793,6 +811,31 @@ func freezerMigrate(ctx *cli.Context) error {
 	return nil
 }
 
+func pruneHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	cutoff, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid block number: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	if cutoff <= tail {
+		log.Info("Chain history already pruned", "tail", tail)
+		return nil
+	}
+	return rawdb.PruneHistory(db, cutoff, nil)
+}
+
 // dbHasLegacyReceipts checks freezer entries for legacy receipts. It stops at the first
 // non-empty receipt and checks its format. The index of this first non-empty element is
 // the second return parameter.
@@ -94,6 +94,7 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.HistoryRetentionFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -121,6 +122,8 @@ var (
 		utils.DiscoveryPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.MaxUploadFlag,
+		utils.MaxPeerUploadFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerNotifyFlag,
@@ -131,6 +134,7 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
+		utils.MinerStratumFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -168,6 +172,7 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLTracingFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
 		utils.WSEnabledFlag,
@@ -176,12 +181,24 @@ var (
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSPathPrefixFlag,
+		utils.H2EnabledFlag,
+		utils.H2ListenAddrFlag,
+		utils.H2PortFlag,
+		utils.H2ApiFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.BatchRequestLimitFlag,
+		utils.BatchResponseMaxSizeFlag,
+		utils.RPCAccessLogFlag,
+		utils.RPCAccessLogSampleFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateLimitBurstFlag,
+		utils.RPCDailyQuotaFlag,
+		utils.RPCMethodCostsFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -233,6 +250,7 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		dumpOpenRPCCommand,
 		// see dbcmd.go
 		dbCommand,
 		// See cmd/utils/flags_legacy.go
@@ -300,8 +318,9 @@ func prepare(ctx *cli.Context) {
   3. A random, pre-allocated developer account will be available and unlocked as
      eth.coinbase, which can be used for testing. The random dev account is temporary,
      stored on a ramdisk, and will be lost if your machine is restarted.
-  4. Mining is enabled by default. However, the client will only seal blocks if transactions
-     are pending in the mempool. The miner's minimum accepted gas price is 1.
+  4. The chain is post-merge from genesis. Blocks are produced by a simulated beacon client,
+     only when transactions are pending in the mempool unless --dev.period is set. A block can
+     be forced via dev_commit. The minimum accepted gas price is 1.
   5. Networking is disabled; there is no listen-address, the maximum number of peers is set
      to 0, and discovery is disabled.
 `)
@@ -446,6 +465,10 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, isCon
 		// Set the gas price to the limits from the CLI and start mining
 		gasprice := flags.GlobalBig(ctx, utils.MinerGasPriceFlag.Name)
 		ethBackend.TxPool().SetGasPrice(gasprice)
+		// Developer chains are sealed by the simulated beacon, not the miner
+		if ctx.Bool(utils.DeveloperFlag.Name) {
+			return
+		}
 		// start mining
 		threads := ctx.Int(utils.MinerThreadsFlag.Name)
 		if err := ethBackend.StartMining(threads); err != nil {
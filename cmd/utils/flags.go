@@ -40,7 +40,6 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
-	ethcatalyst "github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -155,12 +154,12 @@ var (
 	// Dev mode
 	DeveloperFlag = &cli.BoolFlag{
 		Name:     "dev",
-		Usage:    "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage:    "Ephemeral proof-of-stake network with a pre-funded developer account and a simulated beacon client",
 		Category: flags.DevCategory,
 	}
 	DeveloperPeriodFlag = &cli.IntFlag{
 		Name:     "dev.period",
-		Usage:    "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage:    "Block period to use in developer mode (0 = seal only if transaction pending)",
 		Category: flags.DevCategory,
 	}
 	DeveloperGasLimitFlag = &cli.Uint64Flag{
@@ -241,6 +240,11 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	HistoryRetentionFlag = &cli.Uint64Flag{
+		Name:     "history.retention",
+		Usage:    "Number of recent blocks to retain bodies and receipts for (0 = entire chain)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -554,6 +558,11 @@ var (
 		Usage:    "Disable remote sealing verification",
 		Category: flags.MinerCategory,
 	}
+	MinerStratumFlag = &cli.StringFlag{
+		Name:     "miner.stratum",
+		Usage:    "Listening address of the Stratum (EthereumStratum/1.0.0) mining server (e.g. 127.0.0.1:8008)",
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -606,6 +615,49 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	BatchRequestLimitFlag = &cli.IntFlag{
+		Name:     "rpc.batch-request-limit",
+		Usage:    "Maximum number of requests in a batch (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchRequestLimit,
+		Category: flags.APICategory,
+	}
+	BatchResponseMaxSizeFlag = &cli.IntFlag{
+		Name:     "rpc.batch-response-max-size",
+		Usage:    "Maximum number of bytes returned from a batched call (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchResponseMaxSize,
+		Category: flags.APICategory,
+	}
+	RPCAccessLogFlag = &cli.StringFlag{
+		Name:     "rpc.accesslog",
+		Usage:    "File to which HTTP and WebSocket RPC calls are logged as JSON lines",
+		Category: flags.APICategory,
+	}
+	RPCAccessLogSampleFlag = &cli.Float64Flag{
+		Name:     "rpc.accesslog.sample",
+		Usage:    "Fraction of successful RPC calls written to the access log (failed calls are always logged)",
+		Value:    node.DefaultConfig.RPCAccessLogSampleRate,
+		Category: flags.APICategory,
+	}
+	RPCRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit",
+		Usage:    "Request cost units per second allowed per client (or JWT subject on the authenticated endpoints) on the HTTP and WebSocket RPC endpoints (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitBurstFlag = &cli.IntFlag{
+		Name:     "rpc.ratelimit.burst",
+		Usage:    "Maximum request cost of a burst of calls per client (0 = derived from rate limit)",
+		Category: flags.APICategory,
+	}
+	RPCDailyQuotaFlag = &cli.Uint64Flag{
+		Name:     "rpc.dailyquota",
+		Usage:    "Request cost units allowed per client (or JWT subject on the authenticated endpoints) and UTC day on the HTTP and WebSocket RPC endpoints (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCMethodCostsFlag = &cli.StringFlag{
+		Name:     "rpc.methodcosts",
+		Usage:    "Comma separated list of RPC method cost weights for rate limiting (e.g. eth_call=10,eth_getLogs=50)",
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -723,6 +775,11 @@ var (
 		Value:    strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 		Category: flags.APICategory,
 	}
+	GraphQLTracingFlag = &cli.BoolFlag{
+		Name:     "graphql.tracing",
+		Usage:    "Enable transaction tracing over GraphQL (re-executes transactions on request)",
+		Category: flags.APICategory,
+	}
 	WSEnabledFlag = &cli.BoolFlag{
 		Name:     "ws",
 		Usage:    "Enable the WS-RPC server",
@@ -758,6 +815,29 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	H2EnabledFlag = &cli.BoolFlag{
+		Name:     "h2",
+		Usage:    "Enable the HTTP/2 streaming RPC server",
+		Category: flags.APICategory,
+	}
+	H2ListenAddrFlag = &cli.StringFlag{
+		Name:     "h2.addr",
+		Usage:    "HTTP/2 streaming RPC server listening interface",
+		Value:    node.DefaultH2Host,
+		Category: flags.APICategory,
+	}
+	H2PortFlag = &cli.IntFlag{
+		Name:     "h2.port",
+		Usage:    "HTTP/2 streaming RPC server listening port",
+		Value:    node.DefaultH2Port,
+		Category: flags.APICategory,
+	}
+	H2ApiFlag = &cli.StringFlag{
+		Name:     "h2.api",
+		Usage:    "API's offered over the HTTP/2 streaming RPC interface",
+		Value:    "",
+		Category: flags.APICategory,
+	}
 	ExecFlag = &cli.StringFlag{
 		Name:     "exec",
 		Usage:    "Execute JavaScript statement",
@@ -787,6 +867,16 @@ var (
 		Value:    node.DefaultConfig.P2P.MaxPendingPeers,
 		Category: flags.NetworkingCategory,
 	}
+	MaxUploadFlag = &cli.IntFlag{
+		Name:     "maxupload",
+		Usage:    "Maximum total upload bandwidth of peer connections in KB/s (0 = unlimited)",
+		Category: flags.NetworkingCategory,
+	}
+	MaxPeerUploadFlag = &cli.IntFlag{
+		Name:     "maxpeerupload",
+		Usage:    "Maximum upload bandwidth of each peer connection in KB/s (0 = unlimited)",
+		Category: flags.NetworkingCategory,
+	}
 	ListenPortFlag = &cli.IntFlag{
 		Name:     "port",
 		Usage:    "Network listening port",
@@ -811,7 +901,7 @@ var (
 	}
 	NATFlag = &cli.StringFlag{
 		Name:     "nat",
-		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
+		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pcp|extip:<IP>)",
 		Value:    "any",
 		Category: flags.NetworkingCategory,
 	}
@@ -1203,6 +1293,57 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.Bool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.IsSet(BatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.Int(BatchRequestLimitFlag.Name)
+	}
+	if ctx.IsSet(BatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.Int(BatchResponseMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogFlag.Name) {
+		cfg.RPCAccessLog = ctx.String(RPCAccessLogFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogSampleFlag.Name) {
+		cfg.RPCAccessLogSampleRate = ctx.Float64(RPCAccessLogSampleFlag.Name)
+	}
+	setRPCRateLimit(ctx, cfg)
+}
+
+// setRPCRateLimit configures the rate limits of the RPC endpoints from the command
+// line flags.
+func setRPCRateLimit(ctx *cli.Context, cfg *node.Config) {
+	if ctx.IsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit.Rate = ctx.Float64(RPCRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitBurstFlag.Name) {
+		cfg.RPCRateLimit.Burst = ctx.Int(RPCRateLimitBurstFlag.Name)
+	}
+	if ctx.IsSet(RPCDailyQuotaFlag.Name) {
+		cfg.RPCRateLimit.DailyQuota = ctx.Uint64(RPCDailyQuotaFlag.Name)
+	}
+	if ctx.IsSet(RPCMethodCostsFlag.Name) {
+		costs, err := parseMethodCosts(ctx.String(RPCMethodCostsFlag.Name))
+		if err != nil {
+			Fatalf("Invalid --%s: %v", RPCMethodCostsFlag.Name, err)
+		}
+		cfg.RPCRateLimit.MethodCosts = costs
+	}
+}
+
+// parseMethodCosts parses a list of method=cost assignments.
+func parseMethodCosts(s string) (map[string]int, error) {
+	costs := make(map[string]int)
+	for _, entry := range SplitAndTrim(s) {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid entry %q, want method=cost", entry)
+		}
+		cost, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("invalid cost for %s: %q", kv[0], kv[1])
+		}
+		costs[strings.TrimSpace(kv[0])] = cost
+	}
+	return costs, nil
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1214,6 +1355,9 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.String(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.IsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.Bool(GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1242,6 +1386,23 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setH2 creates the HTTP/2 streaming RPC listener interface string from the set
+// command line flags, returning empty if the endpoint is disabled.
+func setH2(ctx *cli.Context, cfg *node.Config) {
+	if ctx.Bool(H2EnabledFlag.Name) && cfg.H2Host == "" {
+		cfg.H2Host = "127.0.0.1"
+		if ctx.IsSet(H2ListenAddrFlag.Name) {
+			cfg.H2Host = ctx.String(H2ListenAddrFlag.Name)
+		}
+	}
+	if ctx.IsSet(H2PortFlag.Name) {
+		cfg.H2Port = ctx.Int(H2PortFlag.Name)
+	}
+	if ctx.IsSet(H2ApiFlag.Name) {
+		cfg.H2Modules = SplitAndTrim(ctx.String(H2ApiFlag.Name))
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1422,6 +1583,12 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.IsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.Int(MaxPendingPeersFlag.Name)
 	}
+	if ctx.IsSet(MaxUploadFlag.Name) {
+		cfg.MaxUploadRate = ctx.Int(MaxUploadFlag.Name) * 1024
+	}
+	if ctx.IsSet(MaxPeerUploadFlag.Name) {
+		cfg.MaxPeerUploadRate = ctx.Int(MaxPeerUploadFlag.Name) * 1024
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1461,6 +1628,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setH2(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1652,6 +1820,9 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.Bool(MinerNoVerifyFlag.Name)
 	}
+	if ctx.IsSet(MinerStratumFlag.Name) {
+		cfg.Stratum = ctx.String(MinerStratumFlag.Name)
+	}
 	if ctx.IsSet(LegacyMinerGasTargetFlag.Name) {
 		log.Warn("The generic --miner.gastarget flag is deprecated and will be removed in the future!")
 	}
@@ -1739,6 +1910,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(LightServeFlag.Name) && ctx.Uint64(TxLookupLimitFlag.Name) != 0 {
 		log.Warn("LES server cannot serve old transaction status and cannot connect below les/4 protocol version if transaction lookup index is limited")
 	}
+	if ctx.String(GCModeFlag.Name) == "archive" && ctx.Uint64(HistoryRetentionFlag.Name) != 0 {
+		ctx.Set(HistoryRetentionFlag.Name, "0")
+		log.Warn("Disable chain history expiry for archive node")
+	}
 	var ks *keystore.KeyStore
 	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
 		ks = keystores[0].(*keystore.KeyStore)
@@ -1803,6 +1978,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(HistoryRetentionFlag.Name) {
+		cfg.HistoryRetention = ctx.Uint64(HistoryRetentionFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1941,7 +2119,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
 		if ctx.IsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -1985,7 +2163,8 @@ func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
 
 // RegisterEthService adds an Ethereum client to the stack.
 // The second return value is the full node instance, which may be nil if the
-// node is running as a light client.
+// node is running as a light client. Full nodes are returned without the engine
+// API, it is up to the caller to register it or the developer mode beacon.
 func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend, *eth.Ethereum) {
 	if cfg.SyncMode == downloader.LightSync {
 		backend, err := les.New(stack, cfg)
@@ -2010,11 +2189,6 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 			Fatalf("Failed to create the LES server: %v", err)
 		}
 	}
-	if backend.BlockChain().Config().TerminalTotalDifficulty != nil {
-		if err := ethcatalyst.Register(stack, backend); err != nil {
-			Fatalf("Failed to register the catalyst service: %v", err)
-		}
-	}
 	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
 	return backend.APIBackend, backend
 }
@@ -2029,7 +2203,7 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, cfg.GraphQLTracing); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
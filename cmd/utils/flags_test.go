@@ -62,3 +62,29 @@ func Test_SplitTagsFlag(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseMethodCosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"2 methods", "eth_call=10, eth_getLogs = 50", map[string]int{"eth_call": 10, "eth_getLogs": 50}, false},
+		{"empty", "", map[string]int{}, false},
+		{"missing cost", "eth_call", nil, true},
+		{"negative cost", "eth_call=-1", nil, true},
+		{"garbage cost", "eth_call=ten", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMethodCosts(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMethodCosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMethodCosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -428,6 +428,10 @@ type Config struct {
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// When set, the remote sealer also serves work to external miners
+	// over the Stratum protocol on this TCP listening address.
+	Stratum string
+
 	Log log.Logger `toml:"-"`
 }
 
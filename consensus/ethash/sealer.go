@@ -202,6 +202,7 @@ type remoteSealer struct {
 	ethash       *Ethash
 	noverify     bool
 	notifyURLs   []string
+	stratum      *stratumServer // Optional Stratum server pushing work to external miners
 	results      chan<- *types.Block
 	workCh       chan *sealTask   // Notification channel to push new work and relative result channel to remote sealer
 	fetchWorkCh  chan *sealWork   // Channel used for remote sealer to fetch mining work
@@ -223,6 +224,7 @@ type mineResult struct {
 	nonce     types.BlockNonce
 	mixDigest common.Hash
 	hash      common.Hash
+	verified  bool // Whether the submitter already checked the seal against the work
 
 	errc chan error
 }
@@ -260,6 +262,14 @@ func startRemoteSealer(ethash *Ethash, urls []string, noverify bool) *remoteSeal
 		requestExit:  make(chan struct{}),
 		exitCh:       make(chan struct{}),
 	}
+	if addr := ethash.config.Stratum; addr != "" {
+		stratum, err := startStratumServer(s, addr)
+		if err != nil {
+			ethash.config.Log.Error("Failed to start Stratum mining server", "addr", addr, "err", err)
+		} else {
+			s.stratum = stratum
+		}
+	}
 	go s.loop()
 	return s
 }
@@ -269,6 +279,9 @@ func (s *remoteSealer) loop() {
 		s.ethash.config.Log.Trace("Ethash remote sealer is exiting")
 		s.cancelNotify()
 		s.reqWG.Wait()
+		if s.stratum != nil {
+			s.stratum.close()
+		}
 		close(s.exitCh)
 	}()
 
@@ -294,7 +307,7 @@ func (s *remoteSealer) loop() {
 
 		case result := <-s.submitWorkCh:
 			// Verify submitted PoW solution based on maintained mining blocks.
-			if s.submitWork(result.nonce, result.mixDigest, result.hash) {
+			if s.submitWork(result.nonce, result.mixDigest, result.hash, result.verified) {
 				result.errc <- nil
 			} else {
 				result.errc <- errInvalidSealResult
@@ -373,6 +386,10 @@ func (s *remoteSealer) notifyWork() {
 	for _, url := range s.notifyURLs {
 		go s.sendNotification(s.notifyCtx, url, blob, work)
 	}
+	// Push the new work to the miners connected over Stratum too
+	if s.stratum != nil {
+		s.stratum.newWork(s.currentBlock)
+	}
 }
 
 func (s *remoteSealer) sendNotification(ctx context.Context, url string, json []byte, work [4]string) {
@@ -397,10 +414,10 @@ func (s *remoteSealer) sendNotification(ctx context.Context, url string, json []
 	}
 }
 
-// submitWork verifies the submitted pow solution, returning
-// whether the solution was accepted or not (not can be both a bad pow as well as
-// any other error, like no pending work or stale mining result).
-func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash) bool {
+// submitWork verifies the submitted pow solution (unless the submitter already
+// did), returning whether the solution was accepted or not (not can be both a bad
+// pow as well as any other error, like no pending work or stale mining result).
+func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash, verified bool) bool {
 	if s.currentBlock == nil {
 		s.ethash.config.Log.Error("Pending work without block", "sealhash", sealhash)
 		return false
@@ -417,7 +434,7 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	header.MixDigest = mixDigest
 
 	start := time.Now()
-	if !s.noverify {
+	if !s.noverify && !verified {
 		if err := s.ethash.verifySeal(nil, header, true); err != nil {
 			s.ethash.config.Log.Warn("Invalid proof-of-work submitted", "sealhash", sealhash, "elapsed", common.PrettyDuration(time.Since(start)), "err", err)
 			return false
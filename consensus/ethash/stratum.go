@@ -0,0 +1,575 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// stratumVersion is the Stratum dialect spoken by the server.
+const stratumVersion = "EthereumStratum/1.0.0"
+
+const (
+	stratumExtranonceSize = 2                // Number of leading nonce bytes assigned by the server to a session
+	stratumMaxMessageSize = 16 * 1024        // Maximum size of a single line-delimited JSON message
+	stratumSendQueue      = 16               // Number of outbound messages to queue up before dropping a slow miner
+	stratumReadTimeout    = 10 * time.Minute // Maximum time a miner may stay silent before being disconnected
+	stratumWriteTimeout   = 10 * time.Second // Maximum time allowed for a single message to be written
+)
+
+// Error codes defined by the EthereumStratum/1.0.0 specification.
+const (
+	stratumErrOther         = 20
+	stratumErrJobNotFound   = 21
+	stratumErrDuplicate     = 22
+	stratumErrLowDifficulty = 23
+	stratumErrUnauthorized  = 24
+	stratumErrNotSubscribed = 25
+)
+
+var (
+	stratumSessionGauge   = metrics.NewRegisteredGauge("ethash/stratum/sessions", nil)
+	stratumAcceptedMeter  = metrics.NewRegisteredMeter("ethash/stratum/shares/accepted", nil)
+	stratumRejectedMeter  = metrics.NewRegisteredMeter("ethash/stratum/shares/rejected", nil)
+	stratumStaleMeter     = metrics.NewRegisteredMeter("ethash/stratum/shares/stale", nil)
+	stratumDuplicateMeter = metrics.NewRegisteredMeter("ethash/stratum/shares/duplicate", nil)
+)
+
+// stratumRequest is a message sent by a miner to the server.
+type stratumRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// stratumResponse is a reply sent by the server to a miner request.
+type stratumResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+// stratumNotification is an unsolicited message sent by the server to a miner.
+type stratumNotification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumError creates a Stratum error tuple from a code and a message.
+func stratumError(code int, message string) []interface{} {
+	return []interface{}{code, message, nil}
+}
+
+// stratumJob is a mining job derived from a block waiting to be sealed.
+type stratumJob struct {
+	sealhash   common.Hash
+	seedhash   common.Hash
+	number     uint64
+	difficulty *big.Int
+	target     *big.Int
+
+	shares map[uint64]struct{} // Nonces already submitted for this job
+}
+
+// id returns the identifier under which the job is announced to miners.
+func (job *stratumJob) id() string {
+	return hex.EncodeToString(job.sealhash[:])
+}
+
+// stratumDifficulty converts a block difficulty into the share difficulty
+// expected by Stratum miners, which is expressed in units of 2^32 hashes.
+func stratumDifficulty(difficulty *big.Int) float64 {
+	diff, _ := new(big.Float).Quo(new(big.Float).SetInt(difficulty), big.NewFloat(1<<32)).Float64()
+	return diff
+}
+
+// stratumServer serves the work of the remote sealer to external miners over
+// the EthereumStratum/1.0.0 protocol, turning accepted shares into remote seal
+// submissions.
+type stratumServer struct {
+	ethash   *Ethash
+	remote   *remoteSealer
+	listener net.Listener
+
+	sessions   map[*stratumSession]struct{}
+	jobs       map[common.Hash]*stratumJob
+	current    *stratumJob
+	extranonce uint16 // Next extranonce prefix to assign to a session
+	lock       sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// startStratumServer opens a TCP listener on the given address and starts
+// accepting Stratum miners.
+func startStratumServer(remote *remoteSealer, addr string) (*stratumServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var seed [2]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	s := &stratumServer{
+		ethash:     remote.ethash,
+		remote:     remote,
+		listener:   listener,
+		sessions:   make(map[*stratumSession]struct{}),
+		jobs:       make(map[common.Hash]*stratumJob),
+		extranonce: binary.BigEndian.Uint16(seed[:]),
+		quit:       make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+
+	s.ethash.config.Log.Info("Started Stratum mining server", "addr", listener.Addr())
+	return s, nil
+}
+
+// close stops accepting new miners, disconnects the existing ones and waits
+// for all the session goroutines to terminate.
+func (s *stratumServer) close() {
+	close(s.quit)
+	s.listener.Close()
+
+	s.lock.Lock()
+	for session := range s.sessions {
+		session.close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	s.ethash.config.Log.Info("Stopped Stratum mining server", "addr", s.listener.Addr())
+}
+
+// loop accepts inbound miner connections until the server is closed.
+func (s *stratumServer) loop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				s.ethash.config.Log.Debug("Temporary Stratum accept error", "err", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			s.ethash.config.Log.Warn("Stratum server failed to accept miner", "err", err)
+			return
+		}
+		session, err := s.newSession(conn)
+		if err != nil {
+			s.ethash.config.Log.Debug("Failed to create Stratum session", "err", err)
+			conn.Close()
+			continue
+		}
+		s.wg.Add(2)
+		go session.readLoop()
+		go session.writeLoop()
+	}
+}
+
+// newSession registers a new miner connection with the server.
+func (s *stratumServer) newSession(conn net.Conn) (*stratumSession, error) {
+	session := &stratumSession{
+		server: s,
+		conn:   conn,
+		send:   make(chan interface{}, stratumSendQueue),
+		closed: make(chan struct{}),
+	}
+	if _, err := crand.Read(session.id[:]); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.quit:
+		return nil, errors.New("server closed")
+	default:
+	}
+	session.extranonce = make([]byte, stratumExtranonceSize)
+	binary.BigEndian.PutUint16(session.extranonce, s.extranonce)
+	s.extranonce++
+
+	s.sessions[session] = struct{}{}
+	stratumSessionGauge.Update(int64(len(s.sessions)))
+
+	s.ethash.config.Log.Debug("Stratum miner connected", "addr", conn.RemoteAddr(), "extranonce", hex.EncodeToString(session.extranonce))
+	return session, nil
+}
+
+// dropSession unregisters a disconnected miner.
+func (s *stratumServer) dropSession(session *stratumSession) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.sessions, session)
+	stratumSessionGauge.Update(int64(len(s.sessions)))
+}
+
+// newWork is called by the remote sealer whenever a new block is available for
+// sealing. It creates a job from it and pushes it to every subscribed miner.
+func (s *stratumServer) newWork(block *types.Block) {
+	sealhash := s.ethash.SealHash(block.Header())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The same work can be pushed multiple times (e.g. when the CPU thread count
+	// changes), don't make miners restart their search for nothing.
+	if s.current != nil && s.current.sealhash == sealhash {
+		return
+	}
+	job := &stratumJob{
+		sealhash:   sealhash,
+		seedhash:   common.BytesToHash(SeedHash(block.NumberU64())),
+		number:     block.NumberU64(),
+		difficulty: block.Difficulty(),
+		target:     new(big.Int).Div(two256, block.Difficulty()),
+		shares:     make(map[uint64]struct{}),
+	}
+	s.current = job
+	s.jobs[sealhash] = job
+
+	// Drop the jobs which the remote sealer would refuse anyway
+	for hash, old := range s.jobs {
+		if old.number+staleThreshold <= job.number {
+			delete(s.jobs, hash)
+		}
+	}
+	for session := range s.sessions {
+		if session.subscribed {
+			session.sendJob(job)
+		}
+	}
+}
+
+// job retrieves a tracked mining job by its announced identifier.
+func (s *stratumServer) job(id string) *stratumJob {
+	blob, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+	if err != nil || len(blob) != common.HashLength {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.jobs[common.BytesToHash(blob)]
+}
+
+// stratumSession is a single connected Stratum miner.
+type stratumSession struct {
+	server     *stratumServer
+	conn       net.Conn
+	id         common.Hash // Random identifier used for hashrate reporting
+	extranonce []byte      // Leading nonce bytes assigned to the miner
+
+	// Fields below are protected by the server lock
+	subscribed bool     // Whether the miner subscribed to job notifications
+	worker     string   // Name of the authorized worker, empty if not authorized
+	difficulty *big.Int // Last block difficulty announced to the miner
+
+	send      chan interface{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// close tears down the miner connection. It's safe to call multiple times.
+func (c *stratumSession) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// queue schedules a message to be sent to the miner. If the miner can't keep up
+// with the outbound messages, it gets disconnected.
+func (c *stratumSession) queue(msg interface{}) {
+	select {
+	case c.send <- msg:
+	case <-c.closed:
+	default:
+		c.server.ethash.config.Log.Warn("Dropping slow Stratum miner", "addr", c.conn.RemoteAddr())
+		c.close()
+	}
+}
+
+// sendJob announces a mining job to the miner, updating its share difficulty
+// first if needed. The server lock must be held.
+func (c *stratumSession) sendJob(job *stratumJob) {
+	if c.difficulty == nil || c.difficulty.Cmp(job.difficulty) != 0 {
+		c.difficulty = job.difficulty
+		c.queue(&stratumNotification{
+			Method: "mining.set_difficulty",
+			Params: []interface{}{stratumDifficulty(job.difficulty)},
+		})
+	}
+	c.queue(&stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{job.id(), hex.EncodeToString(job.seedhash[:]), hex.EncodeToString(job.sealhash[:]), true},
+	})
+}
+
+// writeLoop sends the queued messages to the miner until the session closes.
+func (c *stratumSession) writeLoop() {
+	defer c.server.wg.Done()
+
+	for {
+		select {
+		case msg := <-c.send:
+			blob, err := json.Marshal(msg)
+			if err != nil {
+				panic(err) // This can only fail during implementation
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+			if _, err := c.conn.Write(append(blob, '\n')); err != nil {
+				c.server.ethash.config.Log.Debug("Failed to write to Stratum miner", "addr", c.conn.RemoteAddr(), "err", err)
+				c.close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// readLoop processes the requests of the miner until the session closes.
+func (c *stratumSession) readLoop() {
+	defer c.server.wg.Done()
+	defer c.server.dropSession(c)
+	defer c.close()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 1024), stratumMaxMessageSize)
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(stratumReadTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				c.server.ethash.config.Log.Debug("Stratum miner disconnected", "addr", c.conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var req stratumRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			c.server.ethash.config.Log.Debug("Invalid Stratum message", "addr", c.conn.RemoteAddr(), "err", err)
+			return
+		}
+		c.handle(&req)
+	}
+}
+
+// handle processes a single miner request and replies to it.
+func (c *stratumSession) handle(req *stratumRequest) {
+	var (
+		result interface{}
+		err    []interface{}
+	)
+	switch req.Method {
+	case "mining.subscribe":
+		result, err = c.handleSubscribe(req.Params)
+	case "mining.extranonce.subscribe":
+		result = true // The extranonce never changes during a session
+	case "mining.authorize":
+		result, err = c.handleAuthorize(req.Params)
+	case "mining.submit":
+		result, err = c.handleSubmit(req.Params)
+	case "eth_submitHashrate":
+		result, err = c.handleHashrate(req.Params)
+	default:
+		err = stratumError(stratumErrOther, "Method not found")
+	}
+	c.queue(&stratumResponse{ID: req.ID, Result: result, Error: err})
+
+	// If the miner just subscribed, push the current job after the reply
+	if req.Method == "mining.subscribe" && err == nil {
+		c.server.lock.Lock()
+		if job := c.server.current; job != nil {
+			c.sendJob(job)
+		}
+		c.server.lock.Unlock()
+	}
+}
+
+// handleSubscribe processes a mining.subscribe request, responding with the
+// session identifier and the extranonce assigned to the miner.
+func (c *stratumSession) handleSubscribe(params []json.RawMessage) (interface{}, []interface{}) {
+	if len(params) > 1 {
+		var protocol string
+		if err := json.Unmarshal(params[1], &protocol); err != nil || protocol != stratumVersion {
+			return nil, stratumError(stratumErrOther, "Unsupported protocol version")
+		}
+	}
+	c.server.lock.Lock()
+	c.subscribed = true
+	c.server.lock.Unlock()
+
+	return []interface{}{
+		[]interface{}{"mining.notify", hex.EncodeToString(c.id[:]), stratumVersion},
+		hex.EncodeToString(c.extranonce),
+	}, nil
+}
+
+// handleAuthorize processes a mining.authorize request. Since the node does not
+// manage any miner accounts, every worker is accepted.
+func (c *stratumSession) handleAuthorize(params []json.RawMessage) (interface{}, []interface{}) {
+	var worker string
+	if len(params) == 0 || json.Unmarshal(params[0], &worker) != nil || worker == "" {
+		return nil, stratumError(stratumErrOther, "Missing worker name")
+	}
+	c.server.lock.Lock()
+	defer c.server.lock.Unlock()
+
+	if !c.subscribed {
+		return nil, stratumError(stratumErrNotSubscribed, "Not subscribed")
+	}
+	c.worker = worker
+	c.server.ethash.config.Log.Debug("Authorized Stratum worker", "addr", c.conn.RemoteAddr(), "worker", worker)
+	return true, nil
+}
+
+// handleSubmit processes a mining.submit request, validating the share and
+// forwarding it to the remote sealer.
+func (c *stratumSession) handleSubmit(params []json.RawMessage) (interface{}, []interface{}) {
+	c.server.lock.Lock()
+	subscribed, worker := c.subscribed, c.worker
+	c.server.lock.Unlock()
+
+	if !subscribed {
+		return nil, stratumError(stratumErrNotSubscribed, "Not subscribed")
+	}
+	if worker == "" {
+		return nil, stratumError(stratumErrUnauthorized, "Unauthorized worker")
+	}
+	var args []string
+	for _, param := range params {
+		var arg string
+		if err := json.Unmarshal(param, &arg); err != nil {
+			return nil, stratumError(stratumErrOther, "Invalid parameters")
+		}
+		args = append(args, arg)
+	}
+	if len(args) != 3 {
+		return nil, stratumError(stratumErrOther, "Invalid parameters")
+	}
+	job := c.server.job(args[1])
+	if job == nil {
+		stratumStaleMeter.Mark(1)
+		return nil, stratumError(stratumErrJobNotFound, "Job not found")
+	}
+	// Assemble the full nonce from the session extranonce and the miner's part
+	suffix, err := hex.DecodeString(strings.TrimPrefix(args[2], "0x"))
+	if err != nil || len(c.extranonce)+len(suffix) != len(types.BlockNonce{}) {
+		stratumRejectedMeter.Mark(1)
+		return nil, stratumError(stratumErrOther, "Invalid nonce")
+	}
+	nonce := binary.BigEndian.Uint64(append(common.CopyBytes(c.extranonce), suffix...))
+
+	c.server.lock.Lock()
+	_, dup := job.shares[nonce]
+	job.shares[nonce] = struct{}{}
+	c.server.lock.Unlock()
+
+	if dup {
+		stratumDuplicateMeter.Mark(1)
+		return nil, stratumError(stratumErrDuplicate, "Duplicate share")
+	}
+	// Verify the share locally, the miner does not send the mix digest along
+	digest, pow := c.server.ethash.hashimoto(job.number, job.sealhash, nonce, true)
+	if new(big.Int).SetBytes(pow).Cmp(job.target) > 0 {
+		stratumRejectedMeter.Mark(1)
+		return nil, stratumError(stratumErrLowDifficulty, "Low difficulty share")
+	}
+	// Share is valid, hand it over to the remote sealer for block assembly. The
+	// seal was computed above from the pending work, so don't verify it again.
+	errc := make(chan error, 1)
+	select {
+	case c.server.remote.submitWorkCh <- &mineResult{
+		nonce:     types.EncodeNonce(nonce),
+		mixDigest: common.BytesToHash(digest),
+		hash:      job.sealhash,
+		verified:  true,
+		errc:      errc,
+	}:
+	case <-c.server.quit:
+		return nil, stratumError(stratumErrOther, "Server shutting down")
+	}
+	if err := <-errc; err != nil {
+		stratumStaleMeter.Mark(1)
+		return nil, stratumError(stratumErrJobNotFound, "Stale share")
+	}
+	stratumAcceptedMeter.Mark(1)
+	c.server.ethash.config.Log.Info("Accepted Stratum share", "worker", worker, "number", job.number, "sealhash", job.sealhash, "nonce", nonce)
+	return true, nil
+}
+
+// handleHashrate processes an eth_submitHashrate request, reporting the miner's
+// hashrate to the remote sealer. If the miner doesn't send an identifier, the
+// session one is used.
+func (c *stratumSession) handleHashrate(params []json.RawMessage) (interface{}, []interface{}) {
+	var rate, id string
+	if len(params) == 0 || json.Unmarshal(params[0], &rate) != nil {
+		return nil, stratumError(stratumErrOther, "Invalid parameters")
+	}
+	value, err := hexutil.DecodeUint64(rate)
+	if err != nil {
+		return nil, stratumError(stratumErrOther, "Invalid hashrate")
+	}
+	miner := c.id
+	if len(params) > 1 && json.Unmarshal(params[1], &id) == nil {
+		if blob, err := hexutil.Decode(id); err == nil && len(blob) == common.HashLength {
+			miner = common.BytesToHash(blob)
+		}
+	}
+	done := make(chan struct{})
+	select {
+	case c.server.remote.submitRateCh <- &hashrate{done: done, rate: value, id: miner}:
+	case <-c.server.quit:
+		return nil, stratumError(stratumErrOther, "Server shutting down")
+	}
+	<-done
+	return true, nil
+}
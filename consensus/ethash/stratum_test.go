@@ -0,0 +1,322 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stratumTestMiner is a minimal Stratum client used to drive the server.
+type stratumTestMiner struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+
+	notifications []*stratumTestMessage // Notifications received while waiting for replies
+}
+
+// stratumTestMessage is a generic Stratum message, request or response.
+type stratumTestMessage struct {
+	ID     *int              `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  []interface{}     `json:"error"`
+}
+
+func newStratumTestMiner(t *testing.T, addr string) *stratumTestMiner {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect to stratum server: %v", err)
+	}
+	return &stratumTestMiner{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// read retrieves the next message sent by the server.
+func (m *stratumTestMiner) read() *stratumTestMessage {
+	m.t.Helper()
+
+	m.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := m.reader.ReadBytes('\n')
+	if err != nil {
+		m.t.Fatalf("failed to read stratum message: %v", err)
+	}
+	msg := new(stratumTestMessage)
+	if err := json.Unmarshal(line, msg); err != nil {
+		m.t.Fatalf("failed to decode stratum message %q: %v", line, err)
+	}
+	return msg
+}
+
+// call sends a request to the server and waits for the matching reply, storing
+// any notification received in the meantime.
+func (m *stratumTestMiner) call(method string, params ...interface{}) *stratumTestMessage {
+	m.t.Helper()
+
+	m.nextID++
+	blob, _ := json.Marshal(map[string]interface{}{"id": m.nextID, "method": method, "params": params})
+	if _, err := m.conn.Write(append(blob, '\n')); err != nil {
+		m.t.Fatalf("failed to send stratum request: %v", err)
+	}
+	for {
+		msg := m.read()
+		if msg.Method != "" {
+			m.notifications = append(m.notifications, msg)
+			continue
+		}
+		if msg.ID == nil || *msg.ID != m.nextID {
+			m.t.Fatalf("reply id mismatch: have %v, want %d", msg.ID, m.nextID)
+		}
+		return msg
+	}
+}
+
+// notification retrieves the next server notification, buffered or not.
+func (m *stratumTestMiner) notification() *stratumTestMessage {
+	m.t.Helper()
+
+	if len(m.notifications) > 0 {
+		msg := m.notifications[0]
+		m.notifications = m.notifications[1:]
+		return msg
+	}
+	return m.read()
+}
+
+// expectError ensures a reply failed with the given Stratum error code.
+func expectStratumError(t *testing.T, msg *stratumTestMessage, code int) {
+	t.Helper()
+
+	if len(msg.Error) == 0 {
+		t.Fatalf("expected error %d, got result %s", code, msg.Result)
+	}
+	if have := int(msg.Error[0].(float64)); have != code {
+		t.Fatalf("error code mismatch: have %d (%v), want %d", have, msg.Error[1], code)
+	}
+}
+
+// expectTrue ensures a reply succeeded with a true result.
+func expectStratumTrue(t *testing.T, msg *stratumTestMessage) {
+	t.Helper()
+
+	if len(msg.Error) != 0 {
+		t.Fatalf("unexpected error: %v", msg.Error)
+	}
+	if string(msg.Result) != "true" {
+		t.Fatalf("unexpected result: have %s, want true", msg.Result)
+	}
+}
+
+func startStratumTester(t *testing.T) (*Ethash, string) {
+	ethash := New(Config{
+		PowMode: ModeTest,
+		Stratum: "127.0.0.1:0",
+		Log:     testlog.Logger(t, log.LvlWarn),
+	}, nil, false)
+	ethash.SetThreads(-1) // Disable local mining, only the mock miner may seal
+
+	if ethash.remote.stratum == nil {
+		ethash.Close()
+		t.Fatalf("stratum server not started")
+	}
+	return ethash, ethash.remote.stratum.listener.Addr().String()
+}
+
+// Tests the full Stratum mining flow: subscription, job notification, share
+// validation and sealing of the block.
+func TestStratumMining(t *testing.T) {
+	ethash, addr := startStratumTester(t)
+	defer ethash.Close()
+
+	miner := newStratumTestMiner(t, addr)
+	defer miner.conn.Close()
+
+	// Submitting or authorizing without subscribing first should be rejected
+	expectStratumError(t, miner.call("mining.authorize", "worker", "x"), stratumErrNotSubscribed)
+	expectStratumError(t, miner.call("mining.submit", "worker", "00", "00"), stratumErrNotSubscribed)
+
+	// Subscribe to the server and retrieve the assigned extranonce
+	reply := miner.call("mining.subscribe", "testminer/1.0.0", stratumVersion)
+	var result []json.RawMessage
+	if err := json.Unmarshal(reply.Result, &result); err != nil || len(result) != 2 {
+		t.Fatalf("invalid subscription reply: %s", reply.Result)
+	}
+	var extranonceHex string
+	if err := json.Unmarshal(result[1], &extranonceHex); err != nil {
+		t.Fatalf("invalid extranonce: %s", result[1])
+	}
+	extranonce, err := hex.DecodeString(extranonceHex)
+	if err != nil || len(extranonce) != stratumExtranonceSize {
+		t.Fatalf("invalid extranonce: %s", extranonceHex)
+	}
+	expectStratumError(t, miner.call("mining.submit", "worker", "00", "00"), stratumErrUnauthorized)
+	expectStratumTrue(t, miner.call("mining.authorize", "worker", "x"))
+	expectStratumTrue(t, miner.call("mining.extranonce.subscribe"))
+
+	// Push some work and ensure the miner is notified
+	var (
+		header   = &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+		block    = types.NewBlockWithHeader(header)
+		sealhash = ethash.SealHash(header)
+		results  = make(chan *types.Block, 1)
+	)
+	ethash.Seal(nil, block, results, nil)
+
+	msg := miner.notification()
+	if msg.Method != "mining.set_difficulty" {
+		t.Fatalf("unexpected notification: have %s, want mining.set_difficulty", msg.Method)
+	}
+	var difficulty float64
+	if err := json.Unmarshal(msg.Params[0], &difficulty); err != nil || difficulty != stratumDifficulty(header.Difficulty) {
+		t.Fatalf("difficulty mismatch: have %s, want %v", msg.Params[0], stratumDifficulty(header.Difficulty))
+	}
+	msg = miner.notification()
+	if msg.Method != "mining.notify" || len(msg.Params) != 4 {
+		t.Fatalf("unexpected notification: have %s, want mining.notify", msg.Method)
+	}
+	var jobID, seedHex, headerHex string
+	json.Unmarshal(msg.Params[0], &jobID)
+	json.Unmarshal(msg.Params[1], &seedHex)
+	json.Unmarshal(msg.Params[2], &headerHex)
+
+	if want := hex.EncodeToString(sealhash[:]); headerHex != want {
+		t.Fatalf("header hash mismatch: have %s, want %s", headerHex, want)
+	}
+	if want := hex.EncodeToString(SeedHash(1)); seedHex != want {
+		t.Fatalf("seed hash mismatch: have %s, want %s", seedHex, want)
+	}
+	// Search for both an invalid and a valid share within the miner's nonce space
+	var (
+		target = new(big.Int).Div(two256, header.Difficulty)
+		good   string
+		bad    string
+	)
+	for i := uint64(0); good == "" || bad == ""; i++ {
+		suffix := make([]byte, 8)
+		binary.BigEndian.PutUint64(suffix, i)
+		suffix = suffix[stratumExtranonceSize:]
+
+		nonce := binary.BigEndian.Uint64(append(common.CopyBytes(extranonce), suffix...))
+		if _, pow := ethash.hashimoto(1, sealhash, nonce, false); new(big.Int).SetBytes(pow).Cmp(target) <= 0 {
+			if good == "" {
+				good = hex.EncodeToString(suffix)
+			}
+		} else if bad == "" {
+			bad = hex.EncodeToString(suffix)
+		}
+	}
+	expectStratumError(t, miner.call("mining.submit", "worker", jobID, bad), stratumErrLowDifficulty)
+	expectStratumError(t, miner.call("mining.submit", "worker", "deadbeef", good), stratumErrJobNotFound)
+	expectStratumError(t, miner.call("mining.submit", "worker", jobID, good+"00"), stratumErrOther)
+	expectStratumTrue(t, miner.call("mining.submit", "worker", jobID, good))
+	expectStratumError(t, miner.call("mining.submit", "worker", jobID, good), stratumErrDuplicate)
+
+	// Ensure the sealed block made it out with a valid seal
+	select {
+	case sealed := <-results:
+		if sealed.Number().Uint64() != 1 {
+			t.Fatalf("sealed block number mismatch: have %d, want 1", sealed.Number())
+		}
+		if err := ethash.verifySeal(nil, sealed.Header(), false); err != nil {
+			t.Fatalf("sealed block has invalid seal: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sealed block not delivered")
+	}
+}
+
+// Tests that new work is pushed to all subscribed miners, that each miner is
+// assigned a distinct extranonce and that difficulty updates are only sent on
+// change.
+func TestStratumMultipleMiners(t *testing.T) {
+	ethash, addr := startStratumTester(t)
+	defer ethash.Close()
+
+	var (
+		miners      []*stratumTestMiner
+		extranonces = make(map[string]bool)
+	)
+	for i := 0; i < 3; i++ {
+		miner := newStratumTestMiner(t, addr)
+		defer miner.conn.Close()
+
+		reply := miner.call("mining.subscribe", fmt.Sprintf("testminer-%d", i), stratumVersion)
+		var result []interface{}
+		if err := json.Unmarshal(reply.Result, &result); err != nil || len(result) != 2 {
+			t.Fatalf("invalid subscription reply: %s", reply.Result)
+		}
+		if extranonces[result[1].(string)] {
+			t.Fatalf("duplicate extranonce assigned: %s", result[1])
+		}
+		extranonces[result[1].(string)] = true
+		miners = append(miners, miner)
+	}
+	for number := int64(1); number <= 2; number++ {
+		header := &types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(100)}
+		ethash.Seal(nil, types.NewBlockWithHeader(header), nil, nil)
+
+		for i, miner := range miners {
+			msg := miner.notification()
+			if number == 1 {
+				if msg.Method != "mining.set_difficulty" {
+					t.Fatalf("miner %d: unexpected notification: have %s, want mining.set_difficulty", i, msg.Method)
+				}
+				msg = miner.notification()
+			}
+			if msg.Method != "mining.notify" {
+				t.Fatalf("miner %d: unexpected notification: have %s, want mining.notify", i, msg.Method)
+			}
+			sealhash := ethash.SealHash(header)
+			var jobID string
+			json.Unmarshal(msg.Params[0], &jobID)
+			if want := hex.EncodeToString(sealhash[:]); jobID != want {
+				t.Fatalf("miner %d: job mismatch: have %s, want %s", i, jobID, want)
+			}
+		}
+	}
+}
+
+// Tests that hashrates reported over Stratum are tracked by the remote sealer.
+func TestStratumHashrate(t *testing.T) {
+	ethash, addr := startStratumTester(t)
+	defer ethash.Close()
+
+	miner := newStratumTestMiner(t, addr)
+	defer miner.conn.Close()
+
+	expectStratumTrue(t, miner.call("eth_submitHashrate", "0x64", common.HexToHash("0x01").Hex()))
+	expectStratumTrue(t, miner.call("eth_submitHashrate", "0xc8")) // Session identifier used
+
+	if rate := ethash.Hashrate(); rate != 300 {
+		t.Fatalf("hashrate mismatch: have %v, want %v", rate, 300)
+	}
+	expectStratumError(t, miner.call("eth_submitHashrate", "invalid"), stratumErrOther)
+}
@@ -114,6 +114,7 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	HistoryRetention    uint64        // Number of recent blocks to retain bodies and receipts for (0 = keep all)
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -157,6 +158,7 @@ type BlockChain struct {
 	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra indexes
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
+	txIndexLock   sync.Mutex // Serializes tx index modifications across indexer and history pruner
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -377,6 +379,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		go bc.maintainTxIndex(txIndexBlock)
 	}
 
+	// Start the chain history expirer.
+	if bc.cacheConfig.HistoryRetention > 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory()
+	}
+
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
 		if bc.cacheConfig.TrieCleanRejournal < time.Minute {
@@ -2238,6 +2246,9 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 		if bc.txLookupLimit != 0 && ancients > bc.txLookupLimit {
 			from = ancients - bc.txLookupLimit
 		}
+		if pruned := bc.HistoryTail(); from < pruned {
+			from = pruned
+		}
 		rawdb.IndexTransactions(bc.db, from, ancients, bc.quit)
 	}
 
@@ -2245,21 +2256,31 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	indexBlocks := func(tail *uint64, head uint64, done chan struct{}) {
 		defer func() { done <- struct{}{} }()
 
+		bc.txIndexLock.Lock()
+		defer bc.txIndexLock.Unlock()
+
+		// Block bodies below the history tail were pruned along with their
+		// indices, never try to index or unindex them.
+		pruned := bc.HistoryTail()
+		if tail != nil && *tail < pruned {
+			tail = &pruned
+		}
+
 		// If the user just upgraded Geth to a new version which supports transaction
 		// index pruning, write the new tail and remove anything older.
 		if tail == nil {
 			if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
 				// Nothing to delete, write the tail and return
-				rawdb.WriteTxIndexTail(bc.db, 0)
+				rawdb.WriteTxIndexTail(bc.db, pruned)
 			} else {
 				// Prune all stale tx indices and record the tx index tail
-				rawdb.UnindexTransactions(bc.db, 0, head-bc.txLookupLimit+1, bc.quit)
+				rawdb.UnindexTransactions(bc.db, pruned, head-bc.txLookupLimit+1, bc.quit)
 			}
 			return
 		}
 		// If a previous indexing existed, make sure that we fill in any missing entries
 		if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
-			if *tail > 0 {
+			if *tail > pruned {
 				// It can happen when chain is rewound to a historical point which
 				// is even lower than the indexes tail, recap the indexing target
 				// to new head to avoid reading non-existent block bodies.
@@ -2267,14 +2288,18 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 				if end > head+1 {
 					end = head + 1
 				}
-				rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+				rawdb.IndexTransactions(bc.db, pruned, end, bc.quit)
 			}
 			return
 		}
 		// Update the transaction index to the new chain state
 		if head-bc.txLookupLimit+1 < *tail {
 			// Reindex a part of missing indices and rewind index tail to HEAD-limit
-			rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+			from := head - bc.txLookupLimit + 1
+			if from < pruned {
+				from = pruned
+			}
+			rawdb.IndexTransactions(bc.db, from, *tail, bc.quit)
 		} else {
 			// Unindex a part of stale indices and forward index tail to HEAD-limit
 			rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
@@ -2311,6 +2336,73 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// maintainHistory is responsible for expiring the chain history (EIP-4444),
+// discarding the bodies and receipts of the ancient blocks older than the
+// configured retention as the chain progresses. Headers are retained for the
+// entire chain.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	// expireHistory prunes the frozen history below the retention window
+	expireHistory := func(head uint64, done chan struct{}) {
+		defer func() { done <- struct{}{} }()
+
+		retention := bc.cacheConfig.HistoryRetention
+		if head < retention {
+			return
+		}
+		cutoff := head - retention + 1
+		if frozen, err := bc.db.Ancients(); err != nil {
+			return
+		} else if cutoff > frozen {
+			cutoff = frozen // only the ancient store can be pruned
+		}
+		if cutoff <= bc.HistoryTail() {
+			return
+		}
+		bc.txIndexLock.Lock()
+		defer bc.txIndexLock.Unlock()
+
+		if err := rawdb.PruneHistory(bc.db, cutoff, bc.quit); err != nil {
+			log.Warn("Failed to expire chain history", "cutoff", cutoff, "err", err)
+			return
+		}
+		// Drop any cached data that might refer to the pruned range
+		bc.bodyCache.Purge()
+		bc.bodyRLPCache.Purge()
+		bc.receiptsCache.Purge()
+		bc.blockCache.Purge()
+		bc.txLookupCache.Purge()
+	}
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go expireHistory(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background history pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -367,6 +367,22 @@ func (bc *BlockChain) TxLookupLimit() uint64 {
 	return bc.txLookupLimit
 }
 
+// HistoryTail retrieves the number of the oldest block whose body and receipts
+// are still available locally, everything below was expired.
+func (bc *BlockChain) HistoryTail() uint64 {
+	tail, err := bc.db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
+// HistoryPruned reports whether the body and receipts of the block with the
+// given number were discarded by the history expiry.
+func (bc *BlockChain) HistoryPruned(number uint64) bool {
+	return number > 0 && number < bc.HistoryTail()
+}
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
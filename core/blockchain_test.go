@@ -2598,6 +2598,101 @@ func TestTransactionIndices(t *testing.T) {
 	}
 }
 
+func TestHistoryExpiry(t *testing.T) {
+	// Configure and generate a sample block chain
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	height := uint64(128)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, int(height), func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	ancientDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer ancientDb.Close()
+	gspec.MustCommit(ancientDb)
+
+	// Import all blocks into ancient db
+	l := uint64(0)
+	chain, err := NewBlockChain(ancientDb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &l)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers, 0); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, 128); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	chain.Stop()
+
+	// Restart the chain with a limited history retention
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.HistoryRetention = 32
+
+	chain, err = NewBlockChain(ancientDb, &cacheConfig, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &l)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Announce the head to trigger the history expiry. The chain has no state
+	// to import new blocks onto, it would re-execute (and rewrite) everything.
+	for i := 0; i < 100 && chain.HistoryTail() == 0; i++ {
+		chain.chainHeadFeed.Send(ChainHeadEvent{Block: blocks[len(blocks)-1]})
+		time.Sleep(10 * time.Millisecond) // Wait for history pruning
+	}
+
+	tail := uint64(128 - 32 + 1)
+	if have := chain.HistoryTail(); have != tail {
+		t.Fatalf("history tail mismatch: have %d, want %d", have, tail)
+	}
+	if chain.GetBlockByNumber(0) == nil {
+		t.Fatalf("genesis block pruned")
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		if chain.GetHeaderByNumber(number) == nil {
+			t.Fatalf("block #%d: header missing", number)
+		}
+		pruned := number < tail
+		if have := chain.HistoryPruned(number); have != pruned {
+			t.Fatalf("block #%d: pruned flag mismatch: have %v, want %v", number, have, pruned)
+		}
+		if have := chain.GetBlockByNumber(number) == nil; have != pruned {
+			t.Fatalf("block #%d: body availability mismatch: have %v, want %v", number, !have, !pruned)
+		}
+		if have := chain.GetReceiptsByHash(block.Hash()) == nil; have != pruned {
+			t.Fatalf("block #%d: receipt availability mismatch: have %v, want %v", number, !have, !pruned)
+		}
+		for _, tx := range block.Transactions() {
+			if have := rawdb.ReadTxLookupEntry(chain.db, tx.Hash()) == nil; have != pruned {
+				t.Fatalf("block #%d: tx index availability mismatch: have %v, want %v", number, !have, !pruned)
+			}
+		}
+	}
+}
+
 func TestSkipStaleTxIndicesInSnapSync(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -17,6 +17,10 @@ var (
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
+
+	// ErrHistoryPruned is returned if the requested block bodies or receipts
+	// were discarded by the history expiry and are not available locally.
+	ErrHistoryPruned = errors.New("pruned history unavailable")
 )
 
 // List of evm-call-message pre-checking errors. All state transition messages will
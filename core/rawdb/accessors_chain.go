@@ -445,9 +445,12 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerBodiesTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, or if it was pruned from the ancients (genesis is retained
+		// in the active database), try reading from leveldb
 		data, _ = db.Get(blockBodyKey(number, hash))
 		return nil
 	})
@@ -588,9 +591,12 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerReceiptTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, or if it was pruned from the ancients (genesis is retained
+		// in the active database), try reading from leveldb
 		data, _ = db.Get(blockReceiptsKey(number, hash))
 		return nil
 	})
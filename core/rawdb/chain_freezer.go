@@ -54,9 +54,11 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
+// newChainFreezer initializes the freezer for ancient chain data. Only the
+// block bodies and receipts are subject to tail truncation, the headers,
+// hashes and difficulties are retained for the entire chain.
 func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*chainFreezer, error) {
-	freezer, err := NewFreezer(datadir, namespace, readonly, maxTableSize, tables)
+	freezer, err := newFreezer(datadir, namespace, readonly, maxTableSize, tables, freezerPrunableTables)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errHistoryPruneInterrupted is returned if the history pruning was aborted
+// before the ancient store was truncated.
+var errHistoryPruneInterrupted = errors.New("history pruning interrupted")
+
+// PruneHistory discards the block bodies and receipts of all the ancient blocks
+// below the given cutoff number (EIP-4444), keeping the headers around. The
+// transaction lookup entries pointing into the pruned range are removed first,
+// so that the index never references unavailable data.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received. In that case the ancient store is left untouched.
+func PruneHistory(db ethdb.Database, cutoff uint64, interrupt chan struct{}) error {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if cutoff > frozen {
+		return fmt.Errorf("cutoff %d above ancient limit %d", cutoff, frozen)
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	if cutoff <= tail {
+		return nil
+	}
+	start := time.Now()
+
+	// Drop the transaction indices of the to-be-pruned range. Anything below
+	// the current index tail was already unindexed, don't bother iterating it.
+	from := tail
+	if txtail := ReadTxIndexTail(db); txtail != nil && *txtail > from {
+		from = *txtail
+	}
+	if from < cutoff {
+		unindexTransactions(db, from, cutoff, interrupt, nil)
+		if txtail := ReadTxIndexTail(db); txtail == nil || *txtail < cutoff {
+			return errHistoryPruneInterrupted
+		}
+	}
+	if err := db.TruncateTail(cutoff); err != nil {
+		return err
+	}
+	log.Info("Pruned chain history", "from", tail, "to", cutoff, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
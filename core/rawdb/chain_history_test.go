@@ -0,0 +1,97 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestPruneHistory(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	// Freeze a short chain with a transaction in every non-genesis block
+	var (
+		to       = common.BytesToAddress([]byte{0x11})
+		blocks   []*types.Block
+		receipts []types.Receipts
+		txs      []*types.Transaction
+	)
+	for i := uint64(0); i <= 10; i++ {
+		var body []*types.Transaction
+		if i > 0 {
+			tx := types.NewTransaction(i, to, big.NewInt(111), 1111, big.NewInt(11111), nil)
+			txs = append(txs, tx)
+			body = append(body, tx)
+		}
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, body, nil, nil, newHasher())
+		blocks = append(blocks, block)
+		receipts = append(receipts, types.Receipts{})
+	}
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	// The genesis is always retained in the active database
+	WriteBlock(db, blocks[0])
+	WriteCanonicalHash(db, blocks[0].Hash(), 0)
+
+	IndexTransactions(db, 0, 11, nil)
+
+	// Pruning above the ancient limit is rejected
+	if err := PruneHistory(db, 12, nil); err == nil {
+		t.Fatal("pruned history above the ancient limit")
+	}
+	if err := PruneHistory(db, 6, nil); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	if tail, _ := db.Tail(); tail != 6 {
+		t.Fatalf("history tail mismatch: have %d, want %d", tail, 6)
+	}
+	if tail := ReadTxIndexTail(db); tail == nil || *tail != 6 {
+		t.Fatalf("transaction index tail mismatch: have %v, want %d", tail, 6)
+	}
+	for i, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+
+		if ReadHeader(db, hash, number) == nil {
+			t.Errorf("block #%d: header missing", number)
+		}
+		pruned := number > 0 && number < 6
+		if body := ReadBodyRLP(db, hash, number); (len(body) == 0) != pruned {
+			t.Errorf("block #%d: body availability mismatch: have %v, want %v", number, len(body) != 0, !pruned)
+		}
+		if number > 0 {
+			if entry := ReadTxLookupEntry(db, txs[i-1].Hash()); (entry == nil) != pruned {
+				t.Errorf("block #%d: tx lookup availability mismatch: have %v, want %v", number, entry != nil, !pruned)
+			}
+		}
+	}
+	// Pruning below the current tail is a noop
+	if err := PruneHistory(db, 3, nil); err != nil {
+		t.Fatalf("failed to re-prune history: %v", err)
+	}
+	if tail, _ := db.Tail(); tail != 6 {
+		t.Fatalf("history tail changed: have %d, want %d", tail, 6)
+	}
+}
@@ -77,6 +77,7 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables affected by tail truncation (nil means all)
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
@@ -87,6 +88,15 @@ type Freezer struct {
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance similarly to NewFreezer, but allows
+// restricting tail truncation to a subset of the tables. The 'prunable'
+// argument lists the tables whose old items may be discarded, every other
+// table retains its items regardless of the freezer tail. If nil, all the
+// tables are truncated together.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, prunable map[string]bool) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -110,6 +120,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     prunable,
 		instanceLock: lock,
 		datadir:      datadir,
 	}
@@ -206,7 +217,9 @@ func (f *Freezer) Ancients() (uint64, error) {
 	return atomic.LoadUint64(&f.frozen), nil
 }
 
-// Tail returns the number of first stored item in the freezer.
+// Tail returns the number of first stored item in the freezer. If the tail
+// truncation is restricted to a subset of the tables, the rest of the tables
+// still hold all their items.
 func (f *Freezer) Tail() (uint64, error) {
 	return atomic.LoadUint64(&f.tail), nil
 }
@@ -298,7 +311,10 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	if atomic.LoadUint64(&f.tail) >= tail {
 		return nil
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if !f.isPrunable(name) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -307,6 +323,11 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	return nil
 }
 
+// isPrunable returns whether the named table is affected by tail truncation.
+func (f *Freezer) isPrunable(name string) bool {
+	return f.prunable == nil || f.prunable[name]
+}
+
 // Sync flushes all data tables to disk.
 func (f *Freezer) Sync() error {
 	var errs []error
@@ -338,13 +359,18 @@ func (f *Freezer) validate() error {
 		break
 	}
 	// Now check every table against that length
+	var tail uint64
 	for kind, table := range f.tables {
 		items := atomic.LoadUint64(&table.items)
 		if length != items {
 			return fmt.Errorf("freezer tables %s and %s have differing lengths: %d != %d", kind, name, items, length)
 		}
+		if hidden := atomic.LoadUint64(&table.itemHidden); f.isPrunable(kind) && hidden > tail {
+			tail = hidden
+		}
 	}
 	atomic.StoreUint64(&f.frozen, length)
+	atomic.StoreUint64(&f.tail, tail)
 	return nil
 }
 
@@ -354,20 +380,23 @@ func (f *Freezer) repair() error {
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for name, table := range f.tables {
 		items := atomic.LoadUint64(&table.items)
 		if head > items {
 			head = items
 		}
 		hidden := atomic.LoadUint64(&table.itemHidden)
-		if hidden > tail {
+		if f.isPrunable(name) && hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.isPrunable(name) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -407,3 +407,59 @@ func TestRenameWindows(t *testing.T) {
 		t.Errorf("unexpected file contents. Got %v\n", buf)
 	}
 }
+
+func TestFreezerPrunableTruncateTail(t *testing.T) {
+	tables := map[string]bool{"a": true, "b": true}
+	prunable := map[string]bool{"a": true}
+	dir := t.TempDir()
+
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			require.NoError(t, op.AppendRaw("a", i, []byte{byte(i)}))
+			require.NoError(t, op.AppendRaw("b", i, []byte{byte(i)}))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(5))
+
+	// check verifies that only the prunable table lost its tail
+	check := func(f *Freezer) {
+		t.Helper()
+
+		if tail, _ := f.Tail(); tail != 5 {
+			t.Fatalf("tail mismatch: have %d, want %d", tail, 5)
+		}
+		if _, err := f.Ancient("a", 4); err != errOutOfBounds {
+			t.Fatalf("pruned item retrievable: err %v", err)
+		}
+		if blob, err := f.Ancient("a", 5); err != nil || !bytes.Equal(blob, []byte{5}) {
+			t.Fatalf("retained item mismatch: %x, err %v", blob, err)
+		}
+		if blob, err := f.Ancient("b", 0); err != nil || !bytes.Equal(blob, []byte{0}) {
+			t.Fatalf("non-prunable item mismatch: %x, err %v", blob, err)
+		}
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopen the freezer, the repair must not align the tails across tables
+	f, err = newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopen the freezer as readonly, the tail must be recovered too
+	f, err = newFreezer(dir, "", true, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen readonly freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+}
@@ -138,6 +138,13 @@ var FreezerNoSnappy = map[string]bool{
 	freezerDifficultyTable: true,
 }
 
+// freezerPrunableTables lists the ancient-tables whose items can be discarded
+// from the tail to expire the chain history (EIP-4444).
+var freezerPrunableTables = map[string]bool{
+	freezerBodiesTable:  true,
+	freezerReceiptTable: true,
+}
+
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
 // fields.
 type LegacyTxLookupEntry struct {
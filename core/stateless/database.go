@@ -0,0 +1,99 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// recordingDatabase is a state database which records every trie node and
+// contract code retrieved through it into a witness.
+//
+// Tries opened through it get their own trie database without any caches, so
+// every node they resolve is retrieved - and recorded - exactly once. The nodes
+// themselves are still served by the trie database of the wrapped state database,
+// so recent state only held in memory by the live chain can be recorded too.
+type recordingDatabase struct {
+	state.Database // Cache-less state database resolving nodes through the recorder
+
+	source  state.Database // State database to retrieve the recorded data from
+	witness *Witness
+}
+
+// NewRecordingDatabase wraps a state database so that all the trie nodes and
+// contract codes accessed through it are recorded into the given witness. The
+// returned database must only be used for reading and hashing state, committing
+// into it is not supported.
+//
+// Note, state snapshots bypass the tries altogether, so state objects created on
+// top of the returned database must not be backed by a snapshot.
+func NewRecordingDatabase(db state.Database, witness *Witness) state.Database {
+	recorder := &nodeRecorder{
+		KeyValueStore: db.TrieDB().DiskDB(),
+		triedb:        db.TrieDB(),
+		witness:       witness,
+	}
+	return &recordingDatabase{
+		Database: state.NewDatabase(rawdb.NewDatabase(recorder)),
+		source:   db,
+		witness:  witness,
+	}
+}
+
+// ContractCode retrieves a particular contract's code, recording it.
+func (db *recordingDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.source.ContractCode(addrHash, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	db.witness.AddCode(code)
+	return code, nil
+}
+
+// ContractCodeSize retrieves a particular contracts code's size. The code itself
+// is recorded, as a stateless executor can only derive the size from it.
+func (db *recordingDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// nodeRecorder is a key-value store view used as the disk database of recording
+// tries. Trie nodes - stored under their hash - are served through the source
+// trie database and recorded, everything else goes to the underlying disk.
+type nodeRecorder struct {
+	ethdb.KeyValueStore
+
+	triedb  *trie.Database
+	witness *Witness
+}
+
+// Get retrieves the given key, recording it if it's a trie node.
+func (r *nodeRecorder) Get(key []byte) ([]byte, error) {
+	if len(key) != common.HashLength {
+		return r.KeyValueStore.Get(key)
+	}
+	blob, err := r.triedb.Node(common.BytesToHash(key))
+	if err != nil {
+		return nil, err
+	}
+	r.witness.AddState(blob)
+	return blob, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errGenesisBlock is returned when attempting to record or execute the genesis
+// block, which has no parent state to execute on top of.
+var errGenesisBlock = errors.New("genesis block has no execution witness")
+
+// Record executes a block on top of the parent state held by the given state
+// database and returns the witness of everything the execution accessed. The
+// chain is used to retrieve the ancestor headers BLOCKHASH refers to.
+func Record(config *params.ChainConfig, engine consensus.Engine, chain core.ChainContext, block *types.Block, db state.Database) (*Witness, error) {
+	if block.NumberU64() == 0 {
+		return nil, errGenesisBlock
+	}
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	witness := NewWitness(parent)
+
+	statedb, err := state.New(parent.Root, NewRecordingDatabase(db, witness), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the block, tracking the deepest ancestor header accessed
+	oldest := parent.Number.Uint64()
+	headers := func(hash common.Hash, number uint64) *types.Header {
+		header := chain.GetHeader(hash, number)
+		if header != nil && number < oldest {
+			oldest = number
+		}
+		return header
+	}
+	root, _, err := execute(config, engine, headers, block, statedb)
+	if err != nil {
+		return nil, err
+	}
+	if root != block.Root() {
+		return nil, fmt.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	// BLOCKHASH walks the chain backwards from the parent, so fill in the
+	// contiguous header chain down to the oldest ancestor it reached
+	for last := parent; last.Number.Uint64() > oldest; {
+		header := chain.GetHeader(last.ParentHash, last.Number.Uint64()-1)
+		if header == nil {
+			return nil, fmt.Errorf("ancestor %#x not found", last.ParentHash)
+		}
+		witness.Headers = append(witness.Headers, header)
+		last = header
+	}
+	return witness, nil
+}
+
+// ExecuteStateless runs a block on top of nothing but the pre-state contained in
+// the witness and validates the outcome against the block header. It returns the
+// computed state and receipt roots.
+//
+// The header chain of the witness is checked to link up with the block, but it's
+// the responsibility of the caller to ensure that the block itself is canonical.
+func ExecuteStateless(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *Witness) (common.Hash, common.Hash, error) {
+	if block.NumberU64() == 0 {
+		return common.Hash{}, common.Hash{}, errGenesisBlock
+	}
+	// Ensure the witness headers form the chain leading up to the block
+	if len(witness.Headers) == 0 {
+		return common.Hash{}, common.Hash{}, errors.New("witness without parent header")
+	}
+	want := block.ParentHash()
+	for i, header := range witness.Headers {
+		if header.Hash() != want {
+			return common.Hash{}, common.Hash{}, fmt.Errorf("witness header %d hash mismatch: have %x, want %x", i, header.Hash(), want)
+		}
+		if header.Number.Uint64() != block.NumberU64()-uint64(i)-1 {
+			return common.Hash{}, common.Hash{}, fmt.Errorf("witness header %d number mismatch: have %d, want %d", i, header.Number, block.NumberU64()-uint64(i)-1)
+		}
+		want = header.ParentHash
+	}
+	headers := func(hash common.Hash, number uint64) *types.Header {
+		if number >= block.NumberU64() {
+			return nil
+		}
+		if idx := block.NumberU64() - number - 1; idx < uint64(len(witness.Headers)) {
+			if header := witness.Headers[idx]; header.Hash() == hash {
+				return header
+			}
+		}
+		return nil
+	}
+	statedb, err := state.New(witness.Root(), witness.database(), nil)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	root, receipts, err := execute(config, engine, headers, block, statedb)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	// Validate the outcome against the header, same as the block validator does
+	var (
+		header      = block.Header()
+		receiptRoot = types.DeriveSha(receipts, trie.NewStackTrie(nil))
+		gasUsed     uint64
+	)
+	if len(receipts) > 0 {
+		gasUsed = receipts[len(receipts)-1].CumulativeGasUsed
+	}
+	if gasUsed != header.GasUsed {
+		return root, receiptRoot, fmt.Errorf("gas used mismatch: have %d, want %d", gasUsed, header.GasUsed)
+	}
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		return root, receiptRoot, fmt.Errorf("bloom mismatch: have %x, want %x", bloom, header.Bloom)
+	}
+	if receiptRoot != header.ReceiptHash {
+		return root, receiptRoot, fmt.Errorf("receipt root mismatch: have %x, want %x", receiptRoot, header.ReceiptHash)
+	}
+	if root != header.Root {
+		return root, receiptRoot, fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	return root, receiptRoot, nil
+}
+
+// execute applies the transactions of a block and the consensus engine specific
+// extras on top of the given state, same as core.StateProcessor does, returning
+// the post state root and the receipts. Trie nodes missing from the state surface
+// as an error instead of silently being treated as empty.
+func execute(config *params.ChainConfig, engine consensus.Engine, headers func(common.Hash, uint64) *types.Header, block *types.Block, statedb *state.StateDB) (common.Hash, types.Receipts, error) {
+	var (
+		chain    = &chainContext{config: config, engine: engine, headers: headers}
+		header   = block.Header()
+		receipts types.Receipts
+		usedGas  = new(uint64)
+		gp       = new(core.GasPool).AddGas(block.GasLimit())
+	)
+	// Mutate the block and state according to any hard-fork specs
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), i)
+		receipt, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			return common.Hash{}, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	engine.Finalize(chain, header, statedb, block.Transactions(), block.Uncles())
+
+	root := statedb.IntermediateRoot(config.IsEIP158(header.Number))
+	if err := statedb.Error(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	return root, receipts, nil
+}
+
+// chainContext serves the chain access needed during block execution from a
+// header retrieval function.
+type chainContext struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	headers func(hash common.Hash, number uint64) *types.Header
+}
+
+// Config implements consensus.ChainHeaderReader.
+func (c *chainContext) Config() *params.ChainConfig { return c.config }
+
+// Engine implements core.ChainContext.
+func (c *chainContext) Engine() consensus.Engine { return c.engine }
+
+// GetHeader implements core.ChainContext and consensus.ChainHeaderReader.
+func (c *chainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers(hash, number)
+}
+
+// CurrentHeader implements consensus.ChainHeaderReader. Block execution doesn't
+// need it, so it's not available.
+func (c *chainContext) CurrentHeader() *types.Header { return nil }
+
+// GetHeaderByNumber implements consensus.ChainHeaderReader. Block execution doesn't
+// need it, so it's not available.
+func (c *chainContext) GetHeaderByNumber(number uint64) *types.Header { return nil }
+
+// GetHeaderByHash implements consensus.ChainHeaderReader. Block execution doesn't
+// need it, so it's not available.
+func (c *chainContext) GetHeaderByHash(hash common.Hash) *types.Header { return nil }
+
+// GetTd implements consensus.ChainHeaderReader. Block execution doesn't need it,
+// so it's not available.
+func (c *chainContext) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
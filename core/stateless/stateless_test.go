@@ -0,0 +1,212 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+
+	// testContract stores BLOCKHASH(NUMBER-3) into slot 0, increments slot 1,
+	// clears slot 2 and stores the code size of codeAddr into slot 3.
+	testContract = common.HexToAddress("0xaaaa")
+	testCode     = common.FromHex("0x6003430340600055600154600101600155600060025561bbbb3b60035500")
+
+	codeAddr = common.HexToAddress("0xbbbb")
+	code     = common.FromHex("0x6000600055")
+)
+
+// newTestChain creates a chain of the given length calling the test contract in
+// every block and returns it along with the live blockchain it was imported into.
+func newTestChain(t *testing.T, n int) (*core.BlockChain, []*types.Block) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		config  = params.TestChainConfig
+		engine  = ethash.NewFaker()
+		genesis = &core.Genesis{
+			Config: config,
+			Alloc: core.GenesisAlloc{
+				testAddr: {Balance: big.NewInt(params.Ether)},
+				testContract: {
+					Balance: common.Big0,
+					Code:    testCode,
+					Storage: map[common.Hash]common.Hash{
+						common.HexToHash("0x02"): common.HexToHash("0x01"),
+						common.HexToHash("0x05"): common.HexToHash("0x05"),
+					},
+				},
+				codeAddr: {Balance: common.Big0, Code: code},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(config)
+	)
+	// Generate the blocks one by one on top of an archive chain, so BLOCKHASH
+	// has the headers to resolve against
+	gendb := rawdb.NewMemoryDatabase()
+	gblock := genesis.MustCommit(gendb)
+	genchain, err := core.NewBlockChain(gendb, &core.CacheConfig{TrieDirtyDisabled: true}, config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create generator chain: %v", err)
+	}
+	defer genchain.Stop()
+
+	var blocks []*types.Block
+	for i := 0; i < n; i++ {
+		generated, _ := core.GenerateChain(config, genchain.CurrentBlock(), engine, gendb, 1, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddr), testContract, common.Big0, 100000, b.BaseFee(), nil), signer, testKey)
+			b.AddTxWithChain(genchain, tx)
+		})
+		if _, err := genchain.InsertChain(generated); err != nil {
+			t.Fatalf("failed to import generated block: %v", err)
+		}
+		blocks = append(blocks, generated...)
+	}
+	// Import the blocks into a regular chain, keeping recent state in memory
+	genesis.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return chain, append([]*types.Block{gblock}, blocks...)
+}
+
+// recordWitness records the witness of a block and round trips it through its
+// JSON encoding, same as it would be passed around over RPC.
+func recordWitness(t *testing.T, chain *core.BlockChain, block *types.Block) *Witness {
+	witness, err := Record(chain.Config(), chain.Engine(), chain, block, chain.StateCache())
+	if err != nil {
+		t.Fatalf("failed to record witness: %v", err)
+	}
+	blob, err := json.Marshal(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	dec := new(Witness)
+	if err := json.Unmarshal(blob, dec); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	return dec
+}
+
+func TestExecuteStateless(t *testing.T) {
+	chain, blocks := newTestChain(t, 5)
+	defer chain.Stop()
+
+	for _, block := range blocks[1:] {
+		witness := recordWitness(t, chain, block)
+
+		root, receiptRoot, err := ExecuteStateless(chain.Config(), chain.Engine(), block, witness)
+		if err != nil {
+			t.Fatalf("block %d: failed to execute statelessly: %v", block.NumberU64(), err)
+		}
+		if root != block.Root() {
+			t.Errorf("block %d: state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+		if receiptRoot != block.ReceiptHash() {
+			t.Errorf("block %d: receipt root mismatch: have %x, want %x", block.NumberU64(), receiptRoot, block.ReceiptHash())
+		}
+		// BLOCKHASH(n-3) needs the parent and its parent to be proven, but it
+		// falls out of range for the first blocks
+		headers := 1
+		if block.NumberU64() >= 3 {
+			headers = 2
+		}
+		if len(witness.Headers) != headers {
+			t.Errorf("block %d: header count mismatch: have %d, want %d", block.NumberU64(), len(witness.Headers), headers)
+		}
+		for _, want := range [][]byte{testCode, code} {
+			if _, ok := witness.Codes[string(want)]; !ok {
+				t.Errorf("block %d: code %x missing from witness", block.NumberU64(), want)
+			}
+		}
+	}
+}
+
+func TestExecuteStatelessIncompleteWitness(t *testing.T) {
+	chain, blocks := newTestChain(t, 5)
+	defer chain.Stop()
+
+	block := blocks[len(blocks)-1]
+
+	// Dropping any single trie node must fail the execution
+	for node := range recordWitness(t, chain, block).State {
+		witness := recordWitness(t, chain, block)
+		delete(witness.State, node)
+
+		if _, _, err := ExecuteStateless(chain.Config(), chain.Engine(), block, witness); err == nil {
+			t.Errorf("executed with trie node %x missing", crypto.Keccak256([]byte(node)))
+		}
+	}
+	// Dropping the code only used by EXTCODESIZE must fail the execution
+	witness := recordWitness(t, chain, block)
+	delete(witness.Codes, string(code))
+	if _, _, err := ExecuteStateless(chain.Config(), chain.Engine(), block, witness); err == nil {
+		t.Error("executed with code missing")
+	}
+	// Dropping the headers BLOCKHASH refers to must fail the execution
+	witness = recordWitness(t, chain, block)
+	witness.Headers = witness.Headers[:1]
+	if _, _, err := ExecuteStateless(chain.Config(), chain.Engine(), block, witness); err == nil {
+		t.Error("executed with ancestor header missing")
+	}
+	// Headers not linking up with the block must be rejected
+	witness = recordWitness(t, chain, block)
+	witness.Headers = []*types.Header{blocks[len(blocks)-3].Header()}
+	if _, _, err := ExecuteStateless(chain.Config(), chain.Engine(), block, witness); err == nil {
+		t.Error("executed with unrelated parent header")
+	}
+}
+
+func TestExecuteStatelessInvalidBlock(t *testing.T) {
+	chain, blocks := newTestChain(t, 5)
+	defer chain.Stop()
+
+	block := blocks[len(blocks)-1]
+	witness := recordWitness(t, chain, block)
+
+	// A block claiming a different post state must be rejected
+	header := block.Header()
+	header.Root = common.Hash{0x01}
+	forged := block.WithSeal(header)
+
+	witness.Headers[0] = blocks[len(blocks)-2].Header()
+	if _, _, err := ExecuteStateless(chain.Config(), chain.Engine(), forged, witness); err == nil {
+		t.Error("executed block with invalid state root")
+	}
+	// The genesis block has no parent state to execute on
+	if _, err := Record(chain.Config(), chain.Engine(), chain, blocks[0], chain.StateCache()); err != errGenesisBlock {
+		t.Errorf("genesis error mismatch: have %v, want %v", err, errGenesisBlock)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless records the state accessed while executing a block and
+// re-executes blocks from nothing but that recorded witness.
+package stateless
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// Witness encompasses all the data required to execute a block on top of its
+// parent state without having access to the full state: the trie nodes and
+// contract codes touched during execution and the chain of ancestor headers
+// needed to serve the BLOCKHASH opcode.
+type Witness struct {
+	Headers []*types.Header     // Ancestor headers in reverse order, the parent always being the first
+	Codes   map[string]struct{} // Contract bytecodes accessed during execution
+	State   map[string]struct{} // Account and storage trie nodes accessed during execution
+
+	lock sync.Mutex
+}
+
+// NewWitness creates an empty witness for executing a block on top of the given
+// parent header.
+func NewWitness(parent *types.Header) *Witness {
+	return &Witness{
+		Headers: []*types.Header{parent},
+		Codes:   make(map[string]struct{}),
+		State:   make(map[string]struct{}),
+	}
+}
+
+// Root returns the state root the witness needs to be executed on top of.
+func (w *Witness) Root() common.Hash {
+	return w.Headers[0].Root
+}
+
+// AddCode adds a contract bytecode to the witness.
+func (w *Witness) AddCode(code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.Codes[string(code)] = struct{}{}
+}
+
+// AddState adds an RLP encoded trie node to the witness.
+func (w *Witness) AddState(node []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.State[string(node)] = struct{}{}
+}
+
+// database creates an ephemeral state database containing all the trie nodes
+// and contract codes of the witness. Anything not recorded in the witness will
+// be missing from it, failing the execution.
+func (w *Witness) database() state.Database {
+	db := memorydb.New()
+	for node := range w.State {
+		blob := []byte(node)
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	for code := range w.Codes {
+		blob := []byte(code)
+		rawdb.WriteCode(db, crypto.Keccak256Hash(blob), blob)
+	}
+	return state.NewDatabase(rawdb.NewDatabase(db))
+}
+
+// extWitness is the external representation of a witness. The codes and trie
+// nodes are sorted to make the encoding deterministic.
+type extWitness struct {
+	Headers []*types.Header `json:"headers"`
+	Codes   []hexutil.Bytes `json:"codes"`
+	State   []hexutil.Bytes `json:"state"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w *Witness) MarshalJSON() ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ext := &extWitness{
+		Headers: w.Headers,
+		Codes:   sortedBlobs(w.Codes),
+		State:   sortedBlobs(w.State),
+	}
+	return json.Marshal(ext)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *Witness) UnmarshalJSON(input []byte) error {
+	var ext extWitness
+	if err := json.Unmarshal(input, &ext); err != nil {
+		return err
+	}
+	if len(ext.Headers) == 0 {
+		return errors.New("witness without parent header")
+	}
+	w.Headers = ext.Headers
+	w.Codes = make(map[string]struct{}, len(ext.Codes))
+	for _, code := range ext.Codes {
+		w.Codes[string(code)] = struct{}{}
+	}
+	w.State = make(map[string]struct{}, len(ext.State))
+	for _, node := range ext.State {
+		w.State[string(node)] = struct{}{}
+	}
+	return nil
+}
+
+func sortedBlobs(set map[string]struct{}) []hexutil.Bytes {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	blobs := make([]hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		blobs[i] = hexutil.Bytes(key)
+	}
+	return blobs
+}
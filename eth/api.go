@@ -33,7 +33,10 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -330,6 +333,19 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 	return results, nil
 }
 
+// BeaconSyncStatus retrieves the progress of the post-merge beacon sync: the
+// header subchains assembled so far, the last block backfilled and the header
+// ranges still to be downloaded.
+func (api *DebugAPI) BeaconSyncStatus() (*downloader.BeaconSyncStatus, error) {
+	return api.eth.Downloader().BeaconSyncStatus()
+}
+
+// SnapSyncStatus retrieves the detailed progress of the snap sync, including
+// estimates of the work left in each phase and the pending heal requests.
+func (api *DebugAPI) SnapSyncStatus() *snap.SyncStatus {
+	return api.eth.Downloader().SnapSyncer.Status()
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
@@ -584,3 +600,29 @@ func (api *DebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64, error
 	}
 	return 0, fmt.Errorf("No state found")
 }
+
+// ExecutionWitness re-executes the given block on top of its parent state and
+// returns the witness needed to execute it statelessly: all the account and
+// storage trie nodes and bytecodes accessed, as well as the ancestor headers
+// needed to serve BLOCKHASH.
+func (api *DebugAPI) ExecutionWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.Witness, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis block has no execution witness")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := api.eth.StateAtBlock(parent, 0, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return stateless.Record(api.eth.blockchain.Config(), api.eth.engine, api.eth.blockchain, block, statedb.Database())
+}
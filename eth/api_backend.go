@@ -113,11 +113,21 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 	if number == rpc.FinalizedBlockNumber {
 		return b.eth.blockchain.CurrentFinalizedBlock(), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
+	block := b.eth.blockchain.GetBlockByNumber(uint64(number))
+	if block == nil && b.eth.blockchain.HistoryPruned(uint64(number)) {
+		return nil, core.ErrHistoryPruned
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(hash), nil
+	block := b.eth.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		if header := b.eth.blockchain.GetHeaderByHash(hash); header != nil && b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+			return nil, core.ErrHistoryPruned
+		}
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
@@ -134,6 +144,9 @@ func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash r
 		}
 		block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
 		if block == nil {
+			if b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+				return nil, core.ErrHistoryPruned
+			}
 			return nil, errors.New("header found, but block body is missing")
 		}
 		return block, nil
@@ -185,7 +198,13 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil {
+		if header := b.eth.blockchain.GetHeaderByHash(hash); header != nil && b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+			return nil, core.ErrHistoryPruned
+		}
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
@@ -196,6 +215,9 @@ func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*typ
 	}
 	logs := rawdb.ReadLogs(db, hash, *number, b.eth.blockchain.Config())
 	if logs == nil {
+		if b.eth.blockchain.HistoryPruned(*number) {
+			return nil, core.ErrHistoryPruned
+		}
 		return nil, fmt.Errorf("failed to get logs for block #%d (0x%s)", *number, hash.TerminalString())
 	}
 	return logs, nil
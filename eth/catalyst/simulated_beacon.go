@@ -0,0 +1,259 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SimulatedBeacon drives an execution client through the engine API the same
+// way a real consensus client would, sealing blocks either on a fixed period or
+// whenever transactions are pending in the pool. It is meant for developer
+// networks only: it does no validation of its own and finalizes every block
+// instantly.
+type SimulatedBeacon struct {
+	shutdownCh         chan struct{}
+	eth                *eth.Ethereum
+	period             uint64
+	wg                 sync.WaitGroup
+	engineAPI          *ConsensusAPI
+	curForkchoiceState beacon.ForkchoiceStateV1
+
+	feeRecipient     common.Address
+	feeRecipientLock sync.Mutex // lock gates concurrent access to the feeRecipient
+
+	lastBlockTime uint64
+	lock          sync.Mutex // lock serializes block sealing
+}
+
+// NewSimulatedBeacon constructs a new simulated beacon chain on top of the given
+// backend. A zero period means blocks are only produced when transactions are
+// pending, any other value seals a block every period seconds.
+func NewSimulatedBeacon(period uint64, eth *eth.Ethereum) (*SimulatedBeacon, error) {
+	chainConfig := eth.BlockChain().Config()
+	if chainConfig.TerminalTotalDifficulty == nil || chainConfig.TerminalTotalDifficulty.Sign() != 0 {
+		return nil, errors.New("simulated beacon requires a chain merged at genesis (terminal total difficulty 0)")
+	}
+	engineAPI := NewConsensusAPI(eth)
+
+	// Credit the fees to the node's etherbase if one is available, it can be
+	// changed later through the dev API.
+	feeRecipient, _ := eth.Etherbase()
+
+	// If the chain was previously stopped, resume from the current head with
+	// the finalized block pinned to it.
+	current := eth.BlockChain().CurrentBlock()
+	return &SimulatedBeacon{
+		eth:           eth,
+		period:        period,
+		shutdownCh:    make(chan struct{}),
+		engineAPI:     engineAPI,
+		lastBlockTime: current.Time(),
+		curForkchoiceState: beacon.ForkchoiceStateV1{
+			HeadBlockHash:      current.Hash(),
+			SafeBlockHash:      current.Hash(),
+			FinalizedBlockHash: current.Hash(),
+		},
+		feeRecipient: feeRecipient,
+	}, nil
+}
+
+// setFeeRecipient changes the address credited with the fees of the blocks
+// sealed from now on.
+func (c *SimulatedBeacon) setFeeRecipient(feeRecipient common.Address) {
+	c.feeRecipientLock.Lock()
+	c.feeRecipient = feeRecipient
+	c.feeRecipientLock.Unlock()
+}
+
+// Start invokes the sealing loop in a new goroutine, implementing
+// node.Lifecycle.
+func (c *SimulatedBeacon) Start() error {
+	c.wg.Add(1)
+	if c.period == 0 {
+		// Subscribe before returning to not miss transactions submitted
+		// right after the node was started.
+		newTxs := make(chan core.NewTxsEvent)
+		sub := c.eth.TxPool().SubscribeNewTxsEvent(newTxs)
+		go c.loopOnDemand(newTxs, sub)
+	} else {
+		go c.loop()
+	}
+	return nil
+}
+
+// Stop halts the sealing loop, implementing node.Lifecycle.
+func (c *SimulatedBeacon) Stop() error {
+	close(c.shutdownCh)
+	c.wg.Wait()
+	return nil
+}
+
+// sealBlock runs a full engine API round trip to produce, import and select as
+// head a new block on top of the current one. A zero timestamp means the block
+// time is derived from the wall clock.
+func (c *SimulatedBeacon) sealBlock(timestamp uint64) (*beacon.ExecutableDataV1, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Block timestamps must be strictly increasing, bump the requested one if
+	// it would collide with the current head.
+	if timestamp == 0 {
+		timestamp = uint64(time.Now().Unix())
+	}
+	if timestamp <= c.lastBlockTime {
+		timestamp = c.lastBlockTime + 1
+	}
+	c.feeRecipientLock.Lock()
+	feeRecipient := c.feeRecipient
+	c.feeRecipientLock.Unlock()
+
+	var random common.Hash
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+	fcResponse, err := c.engineAPI.ForkchoiceUpdatedV1(c.curForkchoiceState, &beacon.PayloadAttributesV1{
+		Timestamp:             timestamp,
+		SuggestedFeeRecipient: feeRecipient,
+		Random:                random,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fcResponse.PayloadID == nil {
+		return nil, fmt.Errorf("payload building rejected: %s", fcResponse.PayloadStatus.Status)
+	}
+	payload, err := c.engineAPI.GetPayloadV1(*fcResponse.PayloadID)
+	if err != nil {
+		return nil, err
+	}
+	status, err := c.engineAPI.NewPayloadV1(*payload)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status != beacon.VALID {
+		if status.ValidationError != nil {
+			return nil, fmt.Errorf("payload rejected (%s): %s", status.Status, *status.ValidationError)
+		}
+		return nil, fmt.Errorf("payload rejected: %s", status.Status)
+	}
+	// Mark the new block as head and instantly finalize it, there is nobody
+	// around to reorg a developer chain.
+	c.curForkchoiceState = beacon.ForkchoiceStateV1{
+		HeadBlockHash:      payload.BlockHash,
+		SafeBlockHash:      payload.BlockHash,
+		FinalizedBlockHash: payload.BlockHash,
+	}
+	if _, err := c.engineAPI.ForkchoiceUpdatedV1(c.curForkchoiceState, nil); err != nil {
+		return nil, err
+	}
+	c.lastBlockTime = payload.Timestamp
+
+	log.Info("Sealed simulated beacon block", "number", payload.Number, "hash", payload.BlockHash, "txs", len(payload.Transactions))
+	return payload, nil
+}
+
+// loopOnDemand runs the sealing loop in on-demand mode, producing a block
+// whenever new transactions arrive and until the pool is drained.
+func (c *SimulatedBeacon) loopOnDemand(newTxs chan core.NewTxsEvent, sub event.Subscription) {
+	defer c.wg.Done()
+	defer sub.Unsubscribe()
+
+	// Transactions might have been left over in the pool from a previous
+	// run, seal them right away instead of waiting for new ones.
+	c.sealPending()
+	for {
+		select {
+		case <-c.shutdownCh:
+			return
+		case <-sub.Err():
+			return
+		case <-newTxs:
+			c.sealPending()
+		}
+	}
+}
+
+// sealPending seals blocks as long as there are pending transactions in the
+// pool and the produced blocks come out full, a single block might not fit
+// every pending transaction.
+func (c *SimulatedBeacon) sealPending() {
+	for {
+		if pending, _ := c.eth.TxPool().Stats(); pending == 0 {
+			return
+		}
+		payload, err := c.sealBlock(0)
+		if err != nil {
+			log.Warn("Error performing sealing work", "err", err)
+			return
+		}
+		if payload.GasUsed+params.TxGas <= payload.GasLimit {
+			return
+		}
+		select {
+		case <-c.shutdownCh:
+			return
+		default:
+		}
+	}
+}
+
+// loop runs the sealing loop in periodic mode, producing a block every period
+// seconds regardless of pool contents.
+func (c *SimulatedBeacon) loop() {
+	defer c.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-c.shutdownCh:
+			return
+		case <-timer.C:
+			if _, err := c.sealBlock(0); err != nil {
+				log.Warn("Error performing sealing work", "err", err)
+			}
+			timer.Reset(time.Second * time.Duration(c.period))
+		}
+	}
+}
+
+// RegisterSimulatedBeaconAPIs registers the simulated beacon's developer API
+// with the given node.
+func RegisterSimulatedBeaconAPIs(stack *node.Node, sim *SimulatedBeacon) {
+	stack.RegisterAPIs([]rpc.API{
+		{
+			Namespace: "dev",
+			Service:   &simulatedBeaconAPI{sim},
+		},
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// simulatedBeaconAPI exposes the developer controls of the simulated beacon
+// under the "dev" RPC namespace.
+type simulatedBeaconAPI struct {
+	sim *SimulatedBeacon
+}
+
+// Commit forces the immediate sealing of a new block with the currently pending
+// transactions, regardless of the configured sealing mode. If a timestamp is
+// given, it is used as the block time, bumped if needed to stay ahead of the
+// parent. The hash of the new head block is returned.
+func (a *simulatedBeaconAPI) Commit(timestamp *hexutil.Uint64) (common.Hash, error) {
+	var time uint64
+	if timestamp != nil {
+		time = uint64(*timestamp)
+	}
+	payload, err := a.sim.sealBlock(time)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return payload.BlockHash, nil
+}
+
+// SetFeeRecipient changes the address credited with the fees of all future
+// simulated blocks.
+func (a *simulatedBeaconAPI) SetFeeRecipient(feeRecipient common.Address) {
+	a.sim.setFeeRecipient(feeRecipient)
+}
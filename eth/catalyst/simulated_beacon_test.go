@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func startSimulatedBeaconEthService(t *testing.T, genesis *core.Genesis, period uint64) (*node.Node, *eth.Ethereum, *SimulatedBeacon) {
+	t.Helper()
+
+	n, err := node.New(&node.Config{
+		P2P: p2p.Config{
+			ListenAddr:  "127.0.0.1:0",
+			NoDiscovery: true,
+			MaxPeers:    0,
+		},
+	})
+	if err != nil {
+		t.Fatal("can't create node:", err)
+	}
+	ethcfg := &ethconfig.Config{Genesis: genesis, SyncMode: downloader.FullSync, TrieTimeout: time.Minute, TrieDirtyCache: 256, TrieCleanCache: 256}
+	ethservice, err := eth.New(n, ethcfg)
+	if err != nil {
+		t.Fatal("can't create eth service:", err)
+	}
+	simBeacon, err := NewSimulatedBeacon(period, ethservice)
+	if err != nil {
+		t.Fatal("can't create simulated beacon:", err)
+	}
+	n.RegisterLifecycle(simBeacon)
+
+	if err := n.Start(); err != nil {
+		t.Fatal("can't start node:", err)
+	}
+	ethservice.SetSynced()
+	return n, ethservice, simBeacon
+}
+
+// Tests that the simulated beacon seals blocks on demand, including every
+// transaction sent to the pool, and that the resulting chain has post-merge
+// semantics.
+func TestSimulatedBeaconSendTransactions(t *testing.T) {
+	var (
+		txs     = make(map[common.Hash]*types.Transaction)
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = core.DeveloperGenesisBlock(11_500_000, addr)
+	)
+	node, ethService, _ := startSimulatedBeaconEthService(t, genesis, 0)
+	defer node.Close()
+
+	chainHeadCh := make(chan core.ChainHeadEvent, 10)
+	sub := ethService.BlockChain().SubscribeChainHeadEvent(chainHeadCh)
+	defer sub.Unsubscribe()
+
+	// Generate a batch of transactions to seal
+	var (
+		signer = types.LatestSigner(ethService.BlockChain().Config())
+		batch  []*types.Transaction
+	)
+	for i := 0; i < 20; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xaa}, big.NewInt(1000), params.TxGas, big.NewInt(params.InitialBaseFee), nil), signer, key)
+		if err != nil {
+			t.Fatalf("error signing transaction: %v", err)
+		}
+		txs[tx.Hash()] = tx
+		batch = append(batch, tx)
+	}
+	for _, err := range ethService.TxPool().AddLocals(batch) {
+		if err != nil {
+			t.Fatalf("error adding transaction to pool: %v", err)
+		}
+	}
+	// Wait until every transaction was included
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+
+	for len(txs) > 0 {
+		select {
+		case ev := <-chainHeadCh:
+			block := ev.Block
+			if block.Difficulty().Sign() != 0 {
+				t.Fatalf("block %d: difficulty mismatch: have %v, want 0", block.NumberU64(), block.Difficulty())
+			}
+			if len(block.Uncles()) != 0 {
+				t.Fatalf("block %d: unexpected uncles", block.NumberU64())
+			}
+			for _, tx := range block.Transactions() {
+				delete(txs, tx.Hash())
+			}
+		case <-timer.C:
+			t.Fatalf("timed out waiting for transactions, %d left", len(txs))
+		}
+	}
+}
+
+// Tests that blocks can be forced through the dev API with custom timestamps.
+func TestSimulatedBeaconCommit(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = core.DeveloperGenesisBlock(11_500_000, addr)
+	)
+	node, ethService, simBeacon := startSimulatedBeaconEthService(t, genesis, 0)
+	defer node.Close()
+
+	api := &simulatedBeaconAPI{simBeacon}
+
+	// Force a block with an explicit timestamp
+	want := hexutil.Uint64(time.Now().Unix() + 3600)
+	hash, err := api.Commit(&want)
+	if err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	head := ethService.BlockChain().CurrentBlock()
+	if head.Hash() != hash {
+		t.Fatalf("head mismatch: have %x, want %x", head.Hash(), hash)
+	}
+	if head.Time() != uint64(want) {
+		t.Fatalf("timestamp mismatch: have %d, want %d", head.Time(), want)
+	}
+	if final := ethService.BlockChain().CurrentFinalizedBlock(); final == nil || final.Hash() != hash {
+		t.Fatalf("finalized block mismatch")
+	}
+	// Timestamps in the past must be bumped to stay ahead of the parent
+	past := hexutil.Uint64(1)
+	if _, err := api.Commit(&past); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	if have := ethService.BlockChain().CurrentBlock().Time(); have != uint64(want)+1 {
+		t.Fatalf("timestamp mismatch: have %d, want %d", have, want+1)
+	}
+	// Fee recipient changes should be reflected in the next block
+	recipient := common.Address{0xfe}
+	api.SetFeeRecipient(recipient)
+	if _, err := api.Commit(nil); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	if have := ethService.BlockChain().CurrentBlock().Coinbase(); have != recipient {
+		t.Fatalf("fee recipient mismatch: have %x, want %x", have, recipient)
+	}
+}
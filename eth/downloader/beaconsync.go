@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -179,6 +180,44 @@ func (d *Downloader) beaconSync(mode SyncMode, head *types.Header, force bool) e
 	return nil
 }
 
+// BeaconSubchain is a contiguous segment of reverse downloaded beacon headers.
+type BeaconSubchain struct {
+	Head hexutil.Uint64 `json:"head"` // Block number of the newest header in the subchain
+	Tail hexutil.Uint64 `json:"tail"` // Block number of the oldest header in the subchain
+	Next common.Hash    `json:"next"` // Block hash of the next oldest header to retrieve
+}
+
+// BeaconSyncGap is an inclusive range of beacon headers not yet downloaded.
+type BeaconSyncGap struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// BeaconSyncStatus is a snapshot of the beacon sync progress, reporting the
+// subchains assembled by the skeleton syncer (newest first), the last block
+// backfilled into the local chain and the header ranges still missing.
+type BeaconSyncStatus struct {
+	Subchains []BeaconSubchain `json:"subchains"`
+	Filled    hexutil.Uint64   `json:"filled"`
+	Linked    bool             `json:"linked"`
+	Gaps      []BeaconSyncGap  `json:"gaps"`
+}
+
+// BeaconSyncStatus retrieves the current progress of the beacon sync.
+func (d *Downloader) BeaconSyncStatus() (*BeaconSyncStatus, error) {
+	var filled uint64
+
+	switch {
+	case d.blockchain != nil && d.getMode() == FullSync:
+		filled = d.blockchain.CurrentBlock().NumberU64()
+	case d.blockchain != nil:
+		filled = d.blockchain.CurrentFastBlock().NumberU64()
+	case d.lightchain != nil:
+		filled = d.lightchain.CurrentHeader().Number.Uint64()
+	}
+	return d.skeleton.Status(filled)
+}
+
 // findBeaconAncestor tries to locate the common ancestor link of the local chain
 // and the beacon chain just requested. In the general case when our node was in
 // sync and on the correct chain, checking the top N links should already get us
@@ -228,6 +228,13 @@ func New(checkpoint uint64, stateDb ethdb.Database, mux *event.TypeMux, chain Bl
 	return dl
 }
 
+// SetRateHook installs a callback to be notified of every throughput measurement
+// of the sync peers, where zero items signal a timeout or unavailable data. It
+// must be called before any peers are registered.
+func (d *Downloader) SetRateHook(hook func(id string, kind uint64, items int)) {
+	d.peers.rates.OnUpdate = hook
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -253,6 +260,7 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		log.Error("Unknown downloader chain/mode combo", "light", d.lightchain != nil, "full", d.blockchain != nil, "mode", mode)
 	}
 	progress, pending := d.SnapSyncer.Progress()
+	status := d.SnapSyncer.Status()
 
 	return ethereum.SyncProgress{
 		StartingBlock:       d.syncStatsChainOrigin,
@@ -270,6 +278,18 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HealedBytecodeBytes: uint64(progress.BytecodeHealBytes),
 		HealingTrienodes:    pending.TrienodeHeal,
 		HealingBytecode:     pending.BytecodeHeal,
+		AccountsRemaining:   uint64(status.Accounts.Remaining),
+		AccountsRate:        uint64(status.Accounts.Rate),
+		AccountsETA:         uint64(status.Accounts.ETA),
+		StorageRemaining:    uint64(status.Storage.Remaining),
+		StorageRate:         uint64(status.Storage.Rate),
+		StorageETA:          uint64(status.Storage.ETA),
+		BytecodesRemaining:  uint64(status.Bytecodes.Remaining),
+		BytecodesRate:       uint64(status.Bytecodes.Rate),
+		BytecodesETA:        uint64(status.Bytecodes.ETA),
+		HealingRemaining:    uint64(status.Trienodes.Remaining),
+		HealingRate:         uint64(status.Trienodes.Rate),
+		HealingETA:          uint64(status.Trienodes.ETA),
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
@@ -497,6 +498,12 @@ func (s *skeleton) initSync(head *types.Header) {
 			for _, subchain := range s.progress.Subchains {
 				log.Debug("Restarting skeleton subchain", "head", subchain.Head, "tail", subchain.Tail)
 			}
+			// If the previous run was interrupted, the persisted subchains might
+			// be touching or overlapping each other without having been merged.
+			// Stitch them together before anything else, otherwise the syncer
+			// would need to redownload the already present headers.
+			s.mergeSubchains()
+
 			// Create a new subchain for the head (unless the last can be extended),
 			// trimming anything it would overwrite
 			headchain := &subchain{
@@ -526,7 +533,7 @@ func (s *skeleton) initSync(head *types.Header) {
 				lastchain := s.progress.Subchains[0]
 				if lastchain.Head == headchain.Tail-1 {
 					lasthead := rawdb.ReadSkeletonHeader(s.db, lastchain.Head)
-					if lasthead.Hash() == head.ParentHash {
+					if lasthead != nil && lasthead.Hash() == head.ParentHash {
 						log.Debug("Extended skeleton subchain with new head", "head", headchain.Tail, "tail", lastchain.Tail)
 						lastchain.Head = headchain.Tail
 						extended = true
@@ -576,6 +583,43 @@ func (s *skeleton) initSync(head *types.Header) {
 	log.Debug("Created initial skeleton subchain", "head", number, "tail", number)
 }
 
+// mergeSubchains iterates over the subchains loaded from a previous sync run
+// and merges any two neighbours that link up, trimming overlaps along the way.
+// During a live sync only the primary subchain is ever merged into the next
+// one, so an interruption at the wrong time can leave behind adjacent chains
+// that would otherwise only be merged after redownloading the seam.
+func (s *skeleton) mergeSubchains() {
+	for i := 0; i < len(s.progress.Subchains)-1; {
+		var (
+			newer = s.progress.Subchains[i]
+			older = s.progress.Subchains[i+1]
+		)
+		// If the older subchain overlaps the newer one, the newer headers take
+		// precedence as they were the last ones written
+		if older.Head >= newer.Tail {
+			if older.Tail >= newer.Tail {
+				log.Debug("Dropping overwritten skeleton subchain", "head", older.Head, "tail", older.Tail)
+				s.progress.Subchains = append(s.progress.Subchains[:i+1], s.progress.Subchains[i+2:]...)
+				continue
+			}
+			log.Debug("Trimming overlapping skeleton subchain", "oldhead", older.Head, "newhead", newer.Tail-1, "tail", older.Tail)
+			older.Head = newer.Tail - 1
+		}
+		// If the two subchains are now adjacent and link up by hash, merge them
+		if older.Head+1 == newer.Tail {
+			if head := rawdb.ReadSkeletonHeader(s.db, older.Head); head != nil && head.Hash() == newer.Next {
+				log.Debug("Merging persisted skeleton subchains", "head", newer.Head, "tail", older.Tail, "seam", older.Head)
+				newer.Tail = older.Tail
+				newer.Next = older.Next
+
+				s.progress.Subchains = append(s.progress.Subchains[:i+1], s.progress.Subchains[i+2:]...)
+				continue
+			}
+		}
+		i++
+	}
+}
+
 // saveSyncStatus marshals the remaining sync tasks into leveldb.
 func (s *skeleton) saveSyncStatus(db ethdb.KeyValueWriter) {
 	status, err := json.Marshal(s.progress)
@@ -1048,6 +1092,23 @@ func (s *skeleton) processResponse(res *headerResponse) (linked bool, merged boo
 				merged = true
 			}
 		}
+		// If the subchain reached the head of the next one without overlapping
+		// it (e.g. a leftover from a previous run), merge the two right away
+		// instead of redownloading the next subchain's headers
+		if !merged && len(s.progress.Subchains) > 1 && s.progress.Subchains[1].Head+1 == s.progress.Subchains[0].Tail {
+			head := s.progress.Subchains[1].Head
+			tail := s.progress.Subchains[1].Tail
+			next := s.progress.Subchains[1].Next
+
+			if header := rawdb.ReadSkeletonHeader(s.db, head); header != nil && header.Hash() == s.progress.Subchains[0].Next {
+				log.Debug("Previous subchain merged", "head", head, "tail", tail, "next", next)
+				s.progress.Subchains[0].Tail = tail
+				s.progress.Subchains[0].Next = next
+
+				s.progress.Subchains = append(s.progress.Subchains[:1], s.progress.Subchains[2:]...)
+				merged = true
+			}
+		}
 		// If subchains were merged, all further available headers in the scratch
 		// space are invalid since we skipped ahead. Stop processing the scratch
 		// space to avoid dropping peers thinking they delivered invalid data.
@@ -1174,6 +1235,57 @@ func (s *skeleton) Bounds() (head *types.Header, tail *types.Header, err error)
 	return head, tail, nil
 }
 
+// Status retrieves the persisted sync progress of the skeleton syncer, along
+// with the header ranges still missing between the subchains and between the
+// last subchain and the given local head (the last block backfilled). Similar
+// to Bounds, it reads the state from disk so that it's safe to call any time.
+func (s *skeleton) Status(filled uint64) (*BeaconSyncStatus, error) {
+	status := rawdb.ReadSkeletonSyncStatus(s.db)
+	if len(status) == 0 {
+		return nil, errors.New("beacon sync not yet started")
+	}
+	progress := new(skeletonProgress)
+	if err := json.Unmarshal(status, progress); err != nil {
+		return nil, err
+	}
+	result := &BeaconSyncStatus{
+		Subchains: make([]BeaconSubchain, 0, len(progress.Subchains)),
+		Filled:    hexutil.Uint64(filled),
+		Gaps:      []BeaconSyncGap{},
+	}
+	for i, subchain := range progress.Subchains {
+		result.Subchains = append(result.Subchains, BeaconSubchain{
+			Head: hexutil.Uint64(subchain.Head),
+			Tail: hexutil.Uint64(subchain.Tail),
+			Next: subchain.Next,
+		})
+		// Subchains are ordered newest first, any space between the tail of the
+		// previous one and the head of this one needs to be downloaded
+		if i > 0 && subchain.Head+1 < progress.Subchains[i-1].Tail {
+			result.Gaps = append(result.Gaps, BeaconSyncGap{
+				From: hexutil.Uint64(subchain.Head + 1),
+				To:   hexutil.Uint64(progress.Subchains[i-1].Tail - 1),
+			})
+		}
+	}
+	// If the oldest subchain is not yet linked to the local chain, the headers
+	// between the local head and its tail are missing too
+	if n := len(progress.Subchains); n > 0 {
+		last := progress.Subchains[n-1]
+		result.Linked = n == 1 && last.Tail > 0 &&
+			rawdb.HasBody(s.db, last.Next, last.Tail-1) &&
+			rawdb.HasReceipts(s.db, last.Next, last.Tail-1)
+
+		if !result.Linked && filled+1 < last.Tail {
+			result.Gaps = append(result.Gaps, BeaconSyncGap{
+				From: hexutil.Uint64(filled + 1),
+				To:   hexutil.Uint64(last.Tail - 1),
+			})
+		}
+	}
+	return result, nil
+}
+
 // Header retrieves a specific header tracked by the skeleton syncer. This method
 // is meant to be used by the backfiller, whose life cycle is controlled by the
 // skeleton syncer.
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -894,3 +895,223 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 		skeleton.Terminate()
 	}
 }
+
+// Tests that a skeleton sync interrupted at various points (i.e. a crash or a
+// shutdown) resumes from the persisted subchains after a restart, merging any
+// leftovers that link up without redownloading the headers already present.
+func TestSkeletonSyncResume(t *testing.T) {
+	// Create a long fake chain to test with, only the parent hash progression
+	// is needed for the skeleton syncer.
+	chain := []*types.Header{{Number: big.NewInt(0)}}
+	for i := 1; i < 8*requestHeaders; i++ {
+		chain = append(chain, &types.Header{
+			ParentHash: chain[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+		})
+	}
+	// linked creates a persisted subchain between the given bounds, correctly
+	// linking into the rest of the test chain.
+	linked := func(head, tail uint64) *subchain {
+		return &subchain{Head: head, Tail: tail, Next: chain[tail-1].Hash()}
+	}
+	tests := []struct {
+		oldstate []*subchain   // Sync state persisted before the crash
+		head     *types.Header // Head header announced after the restart
+		midstate []*subchain   // Expected sync state right after the restart
+		endstate []*subchain   // Expected sync state after sync completes
+		endserve uint64        // Expected number of header retrievals after the restart
+	}{
+		// Crash in the middle of reverse downloading a single subchain, restarted
+		// with the same head. Sync should continue from the persisted tail.
+		{
+			oldstate: []*subchain{linked(3*requestHeaders, 2*requestHeaders)},
+			head:     chain[3*requestHeaders],
+			midstate: []*subchain{{Head: 3 * requestHeaders, Tail: 2 * requestHeaders}},
+			endstate: []*subchain{{Head: 3 * requestHeaders, Tail: 1}},
+			endserve: 2*requestHeaders - 1,
+		},
+		// Crash in the middle of reverse downloading a single subchain, restarted
+		// with a head extending it. Sync should extend and continue the subchain.
+		{
+			oldstate: []*subchain{linked(3*requestHeaders, 2*requestHeaders)},
+			head:     chain[3*requestHeaders+1],
+			midstate: []*subchain{{Head: 3*requestHeaders + 1, Tail: 2 * requestHeaders}},
+			endstate: []*subchain{{Head: 3*requestHeaders + 1, Tail: 1}},
+			endserve: 2*requestHeaders - 1,
+		},
+		// Crash in the middle of reverse downloading a single subchain, restarted
+		// with a head far ahead. Sync should fill the gap, merge into the old
+		// subchain and continue from its tail.
+		{
+			oldstate: []*subchain{linked(3*requestHeaders, 2*requestHeaders)},
+			head:     chain[4*requestHeaders+1],
+			midstate: []*subchain{
+				{Head: 4*requestHeaders + 1, Tail: 4*requestHeaders + 1},
+				{Head: 3 * requestHeaders, Tail: 2 * requestHeaders},
+			},
+			endstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 1}},
+			endserve: requestHeaders + 2*requestHeaders - 1,
+		},
+		// Crash right before two adjacent subchains could be merged. Restarting
+		// should merge them from disk without touching the network for the seam.
+		{
+			oldstate: []*subchain{
+				linked(4*requestHeaders+1, 3*requestHeaders+1),
+				linked(3*requestHeaders, 2*requestHeaders),
+			},
+			head:     chain[4*requestHeaders+1],
+			midstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 2 * requestHeaders}},
+			endstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 1}},
+			endserve: 2*requestHeaders - 1,
+		},
+		// Crash after the primary subchain overwrote part of the next one, but
+		// before the overlap was resolved. Restarting should trim and merge.
+		{
+			oldstate: []*subchain{
+				linked(4*requestHeaders+1, 2*requestHeaders+requestHeaders/2),
+				linked(3*requestHeaders, 2*requestHeaders),
+			},
+			head:     chain[4*requestHeaders+1],
+			midstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 2 * requestHeaders}},
+			endstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 1}},
+			endserve: 2*requestHeaders - 1,
+		},
+		// Crash after the primary subchain fully overwrote the next one. The
+		// leftover should be dropped on restart.
+		{
+			oldstate: []*subchain{
+				linked(4*requestHeaders+1, 2*requestHeaders),
+				linked(3*requestHeaders, 3*requestHeaders-10),
+			},
+			head:     chain[4*requestHeaders+1],
+			midstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 2 * requestHeaders}},
+			endstate: []*subchain{{Head: 4*requestHeaders + 1, Tail: 1}},
+			endserve: 2*requestHeaders - 1,
+		},
+		// Crash with multiple gapped subchains persisted, restarted with the same
+		// head. Every gap should be filled and every subchain merged.
+		{
+			oldstate: []*subchain{
+				linked(6*requestHeaders+1, 5*requestHeaders+1),
+				linked(4*requestHeaders, 3*requestHeaders+1),
+				linked(2*requestHeaders, requestHeaders+1),
+			},
+			head: chain[6*requestHeaders+1],
+			midstate: []*subchain{
+				{Head: 6*requestHeaders + 1, Tail: 5*requestHeaders + 1},
+				{Head: 4 * requestHeaders, Tail: 3*requestHeaders + 1},
+				{Head: 2 * requestHeaders, Tail: requestHeaders + 1},
+			},
+			endstate: []*subchain{{Head: 6*requestHeaders + 1, Tail: 1}},
+			endserve: 3 * requestHeaders,
+		},
+	}
+	for i, tt := range tests {
+		// Create a fresh database and initialize it with the state left behind
+		// by the crashed sync
+		db := rawdb.NewMemoryDatabase()
+		rawdb.WriteHeader(db, chain[0])
+
+		for _, sc := range tt.oldstate {
+			for n := sc.Tail; n <= sc.Head; n++ {
+				rawdb.WriteSkeletonHeader(db, chain[n])
+			}
+		}
+		blob, _ := json.Marshal(&skeletonProgress{Subchains: tt.oldstate})
+		rawdb.WriteSkeletonSyncStatus(db, blob)
+
+		// Restart the skeleton sync without any peers and check that the state
+		// was correctly reassembled
+		var (
+			peerset = newPeerSet()
+			wait    = make(chan struct{})
+			once    sync.Once
+		)
+		skeleton := newSkeleton(db, peerset, nil, newHookedBackfiller())
+		skeleton.syncStarting = func() { once.Do(func() { close(wait) }) }
+		skeleton.Sync(tt.head, true)
+		<-wait
+
+		check := func(state string, want []*subchain) error {
+			var progress skeletonProgress
+			json.Unmarshal(rawdb.ReadSkeletonSyncStatus(db), &progress)
+
+			if len(progress.Subchains) != len(want) {
+				return fmt.Errorf("test %d, %s state: subchain count mismatch: have %d, want %d", i, state, len(progress.Subchains), len(want))
+			}
+			for j := 0; j < len(progress.Subchains); j++ {
+				if progress.Subchains[j].Head != want[j].Head {
+					return fmt.Errorf("test %d, %s state: subchain %d head mismatch: have %d, want %d", i, state, j, progress.Subchains[j].Head, want[j].Head)
+				}
+				if progress.Subchains[j].Tail != want[j].Tail {
+					return fmt.Errorf("test %d, %s state: subchain %d tail mismatch: have %d, want %d", i, state, j, progress.Subchains[j].Tail, want[j].Tail)
+				}
+			}
+			return nil
+		}
+		if err := check("mid", tt.midstate); err != nil {
+			t.Error(err)
+			skeleton.Terminate()
+			continue
+		}
+		// Join a peer and wait (bleah) for the sync to finish
+		peer := newSkeletonTestPeer("test-peer", chain)
+		if err := peerset.Register(newPeerConnection(peer.id, eth.ETH66, peer, log.New("id", peer.id))); err != nil {
+			t.Errorf("test %d: failed to register new peer: %v", i, err)
+		}
+		waitStart := time.Now()
+		for waitTime := 20 * time.Millisecond; time.Since(waitStart) < time.Second; waitTime = waitTime * 2 {
+			time.Sleep(waitTime)
+			if err := check("end", tt.endstate); err == nil {
+				break
+			}
+		}
+		if err := check("end", tt.endstate); err != nil {
+			t.Error(err)
+		}
+		if served := atomic.LoadUint64(&peer.served); served != tt.endserve {
+			t.Errorf("test %d, end state: served headers mismatch: have %d, want %d", i, served, tt.endserve)
+		}
+		skeleton.Terminate()
+	}
+}
+
+// Tests that the beacon sync status reports the persisted subchains and the
+// gaps left to download between them and the local chain.
+func TestSkeletonSyncStatus(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	skeleton := newSkeleton(db, newPeerSet(), nil, newHookedBackfiller())
+
+	if _, err := skeleton.Status(0); err == nil {
+		t.Fatalf("status available before sync started")
+	}
+	blob, _ := json.Marshal(&skeletonProgress{Subchains: []*subchain{
+		{Head: 100, Tail: 80},
+		{Head: 60, Tail: 50},
+		{Head: 49, Tail: 30},
+	}})
+	rawdb.WriteSkeletonSyncStatus(db, blob)
+
+	status, err := skeleton.Status(10)
+	if err != nil {
+		t.Fatalf("failed to retrieve status: %v", err)
+	}
+	if len(status.Subchains) != 3 {
+		t.Fatalf("subchain count mismatch: have %d, want %d", len(status.Subchains), 3)
+	}
+	if status.Filled != 10 {
+		t.Errorf("filled head mismatch: have %d, want %d", status.Filled, 10)
+	}
+	if status.Linked {
+		t.Errorf("unlinked sync reported as linked")
+	}
+	want := []BeaconSyncGap{{From: 61, To: 79}, {From: 11, To: 29}}
+	if len(status.Gaps) != len(want) {
+		t.Fatalf("gap count mismatch: have %d, want %d", len(status.Gaps), len(want))
+	}
+	for i := range want {
+		if status.Gaps[i] != want[i] {
+			t.Errorf("gap %d mismatch: have %v, want %v", i, status.Gaps[i], want[i])
+		}
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -87,6 +88,8 @@ type handlerConfig struct {
 	EventMux       *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint     *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	RequiredBlocks map[uint64]common.Hash    // Hard coded map of required block hashes for sync challenges
+
+	ReportPeer func(id enode.ID, ev p2p.ReputationEvent) // Callback to record peer reputation events (optional)
 }
 
 type handler struct {
@@ -116,6 +119,7 @@ type handler struct {
 	minedBlockSub *event.TypeMuxSubscription
 
 	requiredBlocks map[uint64]common.Hash
+	reporter       func(id enode.ID, ev p2p.ReputationEvent)
 
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
@@ -141,6 +145,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:          newPeerSet(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		reporter:       config.ReportPeer,
 		quitSync:       make(chan struct{}),
 	}
 	if config.Sync == downloader.FullSync {
@@ -194,7 +199,14 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	// Construct the downloader (long sync) and its backing state bloom if snap
 	// sync is requested. The downloader is responsible for deallocating the state
 	// bloom when it's done.
-	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.eventMux, h.chain, nil, h.removePeer, success)
+	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.eventMux, h.chain, nil, h.penalisePeer(p2p.ReputationUselessResponse), success)
+	h.downloader.SetRateHook(func(id string, kind uint64, items int) {
+		if items == 0 {
+			h.reportPeer(id, p2p.ReputationTimeout)
+		} else {
+			h.reportPeer(id, p2p.ReputationGoodDelivery)
+		}
+	})
 
 	// Construct the fetcher (short sync)
 	validator := func(header *types.Header) error {
@@ -281,7 +293,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return n, err
 	}
-	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer)
+	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.penalisePeer(p2p.ReputationInvalidBlock))
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -290,7 +302,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return p.RequestTxs(hashes)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx, h.penalisePeer(p2p.ReputationInvalidTx))
 	h.chainSync = newChainSyncer(h)
 	return h, nil
 }
@@ -487,6 +499,25 @@ func (h *handler) removePeer(id string) {
 	}
 }
 
+// reportPeer records a reputation event for a peer with the p2p server.
+func (h *handler) reportPeer(id string, ev p2p.ReputationEvent) {
+	if h.reporter == nil {
+		return
+	}
+	if nodeID, err := enode.ParseID(id); err == nil {
+		h.reporter(nodeID, ev)
+	}
+}
+
+// penalisePeer returns a peer drop callback which records the given reputation
+// event before requesting the disconnection of the peer.
+func (h *handler) penalisePeer(ev p2p.ReputationEvent) func(id string) {
+	return func(id string) {
+		h.reportPeer(id, ev)
+		h.removePeer(id)
+	}
+}
+
 // unregisterPeer removes a peer from the downloader, fetchers and main peer set.
 func (h *handler) unregisterPeer(id string) {
 	// Create a custom logger to avoid printing the entire id
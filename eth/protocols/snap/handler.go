@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
@@ -55,6 +56,12 @@ const (
 	// If we spend too much time, then it's a fairly high chance of timing out
 	// at the remote side, which means all the work is in vain.
 	maxTrieNodeTimeSpent = 5 * time.Second
+
+	// maxStateRangeTimeSpent is the maximum time we should spend on iterating
+	// an account or storage range. Serving from the snapshot is fast, but nodes
+	// without a snapshot iterate the tries, which needs a database read for
+	// every trie node.
+	maxStateRangeTimeSpent = 2 * time.Second
 )
 
 // Handler is a callback to invoke from an outside runner after the boilerplate
@@ -284,12 +291,14 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 	if req.Bytes > softResponseLimit {
 		req.Bytes = softResponseLimit
 	}
+	start := time.Now()
+
 	// Retrieve the requested state and bail out if non existent
 	tr, err := trie.New(common.Hash{}, req.Root, chain.StateCache().TrieDB())
 	if err != nil {
 		return nil, nil
 	}
-	it, err := chain.Snapshots().AccountIterator(req.Root, req.Origin)
+	it, served, err := newAccountRangeIterator(chain, req.Root, req.Origin)
 	if err != nil {
 		return nil, nil
 	}
@@ -300,7 +309,7 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 		last     common.Hash
 	)
 	for it.Next() {
-		hash, account := it.Hash(), common.CopyBytes(it.Account())
+		hash, account := it.Hash(), common.CopyBytes(it.Value())
 
 		// Track the returned interval for the Merkle proofs
 		last = hash
@@ -315,12 +324,19 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 		if bytes.Compare(hash[:], req.Limit[:]) >= 0 {
 			break
 		}
-		if size > req.Bytes {
+		if size > req.Bytes || time.Since(start) > maxStateRangeTimeSpent {
 			break
 		}
 	}
 	it.Release()
 
+	// If the iteration failed before yielding anything, don't claim that the
+	// range is empty.
+	if it.Error() != nil && len(accounts) == 0 {
+		return nil, nil
+	}
+	served.mark(start, len(accounts), size)
+
 	// Generate the Merkle proofs for the first and last account
 	proof := light.NewNodeSet()
 	if err := tr.Prove(req.Origin[:], 0, proof); err != nil {
@@ -350,6 +366,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 
 	// Calculate the hard limit at which to abort, even if mid storage trie
 	hardLimit := uint64(float64(req.Bytes) * (1 + stateLookupSlack))
+	start := time.Now()
 
 	// Retrieve storage ranges until the packet limit is reached
 	var (
@@ -360,7 +377,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 	for _, account := range req.Accounts {
 		// If we've exceeded the requested data limit, abort without opening
 		// a new storage range (that we'd need to prove due to exceeded size)
-		if size >= req.Bytes {
+		if size >= req.Bytes || time.Since(start) > maxStateRangeTimeSpent {
 			break
 		}
 		// The first account might start from a different origin and end sooner
@@ -373,28 +390,31 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			limit, req.Limit = common.BytesToHash(req.Limit), nil
 		}
 		// Retrieve the requested state and bail out if non existent
-		it, err := chain.Snapshots().StorageIterator(req.Root, account, origin)
+		it, served, err := newStorageRangeIterator(chain, req.Root, account, origin)
 		if err != nil {
 			return nil, nil
 		}
 		// Iterate over the requested range and pile slots up
 		var (
-			storage []*StorageData
-			last    common.Hash
-			abort   bool
+			storage   []*StorageData
+			last      common.Hash
+			abort     bool
+			rangeSize uint64
+			iterStart = time.Now()
 		)
 		for it.Next() {
 			if size >= hardLimit {
 				abort = true
 				break
 			}
-			hash, slot := it.Hash(), common.CopyBytes(it.Slot())
+			hash, slot := it.Hash(), common.CopyBytes(it.Value())
 
 			// Track the returned interval for the Merkle proofs
 			last = hash
 
 			// Assemble the reply item
 			size += uint64(common.HashLength + len(slot))
+			rangeSize += uint64(common.HashLength + len(slot))
 			storage = append(storage, &StorageData{
 				Hash: hash,
 				Body: slot,
@@ -403,11 +423,26 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			if bytes.Compare(hash[:], limit[:]) >= 0 {
 				break
 			}
+			// If we've exceeded the time budget, abort mid storage trie
+			if time.Since(start) > maxStateRangeTimeSpent {
+				abort = true
+				break
+			}
+		}
+		it.Release()
+
+		// A failed iteration can only be served up to the last slot retrieved,
+		// which needs to be proven.
+		if it.Error() != nil {
+			if len(storage) == 0 {
+				break
+			}
+			abort = true
 		}
 		if len(storage) > 0 {
 			slots = append(slots, storage)
 		}
-		it.Release()
+		served.mark(iterStart, len(storage), rangeSize)
 
 		// Generate the Merkle proofs for the first and last storage slot, but
 		// only if the response was capped. If the entire storage trie included
@@ -489,18 +524,16 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 	// Make sure we have the state associated with the request
 	triedb := chain.StateCache().TrieDB()
 
-	accTrie, err := trie.NewSecure(common.Hash{}, req.Root, triedb)
+	accTrie, err := trie.New(common.Hash{}, req.Root, triedb)
 	if err != nil {
 		// We don't have the requested state available, bail out
 		return nil, nil
 	}
-	snap := chain.Snapshots().Snapshot(req.Root)
-	if snap == nil {
-		// We don't have the requested state snapshotted yet, bail out.
-		// In reality we could still serve using the account and storage
-		// tries only, but let's protect the node a bit while it's doing
-		// snapshot generation.
-		return nil, nil
+	// The snapshot is used to look up storage roots. If it is disabled or not
+	// generated yet, fall back to the account trie.
+	var snap snapshot.Snapshot
+	if snaps := chain.Snapshots(); snaps != nil {
+		snap = snaps.Snapshot(req.Root)
 	}
 	// Retrieve trie nodes until the packet size limit is reached
 	var (
@@ -526,12 +559,12 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 
 		default:
 			// Storage slots requested, open the storage trie and retrieve from there
-			account, err := snap.Account(common.BytesToHash(pathset[0]))
+			root, err := storageRoot(snap, accTrie, common.BytesToHash(pathset[0]))
 			loads++ // always account database reads, even for failures
-			if err != nil || account == nil {
+			if err != nil || root == (common.Hash{}) {
 				break
 			}
-			stTrie, err := trie.NewSecure(common.BytesToHash(pathset[0]), common.BytesToHash(account.Root), triedb)
+			stTrie, err := trie.NewSecure(common.BytesToHash(pathset[0]), root, triedb)
 			loads++ // always account database reads, even for failures
 			if err != nil {
 				break
@@ -559,6 +592,37 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 	return nodes, nil
 }
 
+// storageRoot retrieves the storage root of an account, using the snapshot if
+// it is available and covers the account, and the account trie otherwise. The
+// zero hash is returned for unknown accounts.
+func storageRoot(snap snapshot.Snapshot, accTrie *trie.Trie, hash common.Hash) (common.Hash, error) {
+	if snap != nil {
+		account, err := snap.Account(hash)
+		if err == nil {
+			if account == nil {
+				return common.Hash{}, nil
+			}
+			// The slim account format omits the root of empty storage tries.
+			if len(account.Root) == 0 {
+				return types.EmptyRootHash, nil
+			}
+			return common.BytesToHash(account.Root), nil
+		}
+		if err != snapshot.ErrNotCoveredYet {
+			return common.Hash{}, err
+		}
+	}
+	blob, err := accTrie.TryGet(hash[:])
+	if err != nil || len(blob) == 0 {
+		return common.Hash{}, err
+	}
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		return common.Hash{}, err
+	}
+	return account.Root, nil
+}
+
 // NodeInfo represents a short summary of the `snap` sub-protocol metadata
 // known about the host peer.
 type NodeInfo struct{}
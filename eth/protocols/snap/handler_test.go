@@ -0,0 +1,168 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newServingChain creates a chain whose genesis state contains a number of
+// accounts, one of them with a large storage. The snapshot is only generated if
+// requested.
+func newServingChain(t *testing.T, snapshot bool) (*core.BlockChain, common.Address) {
+	var (
+		alloc    = make(core.GenesisAlloc)
+		storage  = make(map[common.Hash]common.Hash)
+		contract = common.Address{0xff}
+	)
+	for i := 0; i < 100; i++ {
+		alloc[common.BigToAddress(big.NewInt(int64(i+1)))] = core.GenesisAccount{Balance: big.NewInt(int64(i + 1))}
+	}
+	for i := 0; i < 100; i++ {
+		storage[common.BigToHash(big.NewInt(int64(i)))] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	alloc[contract] = core.GenesisAccount{Balance: common.Big1, Code: []byte{0x0}, Storage: storage}
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = &core.Genesis{Config: params.TestChainConfig, Alloc: alloc}
+		cache   = &core.CacheConfig{TrieCleanLimit: 16, TrieDirtyLimit: 16, SnapshotWait: true}
+	)
+	if snapshot {
+		cache.SnapshotLimit = 16
+	}
+	genesis.MustCommit(db)
+	chain, err := core.NewBlockChain(db, cache, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain, contract
+}
+
+// This test checks that account ranges served from the account trie match the
+// ones served from the snapshot.
+func TestServeAccountRangeWithoutSnapshot(t *testing.T) {
+	snapChain, _ := newServingChain(t, true)
+	defer snapChain.Stop()
+	trieChain, _ := newServingChain(t, false)
+	defer trieChain.Stop()
+
+	if trieChain.Snapshots() != nil {
+		t.Fatal("snapshot enabled")
+	}
+	var (
+		root  = snapChain.CurrentBlock().Root()
+		limit = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	)
+	for i, req := range []GetAccountRangePacket{
+		{Root: root, Limit: limit, Bytes: 2000},
+		{Root: root, Origin: common.HexToHash("0x8000"), Limit: limit, Bytes: softResponseLimit},
+		{Root: root, Origin: common.HexToHash("0x4000"), Limit: common.HexToHash("0x8000"), Bytes: softResponseLimit},
+	} {
+		req1, req2 := req, req
+		wantAccounts, wantProofs := ServiceGetAccountRangeQuery(snapChain, &req1)
+		accounts, proofs := ServiceGetAccountRangeQuery(trieChain, &req2)
+		if len(wantAccounts) == 0 {
+			t.Fatalf("test %d: no accounts served from snapshot", i)
+		}
+		if !reflect.DeepEqual(accounts, wantAccounts) {
+			t.Errorf("test %d: accounts mismatch: have %d, want %d", i, len(accounts), len(wantAccounts))
+		}
+		if !reflect.DeepEqual(proofs, wantProofs) {
+			t.Errorf("test %d: proofs mismatch: have %d, want %d", i, len(proofs), len(wantProofs))
+		}
+	}
+}
+
+// This test checks that storage ranges served from the storage tries match the
+// ones served from the snapshot, both for complete and capped ranges.
+func TestServeStorageRangesWithoutSnapshot(t *testing.T) {
+	snapChain, contract := newServingChain(t, true)
+	defer snapChain.Stop()
+	trieChain, _ := newServingChain(t, false)
+	defer trieChain.Stop()
+
+	var (
+		root    = snapChain.CurrentBlock().Root()
+		account = crypto.Keccak256Hash(contract[:])
+		empty   = crypto.Keccak256Hash([]byte{0x01})
+	)
+	for i, req := range []GetStorageRangesPacket{
+		{Root: root, Accounts: []common.Hash{account}, Bytes: softResponseLimit},
+		{Root: root, Accounts: []common.Hash{account}, Bytes: 1000},
+		{Root: root, Accounts: []common.Hash{account}, Origin: common.HexToHash("0x8000").Bytes(), Bytes: softResponseLimit},
+		{Root: root, Accounts: []common.Hash{empty, account}, Bytes: softResponseLimit},
+	} {
+		req1, req2 := req, req
+		wantSlots, wantProofs := ServiceGetStorageRangesQuery(snapChain, &req1)
+		slots, proofs := ServiceGetStorageRangesQuery(trieChain, &req2)
+		if len(wantSlots) == 0 {
+			t.Fatalf("test %d: no storage served from snapshot", i)
+		}
+		if !reflect.DeepEqual(slots, wantSlots) {
+			t.Errorf("test %d: slots mismatch", i)
+		}
+		if !reflect.DeepEqual(proofs, wantProofs) {
+			t.Errorf("test %d: proofs mismatch: have %d, want %d", i, len(proofs), len(wantProofs))
+		}
+	}
+}
+
+// This test checks that storage trie nodes can be served without a snapshot.
+func TestServeTrieNodesWithoutSnapshot(t *testing.T) {
+	chain, contract := newServingChain(t, false)
+	defer chain.Stop()
+
+	var (
+		root    = chain.CurrentBlock().Root()
+		account = crypto.Keccak256Hash(contract[:])
+	)
+	state, err := chain.StateAt(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageRoot := state.StorageTrie(contract).Hash()
+	req := &GetTrieNodesPacket{
+		Root:  root,
+		Paths: []TrieNodePathSet{{{}}, {account[:], {}}},
+		Bytes: softResponseLimit,
+	}
+	nodes, err := ServiceGetTrieNodesQuery(chain, req, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("wrong number of nodes: have %d, want 2", len(nodes))
+	}
+	if hash := crypto.Keccak256Hash(nodes[0]); hash != root {
+		t.Errorf("wrong account trie root node: hash %x, want %x", hash, root)
+	}
+	if hash := crypto.Keccak256Hash(nodes[1]); hash != storageRoot {
+		t.Errorf("wrong storage trie root node: hash %x, want %x", hash, storageRoot)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// rangeIterator iterates over the accounts or storage slots of a state range in
+// ascending hash order. It is backed either by the snapshot or, if that is not
+// available, by the tries.
+type rangeIterator interface {
+	// Next steps the iterator forward one element, returning false if exhausted.
+	Next() bool
+
+	// Error returns any failure that occurred during iteration.
+	Error() error
+
+	// Hash returns the hash of the account or storage slot the iterator is
+	// currently at.
+	Hash() common.Hash
+
+	// Value returns the current account in slim RLP format, or the current
+	// RLP-encoded storage slot.
+	Value() []byte
+
+	// Release releases associated resources.
+	Release()
+}
+
+// snapAccountIterator serves accounts from a snapshot account iterator.
+type snapAccountIterator struct {
+	snapshot.AccountIterator
+}
+
+func (it snapAccountIterator) Value() []byte { return it.Account() }
+
+// snapStorageIterator serves storage slots from a snapshot storage iterator.
+type snapStorageIterator struct {
+	snapshot.StorageIterator
+}
+
+func (it snapStorageIterator) Value() []byte { return it.Slot() }
+
+// trieRangeIterator serves accounts or storage slots by iterating the leaves of
+// a trie. Accounts are stored in the trie in their full format, so they are
+// converted to the slim format used by the protocol.
+type trieRangeIterator struct {
+	it      *trie.Iterator
+	account bool
+	value   []byte
+	err     error
+}
+
+func newTrieRangeIterator(tr *trie.Trie, origin common.Hash, account bool) *trieRangeIterator {
+	return &trieRangeIterator{
+		it:      trie.NewIterator(tr.NodeIterator(origin[:])),
+		account: account,
+	}
+}
+
+func (it *trieRangeIterator) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		if it.err == nil {
+			it.err = it.it.Err
+		}
+		return false
+	}
+	if !it.account {
+		it.value = common.CopyBytes(it.it.Value)
+		return true
+	}
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(it.it.Value, &acc); err != nil {
+		it.err = err
+		return false
+	}
+	it.value = snapshot.SlimAccountRLP(acc.Nonce, acc.Balance, acc.Root, acc.CodeHash)
+	return true
+}
+
+func (it *trieRangeIterator) Error() error      { return it.err }
+func (it *trieRangeIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *trieRangeIterator) Value() []byte     { return it.value }
+func (it *trieRangeIterator) Release()          {}
+
+// newAccountRangeIterator creates an iterator over the accounts of the given
+// state, starting at origin. The snapshot is preferred, but if it is disabled,
+// still being generated or doesn't cover the state, the account trie is used.
+func newAccountRangeIterator(chain *core.BlockChain, root common.Hash, origin common.Hash) (rangeIterator, *serveMetrics, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.AccountIterator(root, origin); err == nil {
+			return snapAccountIterator{it}, accountSnapshotServe, nil
+		}
+	}
+	tr, err := trie.New(common.Hash{}, root, chain.StateCache().TrieDB())
+	if err != nil {
+		return nil, nil, err
+	}
+	return newTrieRangeIterator(tr, origin, true), accountTrieServe, nil
+}
+
+// newStorageRangeIterator creates an iterator over the storage slots of an
+// account in the given state, starting at origin. Like accounts, storage is
+// served from the snapshot if possible and from the storage trie otherwise.
+func newStorageRangeIterator(chain *core.BlockChain, root common.Hash, account common.Hash, origin common.Hash) (rangeIterator, *serveMetrics, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.StorageIterator(root, account, origin); err == nil {
+			return snapStorageIterator{it}, storageSnapshotServe, nil
+		}
+	}
+	triedb := chain.StateCache().TrieDB()
+	accTrie, err := trie.New(common.Hash{}, root, triedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := accTrie.TryGet(account[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	// Unknown accounts have no storage, same as in the snapshot.
+	var acc types.StateAccount
+	if len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return nil, nil, err
+		}
+	}
+	stTrie, err := trie.New(account, acc.Root, triedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newTrieRangeIterator(stTrie, origin, false), storageTrieServe, nil
+}
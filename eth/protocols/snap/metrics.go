@@ -0,0 +1,55 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Metrics of serving state ranges, split by the data source to compare the cost
+// of iterating the snapshot and the tries.
+var (
+	accountSnapshotServe = newServeMetrics("account", "snapshot")
+	accountTrieServe     = newServeMetrics("account", "trie")
+	storageSnapshotServe = newServeMetrics("storage", "snapshot")
+	storageTrieServe     = newServeMetrics("storage", "trie")
+)
+
+// serveMetrics measures the ranges served from one data source.
+type serveMetrics struct {
+	timer metrics.Timer // Time spent on iterating a single range
+	items metrics.Meter // Number of accounts or storage slots served
+	bytes metrics.Meter // Size of the served accounts or storage slots
+}
+
+func newServeMetrics(kind, source string) *serveMetrics {
+	prefix := "eth/protocols/snap/serve/" + kind + "/" + source
+	return &serveMetrics{
+		timer: metrics.NewRegisteredTimer(prefix+"/time", nil),
+		items: metrics.NewRegisteredMeter(prefix+"/items", nil),
+		bytes: metrics.NewRegisteredMeter(prefix+"/bytes", nil),
+	}
+}
+
+// mark records a served range.
+func (m *serveMetrics) mark(start time.Time, items int, bytes uint64) {
+	m.timer.UpdateSince(start)
+	m.items.Mark(int64(items))
+	m.bytes.Mark(int64(bytes))
+}
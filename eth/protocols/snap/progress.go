@@ -0,0 +1,182 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// statusInterval is the minimum time between recalculating the sync status,
+// which needs to walk all pending heal requests.
+const statusInterval = 3 * time.Second
+
+// SyncPhaseStatus is the progress of a single kind of data retrieved by the
+// syncer, along with an estimate of the work left.
+type SyncPhaseStatus struct {
+	Synced    hexutil.Uint64 `json:"synced"`    // Number of items retrieved
+	Bytes     hexutil.Uint64 `json:"bytes"`     // Size of the items retrieved
+	Remaining hexutil.Uint64 `json:"remaining"` // Estimated number of items left, zero if unknown
+	Rate      float64        `json:"rate"`      // Items retrieved per second
+	ETA       hexutil.Uint64 `json:"eta"`       // Estimated seconds left, zero if unknown
+}
+
+// SyncStatus is a detailed report of the snap sync progress. Opposed to
+// SyncProgress, it includes estimates of the remaining work, which are
+// refreshed periodically while the sync is running.
+type SyncStatus struct {
+	Root     common.Hash    `json:"root"`     // State root being synced
+	Healing  bool           `json:"healing"`  // Whether the sync is in its heal phase
+	Progress float64        `json:"progress"` // Estimated fraction of the current phase done
+	SnapTime hexutil.Uint64 `json:"snapTime"` // Seconds spent in the snap phase, across restarts
+	HealTime hexutil.Uint64 `json:"healTime"` // Seconds spent in the heal phase, across restarts
+
+	// Snap phase
+	Accounts  SyncPhaseStatus `json:"accounts"`
+	Storage   SyncPhaseStatus `json:"storage"`
+	Bytecodes SyncPhaseStatus `json:"bytecodes"`
+
+	// Heal phase
+	Trienodes       SyncPhaseStatus `json:"trienodes"`
+	HealedBytecodes SyncPhaseStatus `json:"healedBytecodes"`
+	HealedAccounts  hexutil.Uint64  `json:"healedAccounts"`
+	HealedStorage   hexutil.Uint64  `json:"healedStorage"`
+
+	// Pending heal requests by trie depth, the base of the heal estimates.
+	HealFrontier        []int `json:"healFrontier"`
+	StorageHealFrontier []int `json:"storageHealFrontier"`
+}
+
+// Status returns a detailed report of the snap sync progress.
+func (s *Syncer) Status() *SyncStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.extStatus == nil {
+		return new(SyncStatus)
+	}
+	return s.extStatus
+}
+
+// trackTime adds the time since the last call to the duration of the current
+// sync phase.
+func (s *Syncer) trackTime() {
+	now := time.Now()
+	if !s.timeMark.IsZero() {
+		if len(s.tasks) == 0 {
+			s.healTime += now.Sub(s.timeMark)
+		} else {
+			s.snapTime += now.Sub(s.timeMark)
+		}
+	}
+	s.timeMark = now
+}
+
+// makeStatus assembles the current sync status. It must be called from the
+// sync loop, as it accesses the heal scheduler.
+func (s *Syncer) makeStatus() *SyncStatus {
+	status := &SyncStatus{
+		Root:           s.root,
+		Healing:        len(s.tasks) == 0,
+		SnapTime:       hexutil.Uint64(s.snapTime / time.Second),
+		HealTime:       hexutil.Uint64(s.healTime / time.Second),
+		HealedAccounts: hexutil.Uint64(s.accountHealed),
+		HealedStorage:  hexutil.Uint64(s.storageHealed),
+	}
+	if !status.Healing {
+		// The account, storage and bytecode retrievals are all driven by the
+		// account range tasks, so they share the same progress.
+		status.Progress = s.snapProgress()
+		status.Accounts = phaseStatus(s.accountSynced, s.accountBytes, estimateRemaining(s.accountSynced, status.Progress), s.snapTime)
+		status.Storage = phaseStatus(s.storageSynced, s.storageBytes, estimateRemaining(s.storageSynced, status.Progress), s.snapTime)
+		status.Bytecodes = phaseStatus(s.bytecodeSynced, s.bytecodeBytes, estimateRemaining(s.bytecodeSynced, status.Progress), s.snapTime)
+		status.Trienodes = phaseStatus(s.trienodeHealSynced, s.trienodeHealBytes, 0, 0)
+		status.HealedBytecodes = phaseStatus(s.bytecodeHealSynced, s.bytecodeHealBytes, 0, 0)
+		return status
+	}
+	status.Accounts = phaseStatus(s.accountSynced, s.accountBytes, 0, s.snapTime)
+	status.Storage = phaseStatus(s.storageSynced, s.storageBytes, 0, s.snapTime)
+	status.Bytecodes = phaseStatus(s.bytecodeSynced, s.bytecodeBytes, 0, s.snapTime)
+
+	// Estimate the trie nodes left to heal from the coverage of the account
+	// trie. Storage tries are too diverse to estimate, but their pending nodes
+	// need to be retrieved for sure.
+	status.HealFrontier, status.StorageHealFrontier = s.healer.scheduler.Frontier()
+	status.Progress = healCoverage(status.HealFrontier)
+
+	remaining := estimateRemaining(s.trienodeHealSynced, status.Progress)
+	for _, n := range status.StorageHealFrontier {
+		remaining += uint64(n)
+	}
+	status.Trienodes = phaseStatus(s.trienodeHealSynced, s.trienodeHealBytes, remaining, s.healTime)
+	status.HealedBytecodes = phaseStatus(s.bytecodeHealSynced, s.bytecodeHealBytes, uint64(len(s.healer.codeTasks)), s.healTime)
+	return status
+}
+
+// snapProgress returns the fraction of the account hash space already synced.
+func (s *Syncer) snapProgress() float64 {
+	gaps := new(big.Int)
+	for _, task := range s.tasks {
+		gaps.Add(gaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
+	}
+	fills := new(big.Int).Sub(hashSpace, gaps)
+	progress, _ := new(big.Float).Quo(new(big.Float).SetInt(fills), new(big.Float).SetInt(hashSpace)).Float64()
+	return progress
+}
+
+// healCoverage estimates the fraction of the account trie which is healed. Every
+// pending trie node at depth d is assumed to root a subtrie that still needs to
+// be healed, covering 16^-d of the hash space.
+func healCoverage(frontier []int) float64 {
+	var open float64
+	for depth, n := range frontier {
+		open += float64(n) * math.Pow(16, -float64(depth))
+	}
+	if open > 1 {
+		return 0
+	}
+	return 1 - open
+}
+
+// estimateRemaining extrapolates the number of items left, given the number of
+// items retrieved so far and the fraction of the work done.
+func estimateRemaining(done uint64, progress float64) uint64 {
+	if progress <= 0 || progress >= 1 {
+		return 0
+	}
+	return uint64(float64(done) * (1 - progress) / progress)
+}
+
+// phaseStatus calculates the rate and ETA of a sync phase.
+func phaseStatus(synced uint64, bytes common.StorageSize, remaining uint64, elapsed time.Duration) SyncPhaseStatus {
+	status := SyncPhaseStatus{
+		Synced:    hexutil.Uint64(synced),
+		Bytes:     hexutil.Uint64(bytes),
+		Remaining: hexutil.Uint64(remaining),
+	}
+	if elapsed > 0 {
+		status.Rate = float64(synced) / elapsed.Seconds()
+	}
+	if status.Rate > 0 && remaining > 0 {
+		status.ETA = hexutil.Uint64(float64(remaining) / status.Rate)
+	}
+	return status
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestHealCoverage(t *testing.T) {
+	tests := []struct {
+		frontier []int
+		coverage float64
+	}{
+		{nil, 1},
+		{[]int{1}, 0},
+		{[]int{0, 8}, 0.5},
+		{[]int{0, 4, 64}, 0.5},
+		{[]int{0, 0, 0, 0, 16}, 1 - 1.0/4096},
+		{[]int{0, 32}, 0},
+	}
+	for _, test := range tests {
+		if coverage := healCoverage(test.frontier); coverage != test.coverage {
+			t.Errorf("frontier %v: coverage %v, want %v", test.frontier, coverage, test.coverage)
+		}
+	}
+}
+
+// Tests the estimates of the snap phase, which are extrapolated from the
+// account hash space already synced.
+func TestSyncStatusEstimates(t *testing.T) {
+	s := NewSyncer(rawdb.NewMemoryDatabase())
+	s.loadSyncStatus()
+
+	// Drop half the tasks, pretending they are done.
+	s.tasks = s.tasks[len(s.tasks)/2:]
+	s.accountSynced, s.storageSynced, s.bytecodeSynced = 100, 1000, 0
+	s.snapTime = 10 * time.Second
+
+	status := s.makeStatus()
+	if status.Healing {
+		t.Fatal("status reports healing")
+	}
+	if status.Progress < 0.49 || status.Progress > 0.51 {
+		t.Fatalf("wrong progress %v", status.Progress)
+	}
+	if a := status.Accounts; a.Remaining < 95 || a.Remaining > 105 || a.Rate != 10 || a.ETA < 9 || a.ETA > 11 {
+		t.Errorf("wrong account estimates: %+v", a)
+	}
+	if st := status.Storage; st.Remaining < 950 || st.Remaining > 1050 || st.Rate != 100 || st.ETA < 9 || st.ETA > 11 {
+		t.Errorf("wrong storage estimates: %+v", st)
+	}
+	if b := status.Bytecodes; b.Remaining != 0 || b.Rate != 0 || b.ETA != 0 {
+		t.Errorf("wrong bytecode estimates: %+v", b)
+	}
+}
+
+// Tests that the heal statistics and phase times survive a restart.
+func TestSyncStatusPersistence(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	s := NewSyncer(db)
+	s.loadSyncStatus()
+	s.tasks = nil
+	s.trienodeHealSynced, s.accountHealed, s.storageHealed = 1000, 10, 20
+	s.accountHealedBytes, s.storageHealedBytes = 100, 200
+	s.snapTime, s.healTime = time.Hour, time.Minute
+	s.saveSyncStatus()
+
+	s = NewSyncer(db)
+	s.loadSyncStatus()
+	if s.trienodeHealSynced != 1000 || s.accountHealed != 10 || s.storageHealed != 20 {
+		t.Errorf("heal counters not restored: nodes %d, accounts %d, slots %d", s.trienodeHealSynced, s.accountHealed, s.storageHealed)
+	}
+	if s.accountHealedBytes != 100 || s.storageHealedBytes != 200 {
+		t.Errorf("heal sizes not restored: accounts %v, slots %v", s.accountHealedBytes, s.storageHealedBytes)
+	}
+	if s.snapTime != time.Hour || s.healTime != time.Minute {
+		t.Errorf("phase times not restored: snap %v, heal %v", s.snapTime, s.healTime)
+	}
+}
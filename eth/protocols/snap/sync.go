@@ -344,6 +344,14 @@ type SyncProgress struct {
 	TrienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
 	BytecodeHealSynced uint64             // Number of bytecodes downloaded
 	BytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+	AccountHealed      uint64             // Number of accounts downloaded during the healing stage
+	AccountHealedBytes common.StorageSize // Number of raw account bytes persisted to disk during the healing stage
+	StorageHealed      uint64             // Number of storage slots downloaded during the healing stage
+	StorageHealedBytes common.StorageSize // Number of raw storage bytes persisted to disk during the healing stage
+
+	// Time spent in the sync phases, used to calculate the sync rates
+	SnapTime time.Duration // Time spent in the syncing phase
+	HealTime time.Duration // Time spent in the healing phase
 }
 
 // SyncPending is analogous to SyncProgress, but it's used to report on pending
@@ -449,6 +457,12 @@ type Syncer struct {
 	startTime time.Time // Time instance when snapshot sync started
 	logTime   time.Time // Time instance when status was last reported
 
+	snapTime   time.Duration // Time spent in the syncing phase, including previous runs
+	healTime   time.Duration // Time spent in the healing phase, including previous runs
+	timeMark   time.Time     // Time instance when the phase times were last updated
+	statusTime time.Time     // Time instance when the status was last assembled
+	extStatus  *SyncStatus   // Detailed status that can be exposed to external caller
+
 	pend sync.WaitGroup // Tracks network request goroutines for graceful shutdown
 	lock sync.RWMutex   // Protects fields that can change outside of sync (peers, reqs, root)
 }
@@ -571,6 +585,15 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			s.forwardAccountTask(task)
 		}
 		s.cleanAccountTasks()
+
+		// Stop the phase timers until the next cycle starts
+		s.trackTime()
+		s.timeMark = time.Time{}
+
+		s.lock.Lock()
+		s.extStatus = s.makeStatus()
+		s.lock.Unlock()
+
 		s.saveSyncStatus()
 	}()
 
@@ -651,6 +674,11 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			BytecodeHealSynced: s.bytecodeHealSynced,
 			BytecodeHealBytes:  s.bytecodeHealBytes,
 		}
+		s.trackTime()
+		if time.Since(s.statusTime) > statusInterval {
+			s.extStatus = s.makeStatus()
+			s.statusTime = time.Now()
+		}
 		s.lock.Unlock()
 		// Wait for something to happen
 		select {
@@ -740,6 +768,13 @@ func (s *Syncer) loadSyncStatus() {
 			s.trienodeHealBytes = progress.TrienodeHealBytes
 			s.bytecodeHealSynced = progress.BytecodeHealSynced
 			s.bytecodeHealBytes = progress.BytecodeHealBytes
+			s.accountHealed = progress.AccountHealed
+			s.accountHealedBytes = progress.AccountHealedBytes
+			s.storageHealed = progress.StorageHealed
+			s.storageHealedBytes = progress.StorageHealedBytes
+
+			s.snapTime = progress.SnapTime
+			s.healTime = progress.HealTime
 			return
 		}
 	}
@@ -752,6 +787,9 @@ func (s *Syncer) loadSyncStatus() {
 	s.storageSynced, s.storageBytes = 0, 0
 	s.trienodeHealSynced, s.trienodeHealBytes = 0, 0
 	s.bytecodeHealSynced, s.bytecodeHealBytes = 0, 0
+	s.accountHealed, s.accountHealedBytes = 0, 0
+	s.storageHealed, s.storageHealedBytes = 0, 0
+	s.snapTime, s.healTime = 0, 0
 
 	var next common.Hash
 	step := new(big.Int).Sub(
@@ -812,6 +850,12 @@ func (s *Syncer) saveSyncStatus() {
 		TrienodeHealBytes:  s.trienodeHealBytes,
 		BytecodeHealSynced: s.bytecodeHealSynced,
 		BytecodeHealBytes:  s.bytecodeHealBytes,
+		AccountHealed:      s.accountHealed,
+		AccountHealedBytes: s.accountHealedBytes,
+		StorageHealed:      s.storageHealed,
+		StorageHealedBytes: s.storageHealedBytes,
+		SnapTime:           s.snapTime,
+		HealTime:           s.healTime,
 	}
 	status, err := json.Marshal(progress)
 	if err != nil {
@@ -2869,8 +2913,13 @@ func (s *Syncer) reportHealProgress(force bool) {
 		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountHealed), s.accountHealedBytes.TerminalString())
 		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageHealed), s.storageHealedBytes.TerminalString())
 	)
-	log.Info("State heal in progress", "accounts", accounts, "slots", storage,
-		"codes", bytecode, "nodes", trienode, "pending", s.healer.scheduler.Pending())
+	ctx := []interface{}{"accounts", accounts, "slots", storage,
+		"codes", bytecode, "nodes", trienode, "pending", s.healer.scheduler.Pending()}
+	if status := s.extStatus; status != nil && status.Healing && status.Trienodes.ETA > 0 {
+		ctx = append(ctx, "remaining", log.FormatLogfmtUint64(uint64(status.Trienodes.Remaining)),
+			"eta", common.PrettyDuration(time.Duration(status.Trienodes.ETA)*time.Second))
+	}
+	log.Info("State heal in progress", ctx...)
 }
 
 // estimateRemainingSlots tries to determine roughly how many slots are left in
@@ -314,8 +314,12 @@ func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, err
 
 // SubscribeNewHead subscribes to notifications about the current blockchain head
 // on the given channel.
+//
+// If the RPC client is in reconnecting mode, heads missed while reconnecting are
+// delivered after the subscription was re-established. The subscription fails with
+// *GapError if they can't be retrieved.
 func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
-	return ec.c.EthSubscribe(ctx, ch, "newHeads")
+	return ec.c.SubscribeWithResumer(ctx, "eth", ch, newHeadResumer(ec), "newHeads")
 }
 
 // State Access
@@ -379,12 +383,28 @@ func (ec *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]typ
 }
 
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
+//
+// If the RPC client is in reconnecting mode, logs missed while reconnecting are
+// delivered after the subscription was re-established. The subscription fails with
+// *GapError if they can't be retrieved.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	arg, err := toFilterArg(q)
 	if err != nil {
 		return nil, err
 	}
-	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
+	if !ec.c.ReconnectEnabled() {
+		return ec.c.EthSubscribe(ctx, ch, "logs", arg)
+	}
+	resumer := newLogResumer(ec, q)
+	sub, err := ec.c.SubscribeWithResumer(ctx, "eth", ch, resumer, "logs", arg)
+	if err != nil {
+		return nil, err
+	}
+	if err := resumer.start(ctx); err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+	return sub, nil
 }
 
 func toFilterArg(q ethereum.FilterQuery) (interface{}, error) {
@@ -582,6 +602,19 @@ type rpcProgress struct {
 	HealedBytecodeBytes hexutil.Uint64
 	HealingTrienodes    hexutil.Uint64
 	HealingBytecode     hexutil.Uint64
+
+	AccountsRemaining  hexutil.Uint64
+	AccountsRate       hexutil.Uint64
+	AccountsETA        hexutil.Uint64
+	StorageRemaining   hexutil.Uint64
+	StorageRate        hexutil.Uint64
+	StorageETA         hexutil.Uint64
+	BytecodesRemaining hexutil.Uint64
+	BytecodesRate      hexutil.Uint64
+	BytecodesETA       hexutil.Uint64
+	HealingRemaining   hexutil.Uint64
+	HealingRate        hexutil.Uint64
+	HealingETA         hexutil.Uint64
 }
 
 func (p *rpcProgress) toSyncProgress() *ethereum.SyncProgress {
@@ -606,5 +639,17 @@ func (p *rpcProgress) toSyncProgress() *ethereum.SyncProgress {
 		HealedBytecodeBytes: uint64(p.HealedBytecodeBytes),
 		HealingTrienodes:    uint64(p.HealingTrienodes),
 		HealingBytecode:     uint64(p.HealingBytecode),
+		AccountsRemaining:   uint64(p.AccountsRemaining),
+		AccountsRate:        uint64(p.AccountsRate),
+		AccountsETA:         uint64(p.AccountsETA),
+		StorageRemaining:    uint64(p.StorageRemaining),
+		StorageRate:         uint64(p.StorageRate),
+		StorageETA:          uint64(p.StorageETA),
+		BytecodesRemaining:  uint64(p.BytecodesRemaining),
+		BytecodesRate:       uint64(p.BytecodesRate),
+		BytecodesETA:        uint64(p.BytecodesETA),
+		HealingRemaining:    uint64(p.HealingRemaining),
+		HealingRate:         uint64(p.HealingRate),
+		HealingETA:          uint64(p.HealingETA),
 	}
 }
@@ -19,6 +19,7 @@ package ethclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -294,6 +295,12 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"HeadResumer": {
+			func(t *testing.T) { testHeadResumer(t, chain, client) },
+		},
+		"LogResumer": {
+			func(t *testing.T) { testLogResumer(t, client) },
+		},
 	}
 
 	t.Parallel()
@@ -669,6 +676,82 @@ func testTransactionSender(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testHeadResumer(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+	genesis, _ := json.Marshal(chain[0].Header())
+
+	// Without a delivered head, nothing is backfilled.
+	r := newHeadResumer(ec)
+	if headers, err := r.Resume(context.Background()); err != nil || len(headers) != 0 {
+		t.Fatalf("unexpected backfill without head: %d headers, err %v", len(headers), err)
+	}
+
+	// Headers after the last delivered one are backfilled.
+	if !r.Accept(genesis) {
+		t.Fatal("head not accepted")
+	}
+	if r.Accept(genesis) {
+		t.Fatal("duplicate head accepted")
+	}
+	headers, err := r.Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != len(chain)-1 {
+		t.Fatalf("wrong number of backfilled headers %d", len(headers))
+	}
+	for i, enc := range headers {
+		var h types.Header
+		if err := json.Unmarshal(enc, &h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Hash() != chain[i+1].Hash() {
+			t.Errorf("header %d: wrong hash %x", i, h.Hash())
+		}
+		if !r.Accept(enc) {
+			t.Errorf("header %d not accepted", i)
+		}
+	}
+
+	// Gaps beyond the limit are reported.
+	r = newHeadResumer(ec)
+	r.limit = 1
+	r.Accept(genesis)
+	_, err = r.Resume(context.Background())
+	if gap, ok := err.(*GapError); !ok || gap.From != 1 || gap.To != uint64(len(chain)-1) {
+		t.Fatalf("wrong error %v", err)
+	}
+}
+
+func testLogResumer(t *testing.T, client *rpc.Client) {
+	ec := NewClient(client)
+	r := newLogResumer(ec, ethereum.FilterQuery{Addresses: []common.Address{testAddr}})
+	if _, err := r.Resume(context.Background()); err == nil {
+		t.Fatal("expected error for unknown subscription start")
+	}
+	if err := r.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	head := r.covered
+
+	// Logs are deduplicated and move the covered block.
+	log := []byte(`{"address":"0x0000000000000000000000000000000000000001","topics":[],"data":"0x","blockNumber":"0x1","transactionHash":"0x0000000000000000000000000000000000000000000000000000000000000002","transactionIndex":"0x0","blockHash":"0x0000000000000000000000000000000000000000000000000000000000000003","logIndex":"0x0","removed":false}`)
+	if !r.Accept(log) || r.Accept(log) {
+		t.Fatal("wrong deduplication of logs")
+	}
+	r.covered = 0
+	logs, err := r.Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("unexpected logs %s", logs)
+	}
+	if r.covered != head {
+		t.Errorf("covered block not updated: %d, want %d", r.covered, head)
+	}
+}
+
 func sendTransaction(ec *Client) error {
 	chainID, err := ec.ChainID(context.Background())
 	if err != nil {
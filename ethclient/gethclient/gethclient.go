@@ -79,7 +79,17 @@ type StorageResult struct {
 // GetProof returns the account and storage values of the specified account including the Merkle-proof.
 // The block number can be nil, in which case the value is taken from the latest known block.
 func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*AccountResult, error) {
+	return ec.getProof(ctx, account, keys, toBlockNumArg(blockNumber))
+}
+
+// GetProofAtHash is almost the same as GetProof except that it selects the block by
+// hash instead of by number, so that proofs of historical state can be pinned to a
+// block which can't be reorged away underneath the caller.
+func (ec *Client) GetProofAtHash(ctx context.Context, account common.Address, keys []string, blockHash common.Hash) (*AccountResult, error) {
+	return ec.getProof(ctx, account, keys, rpc.BlockNumberOrHashWithHash(blockHash, false))
+}
 
+func (ec *Client) getProof(ctx context.Context, account common.Address, keys []string, block interface{}) (*AccountResult, error) {
 	type storageResult struct {
 		Key   string       `json:"key"`
 		Value *hexutil.Big `json:"value"`
@@ -97,7 +107,9 @@ func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []s
 	}
 
 	var res accountResult
-	err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber))
+	if err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, block); err != nil {
+		return nil, err
+	}
 	// Turn hexutils back to normal datatypes
 	storageResults := make([]StorageResult, 0, len(res.StorageProof))
 	for _, st := range res.StorageProof {
@@ -116,7 +128,7 @@ func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []s
 		StorageHash:  res.StorageHash,
 		StorageProof: storageResults,
 	}
-	return &result, err
+	return &result, nil
 }
 
 // OverrideAccount specifies the state of an account to be overridden.
@@ -110,6 +110,9 @@ func TestGethClient(t *testing.T) {
 		{
 			"TestGetProof",
 			func(t *testing.T) { testGetProof(t, client) },
+		}, {
+			"TestGetProofAtHash",
+			func(t *testing.T) { testGetProofAtHash(t, client) },
 		}, {
 			"TestGCStats",
 			func(t *testing.T) { testGCStats(t, client) },
@@ -225,6 +228,29 @@ func testGetProof(t *testing.T, client *rpc.Client) {
 
 }
 
+func testGetProofAtHash(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	ethcl := ethclient.NewClient(client)
+	genesis, err := ethcl.HeaderByNumber(context.Background(), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.GetProofAtHash(context.Background(), testAddr, []string{testSlot.String()}, genesis.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Balance.Cmp(testBalance) != 0 {
+		t.Fatalf("invalid balance, want: %v got: %v", testBalance, result.Balance)
+	}
+	if len(result.StorageProof) != 1 || result.StorageProof[0].Value.Cmp(testValue.Big()) != 0 {
+		t.Fatalf("invalid storage proof, want: %v got: %v", testValue.Big(), result.StorageProof)
+	}
+	// Unknown blocks must be reported as errors instead of empty results
+	if _, err := ec.GetProofAtHash(context.Background(), testAddr, nil, common.Hash{0x01}); err == nil {
+		t.Fatal("expected error for unknown block hash")
+	}
+}
+
 func testGCStats(t *testing.T, client *rpc.Client) {
 	ec := New(client)
 	_, err := ec.GCStats(context.Background())
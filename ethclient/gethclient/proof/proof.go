@@ -0,0 +1,196 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package proof verifies the Merkle-Patricia proofs returned by eth_getProof.
+//
+// A proof obtained through gethclient.GetProof is only as trustworthy as the node
+// which served it. Verifying it against a state root obtained from a trusted source
+// (e.g. a header validated by a light client) proves the returned account and storage
+// values without having to trust the node.
+package proof
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyCodeHash is the code hash of accounts without code.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+var (
+	// ErrInvalidProof is returned if a proof is malformed or doesn't resolve against
+	// the root it is verified against.
+	ErrInvalidProof = errors.New("invalid proof")
+
+	// ErrValueMismatch is returned if a proof is valid, but the value it proves
+	// differs from the one the node claimed alongside it.
+	ErrValueMismatch = errors.New("proven value mismatch")
+)
+
+// Account is an account whose state was verified against a state root.
+type Account struct {
+	Address     common.Address
+	Exists      bool // Whether the account is present in the state trie
+	Nonce       uint64
+	Balance     *big.Int
+	CodeHash    common.Hash
+	StorageRoot common.Hash
+	Storage     []Slot // Verified storage slots, in the order they were requested
+}
+
+// Slot is a storage slot whose value was verified against a storage root.
+type Slot struct {
+	Key   common.Hash
+	Value common.Hash // Zero if the slot is not present in the storage trie
+}
+
+// Verify checks the result of an eth_getProof call for the given address against
+// the state root of the block it was requested for. The account proof is verified
+// against the state root and each storage proof against the proven storage root of
+// the account. Proofs of absence are accepted: an account which doesn't exist has
+// all fields empty and all its slots are zero.
+//
+// Besides checking the proofs, Verify also ensures that every value the node claimed
+// in the result matches the proven one, so the result can't be tampered with outside
+// of the proof nodes either.
+func Verify(root common.Hash, address common.Address, result *gethclient.AccountResult) (*Account, error) {
+	if result.Address != address {
+		return nil, fmt.Errorf("%w: address %v, requested %v", ErrValueMismatch, result.Address, address)
+	}
+	nodes, err := decodeProof(result.AccountProof)
+	if err != nil {
+		return nil, fmt.Errorf("%w: account %v: %v", ErrInvalidProof, address, err)
+	}
+	state, err := VerifyAccountProof(root, address, nodes)
+	if err != nil {
+		return nil, err
+	}
+	account := &Account{
+		Address:     address,
+		Exists:      state != nil,
+		Balance:     new(big.Int),
+		CodeHash:    emptyCodeHash,
+		StorageRoot: types.EmptyRootHash,
+	}
+	if state != nil {
+		account.Nonce = state.Nonce
+		account.Balance = state.Balance
+		account.CodeHash = common.BytesToHash(state.CodeHash)
+		account.StorageRoot = state.Root
+	}
+	// Make sure the node didn't claim anything other than what it proved
+	switch {
+	case result.Nonce != account.Nonce:
+		return nil, fmt.Errorf("%w: account %v nonce %d, proven %d", ErrValueMismatch, address, result.Nonce, account.Nonce)
+	case result.Balance == nil || result.Balance.Cmp(account.Balance) != 0:
+		return nil, fmt.Errorf("%w: account %v balance %v, proven %v", ErrValueMismatch, address, result.Balance, account.Balance)
+	case result.CodeHash != account.CodeHash:
+		return nil, fmt.Errorf("%w: account %v code hash %v, proven %v", ErrValueMismatch, address, result.CodeHash, account.CodeHash)
+	case result.StorageHash != account.StorageRoot:
+		return nil, fmt.Errorf("%w: account %v storage hash %v, proven %v", ErrValueMismatch, address, result.StorageHash, account.StorageRoot)
+	}
+	// Verify all the storage slots against the proven storage root
+	account.Storage = make([]Slot, 0, len(result.StorageProof))
+	for _, claim := range result.StorageProof {
+		key := common.HexToHash(claim.Key)
+
+		nodes, err := decodeProof(claim.Proof)
+		if err != nil {
+			return nil, fmt.Errorf("%w: account %v slot %v: %v", ErrInvalidProof, address, key, err)
+		}
+		value, err := VerifyStorageProof(account.StorageRoot, key, nodes)
+		if err != nil {
+			return nil, fmt.Errorf("account %v: %w", address, err)
+		}
+		if claim.Value == nil || claim.Value.Cmp(value.Big()) != 0 {
+			return nil, fmt.Errorf("%w: account %v slot %v value %v, proven %v", ErrValueMismatch, address, key, claim.Value, value.Big())
+		}
+		account.Storage = append(account.Storage, Slot{Key: key, Value: value})
+	}
+	return account, nil
+}
+
+// VerifyAccountProof verifies the proof of an account against a state root. The
+// returned account is nil if the proof shows that the account doesn't exist.
+func VerifyAccountProof(root common.Hash, address common.Address, proof [][]byte) (*types.StateAccount, error) {
+	blob, err := verify(root, crypto.Keccak256(address.Bytes()), proof)
+	if err != nil {
+		return nil, fmt.Errorf("%w: account %v: %v", ErrInvalidProof, address, err)
+	}
+	if blob == nil {
+		return nil, nil
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, fmt.Errorf("%w: account %v: %v", ErrInvalidProof, address, err)
+	}
+	return account, nil
+}
+
+// VerifyStorageProof verifies the proof of a storage slot against the storage root
+// of an account. Slots which the proof shows to be absent have the zero value.
+func VerifyStorageProof(root common.Hash, key common.Hash, proof [][]byte) (common.Hash, error) {
+	blob, err := verify(root, crypto.Keccak256(key.Bytes()), proof)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: slot %v: %v", ErrInvalidProof, key, err)
+	}
+	if blob == nil {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: slot %v: %v", ErrInvalidProof, key, err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// verify resolves the value of a trie key from a proof against the trie root. An
+// empty trie holds no values, so nothing needs to be proven for it; this is also
+// what nodes return for the storage slots of accounts without storage.
+func verify(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	if root == types.EmptyRootHash {
+		return nil, nil
+	}
+	db := memorydb.New()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return trie.VerifyProof(root, key, db)
+}
+
+// decodeProof converts the hex encoded proof nodes of an eth_getProof result into
+// their binary form.
+func decodeProof(proof []string) ([][]byte, error) {
+	nodes := make([][]byte, len(proof))
+	for i, node := range proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("proof node %d: %v", i, err)
+		}
+		nodes[i] = blob
+	}
+	return nodes, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package proof
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+var (
+	contractAddr = common.HexToAddress("0xc0de")
+	eoaAddr      = common.HexToAddress("0xeeee")
+	missingAddr  = common.HexToAddress("0xdead")
+
+	slotA   = common.HexToHash("0x01")
+	slotB   = common.HexToHash("0x02")
+	slotNil = common.HexToHash("0x03")
+)
+
+// newTestState creates a committed state with a contract holding some storage
+// and a plain account without any.
+func newTestState(t *testing.T) (*state.StateDB, common.Hash) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db, nil)
+
+	statedb.SetNonce(contractAddr, 1)
+	statedb.SetBalance(contractAddr, big.NewInt(1000))
+	statedb.SetCode(contractAddr, []byte{0x60, 0x00})
+	statedb.SetState(contractAddr, slotA, common.HexToHash("0xaa"))
+	statedb.SetState(contractAddr, slotB, common.HexToHash("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"))
+
+	statedb.SetNonce(eoaAddr, 7)
+	statedb.SetBalance(eoaAddr, big.NewInt(1e18))
+
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, err = state.New(root, db, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen state: %v", err)
+	}
+	return statedb, root
+}
+
+// getProof assembles the proof of an account the same way eth_getProof does.
+func getProof(t *testing.T, statedb *state.StateDB, address common.Address, keys ...common.Hash) *gethclient.AccountResult {
+	accountProof, err := statedb.GetProof(address)
+	if err != nil {
+		t.Fatalf("failed to prove account %v: %v", address, err)
+	}
+	result := &gethclient.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      statedb.GetBalance(address),
+		CodeHash:     statedb.GetCodeHash(address),
+		Nonce:        statedb.GetNonce(address),
+		StorageHash:  types.EmptyRootHash,
+	}
+	storageTrie := statedb.StorageTrie(address)
+	if storageTrie != nil {
+		result.StorageHash = storageTrie.Hash()
+	} else {
+		result.CodeHash = emptyCodeHash
+	}
+	for _, key := range keys {
+		claim := gethclient.StorageResult{Key: key.Hex(), Value: new(big.Int), Proof: []string{}}
+		if storageTrie != nil {
+			proof, err := statedb.GetStorageProof(address, key)
+			if err != nil {
+				t.Fatalf("failed to prove slot %v: %v", key, err)
+			}
+			claim.Value = statedb.GetState(address, key).Big()
+			claim.Proof = toHexSlice(proof)
+		}
+		result.StorageProof = append(result.StorageProof, claim)
+	}
+	return result
+}
+
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	statedb, root := newTestState(t)
+
+	tests := []struct {
+		address common.Address
+		exists  bool
+		nonce   uint64
+		balance *big.Int
+		storage []Slot
+	}{
+		// Contract with existing and missing slots
+		{
+			address: contractAddr, exists: true, nonce: 1, balance: big.NewInt(1000),
+			storage: []Slot{
+				{slotA, common.HexToHash("0xaa")},
+				{slotB, common.HexToHash("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")},
+				{slotNil, common.Hash{}},
+			},
+		},
+		// Account without storage, slots are proven against the empty root
+		{
+			address: eoaAddr, exists: true, nonce: 7, balance: big.NewInt(1e18),
+			storage: []Slot{{slotA, common.Hash{}}},
+		},
+		// Non-existent account, proven absent from the state trie
+		{
+			address: missingAddr, exists: false, balance: new(big.Int),
+			storage: []Slot{{slotA, common.Hash{}}, {slotNil, common.Hash{}}},
+		},
+	}
+	for i, tt := range tests {
+		keys := make([]common.Hash, len(tt.storage))
+		for j, slot := range tt.storage {
+			keys[j] = slot.Key
+		}
+		account, err := Verify(root, tt.address, getProof(t, statedb, tt.address, keys...))
+		if err != nil {
+			t.Fatalf("test %d: failed to verify proof: %v", i, err)
+		}
+		if account.Exists != tt.exists {
+			t.Errorf("test %d: existence mismatch: have %v, want %v", i, account.Exists, tt.exists)
+		}
+		if account.Nonce != tt.nonce {
+			t.Errorf("test %d: nonce mismatch: have %d, want %d", i, account.Nonce, tt.nonce)
+		}
+		if account.Balance.Cmp(tt.balance) != 0 {
+			t.Errorf("test %d: balance mismatch: have %v, want %v", i, account.Balance, tt.balance)
+		}
+		if len(account.Storage) != len(tt.storage) {
+			t.Fatalf("test %d: slot count mismatch: have %d, want %d", i, len(account.Storage), len(tt.storage))
+		}
+		for j, slot := range tt.storage {
+			if account.Storage[j] != slot {
+				t.Errorf("test %d, slot %d: value mismatch: have %v, want %v", i, j, account.Storage[j], slot)
+			}
+		}
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	statedb, root := newTestState(t)
+
+	tests := []struct {
+		name   string
+		tamper func(res *gethclient.AccountResult)
+		root   common.Hash
+		want   error
+	}{
+		{
+			name:   "address",
+			tamper: func(res *gethclient.AccountResult) { res.Address = eoaAddr },
+			want:   ErrValueMismatch,
+		},
+		{
+			name:   "balance",
+			tamper: func(res *gethclient.AccountResult) { res.Balance = big.NewInt(1001) },
+			want:   ErrValueMismatch,
+		},
+		{
+			name:   "storage hash",
+			tamper: func(res *gethclient.AccountResult) { res.StorageHash = types.EmptyRootHash },
+			want:   ErrValueMismatch,
+		},
+		{
+			name:   "slot value",
+			tamper: func(res *gethclient.AccountResult) { res.StorageProof[0].Value = big.NewInt(0xab) },
+			want:   ErrValueMismatch,
+		},
+		{
+			name:   "missing slot value",
+			tamper: func(res *gethclient.AccountResult) { res.StorageProof[1].Value = big.NewInt(1) },
+			want:   ErrValueMismatch,
+		},
+		{
+			name:   "account proof node",
+			tamper: func(res *gethclient.AccountResult) { res.AccountProof = res.AccountProof[:len(res.AccountProof)-1] },
+			want:   ErrInvalidProof,
+		},
+		{
+			name:   "storage proof node",
+			tamper: func(res *gethclient.AccountResult) { res.StorageProof[0].Proof = []string{} },
+			want:   ErrInvalidProof,
+		},
+		{
+			name:   "malformed proof node",
+			tamper: func(res *gethclient.AccountResult) { res.AccountProof[0] = "0xzz" },
+			want:   ErrInvalidProof,
+		},
+		{
+			name:   "state root",
+			tamper: func(res *gethclient.AccountResult) {},
+			root:   common.HexToHash("0x01"),
+			want:   ErrInvalidProof,
+		},
+	}
+	for _, tt := range tests {
+		res := getProof(t, statedb, contractAddr, slotA, slotNil)
+		tt.tamper(res)
+
+		verifyRoot := root
+		if tt.root != (common.Hash{}) {
+			verifyRoot = tt.root
+		}
+		if _, err := Verify(verifyRoot, contractAddr, res); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyAbsentAccountClaims(t *testing.T) {
+	statedb, root := newTestState(t)
+
+	// A node must not be able to claim funds for an account proven absent
+	res := getProof(t, statedb, missingAddr, slotA)
+	res.Balance = big.NewInt(1)
+	if _, err := Verify(root, missingAddr, res); !errors.Is(err, ErrValueMismatch) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrValueMismatch)
+	}
+	// Nor any storage for it
+	res = getProof(t, statedb, missingAddr, slotA)
+	res.StorageProof[0].Value = big.NewInt(1)
+	if _, err := Verify(root, missingAddr, res); !errors.Is(err, ErrValueMismatch) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrValueMismatch)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// When the underlying RPC client is in reconnecting mode (see rpc.Client.EnableReconnect),
+// head and log subscriptions backfill the notifications missed while the connection
+// was down by block number.
+
+const (
+	// maxBackfillBlocks is the largest range of blocks backfilled after a
+	// subscription was re-established.
+	maxBackfillBlocks = 256
+
+	// Number of delivered notifications remembered to drop duplicates.
+	recentHeadsLimit = 128
+	recentLogsLimit  = 4096
+)
+
+// GapError is the error of a head or log subscription which missed notifications
+// while reconnecting and could not fill the gap.
+type GapError struct {
+	From, To uint64 // range of blocks whose notifications are missing, To is zero if unknown
+	Err      error  // why the range could not be backfilled, nil if it was too large
+}
+
+func (e *GapError) Error() string {
+	msg := fmt.Sprintf("missed notifications for blocks %d-%d", e.From, e.To)
+	if e.To == 0 {
+		msg = fmt.Sprintf("missed notifications from block %d", e.From)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *GapError) Unwrap() error {
+	return e.Err
+}
+
+// recentKeys remembers the most recently added keys.
+type recentKeys struct {
+	limit int
+	keys  map[string]struct{}
+	order []string
+}
+
+func newRecentKeys(limit int) *recentKeys {
+	return &recentKeys{limit: limit, keys: make(map[string]struct{}, limit)}
+}
+
+// add adds a key, returning false if it is already present.
+func (r *recentKeys) add(key string) bool {
+	if _, ok := r.keys[key]; ok {
+		return false
+	}
+	if len(r.order) == r.limit {
+		delete(r.keys, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.keys[key] = struct{}{}
+	r.order = append(r.order, key)
+	return true
+}
+
+// headResumer backfills newHeads subscriptions.
+type headResumer struct {
+	ec    *Client
+	limit uint64 // maximum number of backfilled blocks
+
+	mu     sync.Mutex
+	seen   bool   // whether a head was delivered
+	last   uint64 // number of the last delivered head
+	recent *recentKeys
+}
+
+func newHeadResumer(ec *Client) *headResumer {
+	return &headResumer{ec: ec, limit: maxBackfillBlocks, recent: newRecentKeys(recentHeadsLimit)}
+}
+
+// Accept implements rpc.Resumer.
+func (r *headResumer) Accept(msg json.RawMessage) bool {
+	var head struct {
+		Number *hexutil.Big `json:"number"`
+		Hash   common.Hash  `json:"hash"`
+	}
+	if err := json.Unmarshal(msg, &head); err != nil || head.Number == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recent.add(string(head.Hash[:])) {
+		return false
+	}
+	r.seen, r.last = true, head.Number.ToInt().Uint64()
+	return true
+}
+
+// Resume implements rpc.Resumer. It fetches the headers after the last delivered one.
+func (r *headResumer) Resume(ctx context.Context) ([]json.RawMessage, error) {
+	r.mu.Lock()
+	seen, from := r.seen, r.last+1
+	r.mu.Unlock()
+	if !seen {
+		return nil, nil
+	}
+	to, err := r.ec.BlockNumber(ctx)
+	if err != nil {
+		return nil, &GapError{From: from, Err: err}
+	}
+	if to < from {
+		return nil, nil
+	}
+	if to-from+1 > r.limit {
+		return nil, &GapError{From: from, To: to}
+	}
+	var (
+		headers = make([]json.RawMessage, to-from+1)
+		batch   = make([]rpc.BatchElem, len(headers))
+	)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(from + uint64(i)), false},
+			Result: &headers[i],
+		}
+	}
+	if err := r.ec.c.BatchCallContext(ctx, batch); err != nil {
+		return nil, &GapError{From: from, To: to, Err: err}
+	}
+	for i, elem := range batch {
+		if elem.Error == nil && string(headers[i]) == "null" {
+			elem.Error = ethereum.NotFound
+		}
+		if elem.Error != nil {
+			return nil, &GapError{From: from, To: to, Err: elem.Error}
+		}
+	}
+	return headers, nil
+}
+
+// logResumer backfills logs subscriptions.
+type logResumer struct {
+	ec    *Client
+	query ethereum.FilterQuery
+	limit uint64 // maximum number of backfilled blocks
+
+	mu      sync.Mutex
+	known   bool   // whether covered is set
+	covered uint64 // highest block whose logs were all delivered
+	recent  *recentKeys
+}
+
+func newLogResumer(ec *Client, q ethereum.FilterQuery) *logResumer {
+	// Subscriptions can't be limited to a block range, only the filter criteria
+	// are used for backfilling.
+	q = ethereum.FilterQuery{Addresses: q.Addresses, Topics: q.Topics}
+	return &logResumer{ec: ec, query: q, limit: maxBackfillBlocks, recent: newRecentKeys(recentLogsLimit)}
+}
+
+// start records the chain head at the time of subscribing.
+func (r *logResumer) start(ctx context.Context) error {
+	head, err := r.ec.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	r.setCovered(head)
+	return nil
+}
+
+func (r *logResumer) setCovered(number uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.known || number > r.covered {
+		r.known, r.covered = true, number
+	}
+}
+
+// Accept implements rpc.Resumer.
+func (r *logResumer) Accept(msg json.RawMessage) bool {
+	var log struct {
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		BlockHash   common.Hash    `json:"blockHash"`
+		Index       hexutil.Uint   `json:"logIndex"`
+		Removed     bool           `json:"removed"`
+	}
+	if err := json.Unmarshal(msg, &log); err != nil {
+		return true
+	}
+	key := fmt.Sprintf("%x-%d-%t", log.BlockHash, log.Index, log.Removed)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recent.add(key) {
+		return false
+	}
+	// Other logs of the block may still follow, so the block is not covered yet.
+	if !log.Removed && log.BlockNumber > 0 && (!r.known || uint64(log.BlockNumber)-1 > r.covered) {
+		r.known, r.covered = true, uint64(log.BlockNumber)-1
+	}
+	return true
+}
+
+// Resume implements rpc.Resumer. It fetches the logs of the blocks after the last
+// covered one.
+func (r *logResumer) Resume(ctx context.Context) ([]json.RawMessage, error) {
+	r.mu.Lock()
+	known, from := r.known, r.covered+1
+	r.mu.Unlock()
+	if !known {
+		return nil, errors.New("can't backfill logs, subscription start unknown")
+	}
+	to, err := r.ec.BlockNumber(ctx)
+	if err != nil {
+		return nil, &GapError{From: from, Err: err}
+	}
+	if to < from {
+		return nil, nil
+	}
+	if to-from+1 > r.limit {
+		return nil, &GapError{From: from, To: to}
+	}
+	q := r.query
+	q.FromBlock, q.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	var logs []json.RawMessage
+	if err := r.ec.c.CallContext(ctx, &logs, "eth_getLogs", arg); err != nil {
+		return nil, &GapError{From: from, To: to, Err: err}
+	}
+	r.setCovered(to)
+	return logs, nil
+}
@@ -19,10 +19,13 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -32,6 +35,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -74,6 +79,27 @@ func (b *Long) UnmarshalGraphQL(input interface{}) error {
 	return err
 }
 
+// JSON is an arbitrary JSON value.
+type JSON json.RawMessage
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	enc, err := json.Marshal(input)
+	*j = enc
+	return err
+}
+
+// MarshalJSON returns the JSON value.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend       ethapi.Backend
@@ -83,6 +109,9 @@ type Account struct {
 
 // getState fetches the StateDB object for an account.
 func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	if err := charge(ctx, costRead); err != nil {
+		return nil, err
+	}
 	state, _, err := a.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
 	return state, err
 }
@@ -127,12 +156,19 @@ func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
 	return state.GetCode(a.address), nil
 }
 
-func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+func (a *Account) Storage(ctx context.Context, args struct{ Slots []common.Hash }) ([]common.Hash, error) {
+	if err := charge(ctx, int64(len(args.Slots))*costRead); err != nil {
+		return nil, err
+	}
 	state, err := a.getState(ctx)
 	if err != nil {
-		return common.Hash{}, err
+		return nil, err
+	}
+	values := make([]common.Hash, len(args.Slots))
+	for i, slot := range args.Slots {
+		values[i] = state.GetState(a.address, slot)
 	}
-	return state.GetState(a.address, args.Slot), nil
+	return values, nil
 }
 
 // Log represents an individual log message. All arguments are mandatory.
@@ -193,6 +229,9 @@ type Transaction struct {
 // resolve returns the internal transaction object, fetching it if needed.
 func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
 	if t.tx == nil {
+		if err := charge(ctx, costRead); err != nil {
+			return nil, err
+		}
 		// Try to return an already finalized transaction
 		tx, blockHash, _, index, err := t.backend.GetTransaction(ctx, t.hash)
 		if err == nil && tx != nil {
@@ -533,6 +572,83 @@ func (t *Transaction) RawReceipt(ctx context.Context) (hexutil.Bytes, error) {
 	return receipt.MarshalBinary()
 }
 
+// TraceConfig holds the options of `trace` on a transaction.
+type TraceConfig struct {
+	EnableMemory     *bool
+	DisableStack     *bool
+	DisableStorage   *bool
+	EnableReturnData *bool
+	Limit            *int32
+	Timeout          *string
+	Reexec           *Long
+}
+
+func (t *Transaction) Trace(ctx context.Context, args struct {
+	Tracer *string
+	Config *TraceConfig
+}) (*JSON, error) {
+	if !tracingEnabled(ctx) {
+		return nil, errTracingDisabled
+	}
+	backend, ok := t.backend.(tracers.Backend)
+	if !ok {
+		return nil, errors.New("transaction tracing is not supported")
+	}
+	// Transactions which are not yet mined can't be traced
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return nil, err
+	}
+	if err := charge(ctx, costExecution); err != nil {
+		return nil, err
+	}
+	// The timeout and the state regeneration are capped by the server
+	var (
+		timeout = maxTraceTimeout.String()
+		reexec  = uint64(maxTraceReexec)
+	)
+	config := &tracers.TraceConfig{Config: new(logger.Config), Tracer: args.Tracer, Timeout: &timeout, Reexec: &reexec}
+	if c := args.Config; c != nil {
+		if c.EnableMemory != nil {
+			config.EnableMemory = *c.EnableMemory
+		}
+		if c.DisableStack != nil {
+			config.DisableStack = *c.DisableStack
+		}
+		if c.DisableStorage != nil {
+			config.DisableStorage = *c.DisableStorage
+		}
+		if c.EnableReturnData != nil {
+			config.EnableReturnData = *c.EnableReturnData
+		}
+		if c.Limit != nil {
+			config.Limit = int(*c.Limit)
+		}
+		if c.Reexec != nil && uint64(*c.Reexec) < reexec {
+			reexec = uint64(*c.Reexec)
+		}
+		if c.Timeout != nil {
+			d, err := time.ParseDuration(*c.Timeout)
+			if err != nil {
+				return nil, err
+			}
+			if d < maxTraceTimeout {
+				timeout = *c.Timeout
+			}
+		}
+	}
+	result, err := tracers.NewAPI(backend).TraceTransaction(ctx, t.hash, config)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	trace := JSON(enc)
+	return &trace, nil
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -557,6 +673,9 @@ func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
 		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 		b.numberOrHash = &latest
 	}
+	if err := charge(ctx, costRead); err != nil {
+		return nil, err
+	}
 	var err error
 	b.block, err = b.backend.BlockByNumberOrHash(ctx, *b.numberOrHash)
 	if b.block != nil && b.header == nil {
@@ -577,6 +696,9 @@ func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
 	}
 	var err error
 	if b.header == nil {
+		if err := charge(ctx, costRead); err != nil {
+			return nil, err
+		}
 		if b.hash != (common.Hash{}) {
 			b.header, err = b.backend.HeaderByHash(ctx, b.hash)
 		} else {
@@ -598,6 +720,9 @@ func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
 			}
 			hash = header.Hash()
 		}
+		if err := charge(ctx, costRead); err != nil {
+			return nil, err
+		}
 		receipts, err := b.backend.GetReceipts(ctx, hash)
 		if err != nil {
 			return nil, err
@@ -1041,6 +1166,9 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
+	if err := charge(ctx, costExecution); err != nil {
+		return nil, err
+	}
 	result, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, b.backend.RPCEVMTimeout(), b.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
@@ -1066,6 +1194,9 @@ func (b *Block) EstimateGas(ctx context.Context, args struct {
 			return 0, err
 		}
 	}
+	if err := charge(ctx, costExecution); err != nil {
+		return 0, err
+	}
 	gas, err := ethapi.DoEstimateGas(ctx, b.backend, args.Data, *b.numberOrHash, b.backend.RPCGasCap())
 	return Long(gas), err
 }
@@ -1111,6 +1242,9 @@ func (p *Pending) Call(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (*CallResult, error) {
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if err := charge(ctx, costExecution); err != nil {
+		return nil, err
+	}
 	result, err := ethapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, p.backend.RPCEVMTimeout(), p.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
@@ -1131,6 +1265,9 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (Long, error) {
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if err := charge(ctx, costExecution); err != nil {
+		return 0, err
+	}
 	gas, err := ethapi.DoEstimateGas(ctx, p.backend, args.Data, pendingBlockNr, p.backend.RPCGasCap())
 	return Long(gas), err
 }
@@ -1138,6 +1275,9 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+
+	eventsOnce sync.Once
+	events     *filters.EventSystem // created by the first subscription
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
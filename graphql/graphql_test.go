@@ -17,6 +17,9 @@
 package graphql
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -26,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -33,9 +37,11 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +56,7 @@ func TestBuildSchema(t *testing.T) {
 	}
 	defer stack.Close()
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, []string{}, []string{}, false); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -195,6 +201,227 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 	}
 }
 
+func TestGraphQLTransactionTrace(t *testing.T) {
+	stack := createNode(t, true, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	// The struct logger is used by default.
+	var structLogs struct {
+		Transaction struct {
+			Trace struct {
+				Gas        uint64
+				Failed     bool
+				StructLogs []struct {
+					Op    string
+					Stack []string
+				}
+			}
+		}
+	}
+	query := `{ transaction(hash: "0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b") { trace(config: {disableStack: true}) } }`
+	postGraphQLQuery(t, stack, query, &structLogs)
+	trace := structLogs.Transaction.Trace
+	if trace.Failed || trace.Gas == 0 {
+		t.Errorf("wrong trace result: %+v", trace)
+	}
+	var ops []string
+	for _, log := range trace.StructLogs {
+		ops = append(ops, log.Op)
+		if len(log.Stack) != 0 {
+			t.Errorf("stack captured despite disableStack")
+		}
+	}
+	if want := []string{"PC", "PC", "SLOAD", "SLOAD", "STOP"}; strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("wrong traced opcodes %v, want %v", ops, want)
+	}
+
+	// Native tracers are available by name.
+	var callTrace struct {
+		Transaction struct {
+			Trace struct {
+				Type string
+				To   common.Address
+			}
+		}
+	}
+	query = `{ transaction(hash: "0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b") { trace(tracer: "callTracer") } }`
+	postGraphQLQuery(t, stack, query, &callTrace)
+	if trace := callTrace.Transaction.Trace; trace.Type != "CALL" || trace.To != common.HexToAddress("0xdad") {
+		t.Errorf("wrong call trace: %+v", trace)
+	}
+
+	// Client supplied limits above the ones of the server are capped.
+	query = `{ transaction(hash: "0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b") { trace(tracer: "callTracer", config: {timeout: "1h", reexec: 1000000}) } }`
+	postGraphQLQuery(t, stack, query, &callTrace)
+	if trace := callTrace.Transaction.Trace; trace.Type != "CALL" {
+		t.Errorf("wrong call trace with capped limits: %+v", trace)
+	}
+
+	// Pending transactions have no trace.
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dad := common.HexToAddress("0x0000000000000000000000000000000000000dad")
+	pending, _ := types.SignNewTx(key, types.LatestSigner(params.AllEthashProtocolChanges), &types.LegacyTx{
+		Nonce:    2,
+		To:       &dad,
+		Gas:      21000,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	raw, _ := pending.MarshalBinary()
+	var sent struct{ SendRawTransaction common.Hash }
+	postGraphQLQuery(t, stack, fmt.Sprintf(`mutation { sendRawTransaction(data: "%s") }`, hexutil.Encode(raw)), &sent)
+
+	var pendingTrace struct {
+		Transaction struct {
+			Hash  common.Hash
+			Trace *json.RawMessage
+		}
+	}
+	postGraphQLQuery(t, stack, fmt.Sprintf(`{ transaction(hash: "%s") { hash trace } }`, sent.SendRawTransaction.Hex()), &pendingTrace)
+	if pendingTrace.Transaction.Hash != pending.Hash() || pendingTrace.Transaction.Trace != nil {
+		t.Errorf("wrong pending transaction trace: %+v", pendingTrace)
+	}
+
+	// Tracing is only available if enabled.
+	tx := &Transaction{hash: pending.Hash()}
+	if _, err := tx.Trace(context.Background(), struct {
+		Tracer *string
+		Config *TraceConfig
+	}{}); err != errTracingDisabled {
+		t.Errorf("wrong error with tracing disabled: have %v, want %v", err, errTracingDisabled)
+	}
+
+	// Storage slots can be read in one go.
+	var storage struct {
+		Block struct {
+			Account struct {
+				Storage []common.Hash
+			}
+		}
+	}
+	query = `{ block { account(address: "0x0000000000000000000000000000000000000dad") { storage(slots: ["0x0000000000000000000000000000000000000000000000000000000000000000", "0x0000000000000000000000000000000000000000000000000000000000000001"]) } } }`
+	postGraphQLQuery(t, stack, query, &storage)
+	if slots := storage.Block.Account.Storage; len(slots) != 2 || slots[0] != (common.Hash{}) || slots[1] != (common.Hash{}) {
+		t.Errorf("wrong storage slots %v", slots)
+	}
+}
+
+func TestGraphQLQueryLimits(t *testing.T) {
+	stack := createNode(t, true, false)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	// Every aliased field loads a header and a state.
+	expensive := "{"
+	for i := 0; i <= maxQueryCost/2; i++ {
+		expensive += fmt.Sprintf(" b%d: block(number: 0) { miner { balance } }", i)
+	}
+	expensive += " }"
+
+	for i, tt := range []struct {
+		query string
+		want  string
+	}{
+		{
+			query: expensive,
+			want:  errQueryTooExpensive.Error(),
+		},
+		{
+			query: `{ blocks(from: 0) { miner { balance } } }`,
+		},
+		{
+			query: "{ block { " + strings.Repeat("parent { ", maxQueryDepth) + "number" + strings.Repeat(" }", maxQueryDepth) + " } }",
+			want:  "exceeds max depth",
+		},
+	} {
+		body, _ := json.Marshal(map[string]string{"query": tt.query})
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		result, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if tt.want == "" && resp.StatusCode != http.StatusOK {
+			t.Errorf("testcase %d: unexpected error: %s", i, result)
+		}
+		if tt.want != "" && !strings.Contains(string(result), tt.want) {
+			t.Errorf("testcase %d: expected error %q, got %s", i, tt.want, result)
+		}
+	}
+}
+
+// This test checks that subscriptions are charged per notification.
+func TestQueryBudgetPerNotification(t *testing.T) {
+	ctx, cancel := context.WithCancel(withQueryBudget(context.Background(), 10))
+	defer cancel()
+
+	// Notifications are resolved with contexts of their own.
+	for i := 0; i < 3; i++ {
+		notifyCtx, cancel := context.WithTimeout(ctx, time.Second)
+		if err := charge(notifyCtx, 10); err != nil {
+			t.Fatalf("notification %d: unexpected error: %v", i, err)
+		}
+		if err := charge(notifyCtx, 1); err != errQueryTooExpensive {
+			t.Fatalf("notification %d: wrong error: %v", i, err)
+		}
+		cancel()
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	for i, tt := range []struct {
+		doc, name, want string
+	}{
+		{doc: `{ block { number } }`, want: "query"},
+		{doc: `query { block { number } }`, want: "query"},
+		{doc: `mutation Send($data: Bytes!) { sendRawTransaction(data: $data) }`, want: "mutation"},
+		{doc: `subscription { newBlock { number } }`, want: "subscription"},
+		{doc: `subscription Blocks @skip(if: false) { newBlock { ...fields } } fragment fields on Block { number }`, want: "subscription"},
+		{doc: "# subscription\n{ block { number } }", want: "query"},
+		{doc: `query Q($x: String = "subscription {") { block { number } }`, want: "query"},
+		{doc: `query Q { block { number } } subscription S { newBlock { number } }`, name: "S", want: "subscription"},
+		{doc: `query Q { block { number } } subscription S { newBlock { number } }`, name: "Q", want: "query"},
+		// Ambiguous and invalid documents are left to the query schema.
+		{doc: `query Q { block { number } } subscription S { newBlock { number } }`, want: "query"},
+		{doc: `subscription { newBlock { number } }`, name: "X", want: "query"},
+		{doc: `subscription {`, want: "query"},
+	} {
+		if have := operationType(tt.doc, tt.name); have != tt.want {
+			t.Errorf("testcase %d: wrong operation type %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+// postGraphQLQuery sends a query and decodes the data of the response into result.
+func postGraphQLQuery(t *testing.T, stack *node.Node, query string, result interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not post: %v", err)
+	}
+	defer resp.Body.Close()
+	var response struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if len(response.Errors) > 0 {
+		t.Fatalf("query failed: %v", response.Errors)
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		t.Fatalf("could not decode result: %v", err)
+	}
+}
+
 // Tests that a graphQL request is not handled successfully when graphql is not enabled on the specified endpoint
 func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	stack := createNode(t, false, false)
@@ -211,6 +438,246 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
+func TestGraphQLSubscriptions(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		// The emitter contract logs topic 0x01 without data.
+		emitter = common.HexToAddress("0xbeef")
+		code    = []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.LOG1)}
+	)
+	stack := createNode(t, false, false)
+	defer stack.Close()
+	ethConf := &ethconfig.Config{
+		Genesis: &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: code, Balance: big.NewInt(0)},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		},
+		Ethash: ethash.Config{
+			PowMode: ethash.ModeFake,
+		},
+		NetworkId:      1337,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+	}
+	ethBackend, err := eth.New(stack, ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}, true); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	conn := dialGraphQLWebsocket(t, stack, "graphql-transport-ws")
+	defer conn.Close()
+	writeWSMessage(t, conn, `{"type":"connection_init"}`)
+	if msg := readWSMessage(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %+v", msg)
+	}
+	writeWSMessage(t, conn, `{"id":"blocks","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`)
+	writeWSMessage(t, conn, `{"id":"logs","type":"subscribe","payload":{"query":"subscription Logs($addr: Address!) { logs(filter: {addresses: [$addr]}) { index topics transaction { hash } } }","variables":{"addr":"0x000000000000000000000000000000000000beef"}}}`)
+	writeWSMessage(t, conn, `{"id":"other","type":"subscribe","payload":{"query":"subscription { logs(filter: {addresses: [\"0x0000000000000000000000000000000000000dad\"]}) { index } }"}}`)
+	writeWSMessage(t, conn, `{"id":"txs","type":"subscribe","payload":{"query":"subscription { pendingTransactions { hash } }"}}`)
+	// Subscriptions are set up in order, so they are all active once the ping is answered.
+	writeWSMessage(t, conn, `{"type":"ping"}`)
+	if msg := readWSMessage(t, conn); msg.Type != "pong" {
+		t.Fatalf("expected pong, got %+v", msg)
+	}
+
+	signer := types.LatestSigner(ethConf.Genesis.Config)
+	tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &emitter,
+		Gas:      50000,
+		GasPrice: big.NewInt(2 * params.InitialBaseFee),
+	})
+	if err := ethBackend.TxPool().AddLocal(tx); err != nil {
+		t.Fatalf("could not add transaction: %v", err)
+	}
+	want := fmt.Sprintf(`{"data":{"pendingTransactions":{"hash":"%s"}}}`, tx.Hash().Hex())
+	if msg := readWSMessage(t, conn); msg.ID != "txs" || msg.Type != "next" || string(msg.Payload) != want {
+		t.Fatalf("wrong pending transaction notification %s %s %s", msg.ID, msg.Type, msg.Payload)
+	}
+
+	chain, _ := core.GenerateChain(ethConf.Genesis.Config, ethBackend.BlockChain().Genesis(),
+		ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, b *core.BlockGen) {
+			b.AddTx(tx)
+		})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	wants := map[string]string{
+		"blocks": `{"data":{"newBlock":{"number":1}}}`,
+		"logs":   fmt.Sprintf(`{"data":{"logs":{"index":0,"topics":["0x0000000000000000000000000000000000000000000000000000000000000001"],"transaction":{"hash":"%s"}}}}`, tx.Hash().Hex()),
+	}
+	for len(wants) > 0 {
+		msg := readWSMessage(t, conn)
+		want, ok := wants[msg.ID]
+		if !ok || msg.Type != "next" || string(msg.Payload) != want {
+			t.Fatalf("unexpected message %s %s %s", msg.ID, msg.Type, msg.Payload)
+		}
+		delete(wants, msg.ID)
+	}
+
+	// Stopped subscriptions are not completed by the server.
+	writeWSMessage(t, conn, `{"id":"blocks","type":"complete"}`)
+	writeWSMessage(t, conn, `{"type":"ping"}`)
+	if msg := readWSMessage(t, conn); msg.Type != "pong" {
+		t.Fatalf("expected pong, got %+v", msg)
+	}
+}
+
+func TestGraphQLWebsocketProtocol(t *testing.T) {
+	stack := createNode(t, true, false)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	// Queries work over the legacy protocol as well.
+	conn := dialGraphQLWebsocket(t, stack, "graphql-ws")
+	defer conn.Close()
+	writeWSMessage(t, conn, `{"type":"connection_init","payload":{}}`)
+	for _, want := range []string{"connection_ack", "ka"} {
+		if msg := readWSMessage(t, conn); msg.Type != want {
+			t.Fatalf("expected %s, got %+v", want, msg)
+		}
+	}
+	writeWSMessage(t, conn, `{"id":"1","type":"start","payload":{"query":"{ block { number } }"}}`)
+	if msg := readWSMessage(t, conn); msg.ID != "1" || msg.Type != "data" || string(msg.Payload) != `{"data":{"block":{"number":10}}}` {
+		t.Fatalf("wrong result %s %s %s", msg.ID, msg.Type, msg.Payload)
+	}
+	if msg := readWSMessage(t, conn); msg.ID != "1" || msg.Type != "complete" {
+		t.Fatalf("expected complete, got %+v", msg)
+	}
+	writeWSMessage(t, conn, `{"id":"2","type":"start","payload":{"query":"subscription { bleh }"}}`)
+	if msg := readWSMessage(t, conn); msg.ID != "2" || msg.Type != "error" {
+		t.Fatalf("expected error, got %+v", msg)
+	}
+
+	// The number of active operations on a connection is limited.
+	conn = dialGraphQLWebsocket(t, stack, "graphql-transport-ws")
+	defer conn.Close()
+	writeWSMessage(t, conn, `{"type":"connection_init"}`)
+	if msg := readWSMessage(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %+v", msg)
+	}
+	for i := 0; i < wsMaxOperations; i++ {
+		writeWSMessage(t, conn, fmt.Sprintf(`{"id":"%d","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`, i))
+	}
+	writeWSMessage(t, conn, `{"id":"excess","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`)
+	if msg := readWSMessage(t, conn); msg.ID != "excess" || msg.Type != "error" {
+		t.Fatalf("expected error for excess operation, got %+v", msg)
+	}
+	writeWSMessage(t, conn, `{"id":"0","type":"complete"}`)
+	writeWSMessage(t, conn, `{"id":"excess","type":"subscribe","payload":{"query":"{ block { number } }"}}`)
+	if msg := readWSMessage(t, conn); msg.ID != "excess" || msg.Type != "next" {
+		t.Fatalf("expected result once an operation is stopped, got %+v", msg)
+	}
+
+	// Upgrades are subject to the virtual host check.
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	header := http.Header{"Host": []string{"evil.example.com"}}
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	if conn, resp, err := dialer.Dial(url, header); err == nil {
+		conn.Close()
+		t.Errorf("websocket upgraded with invalid host")
+	} else if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected forbidden response for invalid host, got %v", err)
+	}
+
+	// Protocol violations close the connection.
+	for _, tt := range []struct {
+		protocol string
+		messages []string
+		code     int
+	}{
+		{
+			protocol: "",
+			code:     closeSubprotocol,
+		},
+		{
+			protocol: "graphql-transport-ws",
+			messages: []string{`{"id":"1","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`},
+			code:     closeUnauthorized,
+		},
+		{
+			protocol: "graphql-transport-ws",
+			messages: []string{`{"type":"connection_init"}`, `{"type":"connection_init"}`},
+			code:     closeTooManyInits,
+		},
+		{
+			protocol: "graphql-transport-ws",
+			messages: []string{
+				`{"type":"connection_init"}`,
+				`{"id":"1","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`,
+				`{"id":"1","type":"subscribe","payload":{"query":"subscription { newBlock { number } }"}}`,
+			},
+			code: closeSubscriberExists,
+		},
+		{
+			protocol: "graphql-transport-ws",
+			messages: []string{`{"type":"connection_init"}`, `{"id":"1","type":"start"}`},
+			code:     closeBadRequest,
+		},
+	} {
+		conn := dialGraphQLWebsocket(t, stack, tt.protocol)
+		for _, msg := range tt.messages {
+			writeWSMessage(t, conn, msg)
+		}
+		for {
+			_, _, err := conn.ReadMessage()
+			if err == nil {
+				continue
+			}
+			if !websocket.IsCloseError(err, tt.code) {
+				t.Errorf("protocol %q, messages %v: expected close code %d, got %v", tt.protocol, tt.messages, tt.code, err)
+			}
+			break
+		}
+		conn.Close()
+	}
+}
+
+func dialGraphQLWebsocket(t *testing.T, stack *node.Node, protocol string) *websocket.Conn {
+	var dialer websocket.Dialer
+	if protocol != "" {
+		dialer.Subprotocols = []string{protocol}
+	}
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial %s: %v", url, err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+func writeWSMessage(t *testing.T, conn *websocket.Conn, msg string) {
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("could not write message: %v", err)
+	}
+}
+
+func readWSMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("could not read message: %v", err)
+	}
+	return msg
+}
+
 func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -232,7 +699,7 @@ func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	return stack
 }
 
-func createGQLService(t *testing.T, stack *node.Node) {
+func createGQLService(t *testing.T, stack *node.Node) *eth.Ethereum {
 	// create backend
 	ethConf := &ethconfig.Config{
 		Genesis: &core.Genesis{
@@ -263,10 +730,11 @@ func createGQLService(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create import blocks: %v", err)
 	}
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, []string{}, []string{}, true)
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
+	return ethBackend
 }
 
 func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
@@ -348,7 +816,7 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create import blocks: %v", err)
 	}
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, []string{}, []string{}, true)
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
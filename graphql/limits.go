@@ -0,0 +1,98 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limits on the work done for a single request. The cost of a request is the number
+// of headers, blocks, receipts, transactions and states it loads from the backend,
+// with EVM executions weighing more.
+const (
+	maxQueryDepth = 20   // maximum nesting of fields in a query
+	maxQueryCost  = 1000 // maximum cost of a request, or of a subscription notification
+
+	costRead      = 1  // cost of loading an object from the backend
+	costExecution = 50 // cost of executing a call or tracing a transaction
+
+	maxTraceTimeout = 5 * time.Second // maximum duration of a transaction trace
+	maxTraceReexec  = 128             // maximum number of blocks re-executed to regenerate state for a trace
+)
+
+var (
+	errQueryTooExpensive = fmt.Errorf("query exceeds the cost limit of %d", maxQueryCost)
+	errTracingDisabled   = errors.New("transaction tracing is disabled")
+)
+
+type queryBudgetKey struct{}
+
+// queryBudget tracks the cost of a request. Every notification of a subscription
+// is charged on its own. graphql-go resolves each of them with a separate context
+// derived from the subscription's, one after the other, so the budget starts over
+// whenever it is charged from a different context.
+type queryBudget struct {
+	limit int64
+	used  int64 // accessed atomically
+
+	mu      sync.Mutex
+	current <-chan struct{} // done channel of the context charged last
+}
+
+// withQueryBudget limits the cost of the request executed with the returned context.
+func withQueryBudget(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, queryBudgetKey{}, &queryBudget{limit: limit})
+}
+
+// charge adds to the cost of a request. It fails when the limit of the request is
+// exceeded. Requests without a budget are not limited.
+func charge(ctx context.Context, cost int64) error {
+	budget, _ := ctx.Value(queryBudgetKey{}).(*queryBudget)
+	if budget == nil {
+		return nil
+	}
+	budget.mu.Lock()
+	if done := ctx.Done(); done != budget.current {
+		budget.current = done
+		atomic.StoreInt64(&budget.used, 0)
+	}
+	budget.mu.Unlock()
+
+	if atomic.AddInt64(&budget.used, cost) > budget.limit {
+		return errQueryTooExpensive
+	}
+	return nil
+}
+
+type tracingKey struct{}
+
+// withTracing enables transaction tracing for the request executed with the
+// returned context.
+func withTracing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tracingKey{}, true)
+}
+
+// tracingEnabled reports whether transaction tracing is enabled for a request.
+func tracingEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(tracingKey{}).(bool)
+	return enabled
+}
@@ -16,7 +16,28 @@
 
 package graphql
 
+// schema is the schema queries and mutations are executed on.
 const schema string = `
+    schema {
+        query: Query
+        mutation: Mutation
+    }
+` + schemaTypes
+
+// subscriptionSchema is the schema subscriptions are executed on. Both the query
+// and the subscription root have a logs field, which can't be told apart by a
+// single root resolver, so subscriptions are served by a resolver of their own.
+// Queries are never executed on this schema, the subscription root stands in as
+// the query root only because a schema can't go without one.
+const subscriptionSchema string = `
+    schema {
+        query: Subscription
+        subscription: Subscription
+    }
+` + schemaTypes
+
+// schemaTypes contains the type definitions shared by both schemas.
+const schemaTypes string = `
     # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
     scalar Bytes32
     # Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
@@ -30,11 +51,8 @@ const schema string = `
     scalar BigInt
     # Long is a 64 bit unsigned integer.
     scalar Long
-
-    schema {
-        query: Query
-        mutation: Mutation
-    }
+    # JSON is an arbitrary JSON value.
+    scalar JSON
 
     # Account is an Ethereum account at a particular block.
     type Account {
@@ -49,9 +67,9 @@ const schema string = `
         # Code contains the smart contract code for this account, if the account
         # is a (non-self-destructed) contract.
         code: Bytes!
-        # Storage provides access to the storage of a contract account, indexed
-        # by its 32 byte slot identifier.
-        storage(slot: Bytes32!): Bytes32!
+        # Storage provides access to the storage of a contract account. It returns
+        # the values of the given 32 byte slot identifiers, in the same order.
+        storage(slots: [Bytes32!]!): [Bytes32!]!
     }
 
     # Log is an Ethereum event log.
@@ -147,6 +165,31 @@ const schema string = `
         # RawReceipt is the canonical encoding of the receipt. For post EIP-2718 typed transactions
         # this is equivalent to TxType || ReceiptEncoding.
         rawReceipt: Bytes!
+        # Trace re-executes the transaction and returns the result of the given
+        # tracer, which is the struct logger if not supplied. It is null if the
+        # transaction has not yet been mined. Tracing has to be enabled on the
+        # server, which also caps the timeout and reexec options.
+        trace(tracer: String, config: TraceConfig): JSON
+    }
+
+    # TraceConfig configures the tracing of a transaction. All fields are optional.
+    input TraceConfig {
+        # EnableMemory enables capturing the memory in struct logs.
+        enableMemory: Boolean
+        # DisableStack disables capturing the stack in struct logs.
+        disableStack: Boolean
+        # DisableStorage disables capturing the storage in struct logs.
+        disableStorage: Boolean
+        # EnableReturnData enables capturing the return data in struct logs.
+        enableReturnData: Boolean
+        # Limit is the maximum number of struct logs, zero means unlimited.
+        limit: Int
+        # Timeout is the maximum duration of the trace, e.g. "2s". It can't exceed
+        # the limit of the server, which is also the default.
+        timeout: String
+        # Reexec is the number of blocks re-executed to regenerate missing state.
+        # It can't exceed the limit of the server, which is also the default.
+        reexec: Long
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -358,4 +401,27 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # LogFilter restricts the logs delivered by the logs subscription.
+    input LogFilter {
+        # Addresses is a list of addresses that are of interest. If this list is
+        # empty, results will not be filtered by address.
+        addresses: [Address!]
+        # Topics list restricts matches to particular event topics, in the same
+        # way as the topics of FilterCriteria.
+        topics: [[Bytes32!]!]
+    }
+
+    # Subscription streams chain events. Subscriptions are served over websocket
+    # using the graphql-transport-ws or graphql-ws protocol.
+    type Subscription {
+        # NewBlock delivers each block that becomes the head of the canonical chain.
+        # In case of a reorg, the new head is delivered without its ancestors.
+        newBlock: Block!
+        # Logs delivers the logs of new canonical blocks matching the filter.
+        # Logs removed by a reorg are not delivered again.
+        logs(filter: LogFilter!): Log!
+        # PendingTransactions delivers transactions entering the transaction pool.
+        pendingTransactions: Transaction!
+    }
 `
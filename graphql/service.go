@@ -22,11 +22,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 )
 
 type handler struct {
-	Schema *graphql.Schema
+	Schema  *graphql.Schema
+	tracing bool // whether transaction tracing is enabled
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -40,7 +42,11 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.Schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+	ctx := withQueryBudget(r.Context(), maxQueryCost)
+	if h.tracing {
+		ctx = withTracing(ctx)
+	}
+	response := h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -55,26 +61,43 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance. Transactions can only be traced
+// if tracing is enabled.
+func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string, tracing bool) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, cors, vhosts, tracing)
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+// Subscriptions are served to websocket clients on the same path. It
+// additionally exports an interactive query browser on the / endpoint.
+func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string, tracing bool) error {
+	q := Resolver{backend: backend}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	s, err := graphql.ParseSchema(schema, &q, graphql.MaxDepth(maxQueryDepth))
+	if err != nil {
+		return err
+	}
+	subs, err := graphql.ParseSchema(subscriptionSchema, &SubscriptionResolver{&q}, graphql.MaxDepth(maxQueryDepth))
 	if err != nil {
 		return err
 	}
-	h := handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
+	var (
+		httpHandler = handler{Schema: s, tracing: tracing}
+		wsHandler   = newWSHandler(s, subs, cors, tracing)
+	)
+	// Websocket upgrades pass through the HTTP handler stack as well, so they
+	// are subject to the same virtual host check.
+	handler := node.NewHTTPHandlerStack(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			wsHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	}), cors, vhosts, nil)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -0,0 +1,201 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// subscriptionBuffer is the number of notifications queued for a subscriber. The
+// subscription ends when a subscriber falls further behind, so that it can't stall
+// the event system.
+const subscriptionBuffer = 1024
+
+// fullNodeBackend is implemented by the backend of full nodes. The event system
+// needs to know whether it serves a light client.
+type fullNodeBackend interface {
+	Miner() *miner.Miner
+}
+
+// eventSystem returns the event system delivering chain events to subscriptions.
+func (r *Resolver) eventSystem() *filters.EventSystem {
+	r.eventsOnce.Do(func() {
+		_, full := r.backend.(fullNodeBackend)
+		r.events = filters.NewEventSystem(r.backend, !full)
+	})
+	return r.events
+}
+
+// SubscriptionResolver is the top-level object of subscriptions. It's separate
+// from the query resolver, as the logs field of the two can't share a method.
+type SubscriptionResolver struct {
+	*Resolver
+}
+
+// LogFilter encapsulates the arguments to `logs` on the subscription resolver.
+type LogFilter struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to particular event topics
+}
+
+func (r *SubscriptionResolver) NewBlock(ctx context.Context) (<-chan *Block, error) {
+	var (
+		headers = make(chan *types.Header)
+		sub     = r.eventSystem().SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		var queue []*Block
+		for {
+			var (
+				send chan *Block
+				next *Block
+			)
+			if len(queue) > 0 {
+				send, next = blocks, queue[0]
+			}
+			select {
+			case header := <-headers:
+				if len(queue) == subscriptionBuffer {
+					log.Debug("Dropping slow GraphQL subscriber", "subscription", "newBlock")
+					return
+				}
+				hash := header.Hash()
+				numberOrHash := rpc.BlockNumberOrHashWithHash(hash, false)
+				queue = append(queue, &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         hash,
+					header:       header,
+				})
+			case send <- next:
+				queue = queue[1:]
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+func (r *SubscriptionResolver) Logs(ctx context.Context, args struct{ Filter LogFilter }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := r.eventSystem().SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		var queue []*Log
+		for {
+			var (
+				send chan *Log
+				next *Log
+			)
+			if len(queue) > 0 {
+				send, next = logs, queue[0]
+			}
+			select {
+			case found := <-matches:
+				for _, l := range found {
+					if l.Removed {
+						continue
+					}
+					if len(queue) == subscriptionBuffer {
+						log.Debug("Dropping slow GraphQL subscriber", "subscription", "logs")
+						return
+					}
+					queue = append(queue, &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: l.TxHash},
+						log:         l,
+					})
+				}
+			case send <- next:
+				queue = queue[1:]
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+func (r *SubscriptionResolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	var (
+		hashes = make(chan []common.Hash)
+		sub    = r.eventSystem().SubscribePendingTxs(hashes)
+		txs    = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		var queue []*Transaction
+		for {
+			var (
+				send chan *Transaction
+				next *Transaction
+			)
+			if len(queue) > 0 {
+				send, next = txs, queue[0]
+			}
+			select {
+			case found := <-hashes:
+				for _, hash := range found {
+					if len(queue) == subscriptionBuffer {
+						log.Debug("Dropping slow GraphQL subscriber", "subscription", "pendingTransactions")
+						return
+					}
+					queue = append(queue, &Transaction{backend: r.backend, hash: hash})
+				}
+			case send <- next:
+				queue = queue[1:]
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
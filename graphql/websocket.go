@@ -0,0 +1,475 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	wsReadLimit         = 1024 * 1024
+	wsWriteTimeout      = 10 * time.Second
+	wsInitTimeout       = 10 * time.Second
+	wsKeepAliveInterval = 30 * time.Second
+	wsMaxOperations     = 64 // maximum number of active operations per connection
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	closeBadRequest       = 4400
+	closeUnauthorized     = 4401
+	closeSubprotocol      = 4406
+	closeInitTimeout      = 4408
+	closeSubscriberExists = 4409
+	closeTooManyInits     = 4429
+)
+
+// wsProtocol describes the message types of a GraphQL over websocket protocol.
+type wsProtocol struct {
+	subscribe string // starts an operation
+	next      string // carries a result of an operation
+	stop      string // stops an operation
+	keepAlive string // sent periodically by the server, if set
+	terminate string // sent by the client to close the connection, if set
+	pingPong  bool   // whether ping and pong messages are exchanged
+}
+
+var wsProtocols = map[string]*wsProtocol{
+	// The protocol of the graphql-ws library.
+	"graphql-transport-ws": {
+		subscribe: "subscribe",
+		next:      "next",
+		stop:      "complete",
+		pingPong:  true,
+	},
+	// The protocol of the older subscriptions-transport-ws library.
+	"graphql-ws": {
+		subscribe: "start",
+		next:      "data",
+		stop:      "stop",
+		keepAlive: "ka",
+		terminate: "connection_terminate",
+	},
+}
+
+// wsMessage is a message of both protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsHandler serves GraphQL operations, including subscriptions, over websocket.
+type wsHandler struct {
+	schema        *graphql.Schema // schema of queries and mutations
+	subscriptions *graphql.Schema // schema of subscriptions
+	tracing       bool            // whether transaction tracing is enabled
+	upgrader      websocket.Upgrader
+}
+
+func newWSHandler(schema, subscriptions *graphql.Schema, allowedOrigins []string, tracing bool) *wsHandler {
+	return &wsHandler{
+		schema:        schema,
+		subscriptions: subscriptions,
+		tracing:       tracing,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{"graphql-transport-ws", "graphql-ws"},
+			CheckOrigin:  wsOriginValidator(allowedOrigins),
+		},
+	}
+}
+
+// wsOriginValidator returns a function verifying the origin of websocket requests.
+// Requests without origin, same-origin requests and requests from the CORS domains
+// are accepted.
+func wsOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		origins[strings.ToLower(origin)] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || origins["*"] || origins[strings.ToLower(origin)] {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		log.Warn("Rejected GraphQL websocket connection", "origin", origin)
+		return false
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	proto := wsProtocols[conn.Subprotocol()]
+	if proto == nil {
+		msg := websocket.FormatCloseMessage(closeSubprotocol, "Subprotocol not acceptable")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &wsConn{
+		h:      h,
+		conn:   conn,
+		proto:  proto,
+		ctx:    ctx,
+		cancel: cancel,
+		ops:    make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a websocket connection of a GraphQL client.
+type wsConn struct {
+	h           *wsHandler
+	conn        *websocket.Conn
+	proto       *wsProtocol
+	initialised bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	writeMu sync.Mutex
+	opsMu   sync.Mutex
+	ops     map[string]context.CancelFunc // running operations by ID
+}
+
+// serve reads client messages until the connection is closed.
+func (c *wsConn) serve() {
+	defer func() {
+		c.cancel()
+		c.conn.Close()
+		c.wg.Wait()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && !c.initialised {
+				c.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+		if !c.handle(&msg) {
+			return
+		}
+	}
+}
+
+// handle processes a client message. It returns false if the connection should be
+// closed.
+func (c *wsConn) handle(msg *wsMessage) bool {
+	switch {
+	case msg.Type == "ping" && c.proto.pingPong:
+		return c.write(&wsMessage{Type: "pong", Payload: msg.Payload}) == nil
+
+	case msg.Type == "pong" && c.proto.pingPong:
+		return true
+
+	case msg.Type == "connection_init":
+		if c.initialised {
+			c.close(closeTooManyInits, "Too many initialisation requests")
+			return false
+		}
+		c.initialised = true
+		c.conn.SetReadDeadline(time.Time{})
+		if err := c.write(&wsMessage{Type: "connection_ack"}); err != nil {
+			return false
+		}
+		if c.proto.keepAlive != "" {
+			c.wg.Add(1)
+			go c.keepAlive()
+		}
+		return true
+
+	case !c.initialised:
+		c.close(closeUnauthorized, "Unauthorized")
+		return false
+
+	case msg.Type == c.proto.subscribe:
+		return c.start(msg)
+
+	case msg.Type == c.proto.stop:
+		c.stop(msg.ID)
+		return true
+
+	case msg.Type == c.proto.terminate:
+		return false
+
+	default:
+		c.close(closeBadRequest, fmt.Sprintf("Unexpected message of type %s received", msg.Type))
+		return false
+	}
+}
+
+// start runs an operation. Its results are delivered in the background.
+func (c *wsConn) start(msg *wsMessage) bool {
+	var req struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+		c.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+	c.opsMu.Lock()
+	if _, exists := c.ops[msg.ID]; exists {
+		c.opsMu.Unlock()
+		c.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.opsMu.Unlock()
+		payload, _ := json.Marshal([]map[string]string{{"message": "too many active operations"}})
+		return c.write(&wsMessage{ID: msg.ID, Type: "error", Payload: payload}) == nil
+	}
+	ctx := withQueryBudget(c.ctx, maxQueryCost)
+	if c.h.tracing {
+		ctx = withTracing(ctx)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.ops[msg.ID] = cancel
+	c.opsMu.Unlock()
+
+	// Operations are executed or subscribed to right away, so the operations of
+	// a connection are set up in order.
+	responses, err := c.h.run(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.remove(msg.ID)
+		payload, _ := json.Marshal([]map[string]string{{"message": err.Error()}})
+		return c.write(&wsMessage{ID: msg.ID, Type: "error", Payload: payload}) == nil
+	}
+	c.wg.Add(1)
+	go c.forward(ctx, msg.ID, responses)
+	return true
+}
+
+// run starts an operation and returns the channel delivering its results. Queries
+// and mutations are executed on the main schema, subscriptions are subscribed to
+// on the subscription schema.
+func (h *wsHandler) run(ctx context.Context, query string, operation string, variables map[string]interface{}) (<-chan interface{}, error) {
+	if operationType(query, operation) == "subscription" {
+		return h.subscriptions.Subscribe(ctx, query, operation, variables)
+	}
+	responses := make(chan interface{}, 1)
+	responses <- h.schema.Exec(ctx, query, operation, variables)
+	close(responses)
+	return responses, nil
+}
+
+// operationType returns the type of the named operation in a GraphQL document, or
+// of its only operation if no name is given. Invalid documents and unknown
+// operations yield the type of a query, whose execution reports the error.
+func operationType(doc string, name string) string {
+	var (
+		depth      int    // nesting of braces and parentheses
+		kind, op   string // keyword and name of the current definition
+		directive  bool   // whether the next name is a directive
+		operations = make(map[string]string)
+	)
+	for i := 0; i < len(doc); i++ {
+		switch c := doc[i]; {
+		case c == '#':
+			// Comments run to the end of the line.
+			for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(doc[i:], `"""`):
+			// Block strings end at the first unescaped triple quote.
+			for i += 3; i < len(doc) && !strings.HasPrefix(doc[i:], `"""`); i++ {
+				if strings.HasPrefix(doc[i:], `\"""`) {
+					i += 3
+				}
+			}
+			i += 2
+		case c == '"':
+			for i++; i < len(doc) && doc[i] != '"'; i++ {
+				if doc[i] == '\\' {
+					i++
+				}
+			}
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			if depth == 0 && c == '}' {
+				// The selection set of a definition is complete.
+				if kind == "" {
+					kind = "query"
+				}
+				if kind != "fragment" {
+					operations[op] = kind
+				}
+				kind, op = "", ""
+			}
+		case c == '@':
+			directive = true
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			start := i
+			for i+1 < len(doc) && isNameChar(doc[i+1]) {
+				i++
+			}
+			word := doc[start : i+1]
+			switch {
+			case depth > 0:
+			case directive:
+				directive = false
+			case kind == "":
+				kind = word
+			case op == "":
+				op = word
+			}
+		}
+	}
+	if name == "" && len(operations) == 1 {
+		for _, kind := range operations {
+			return kind
+		}
+	}
+	if kind, ok := operations[name]; ok && name != "" {
+		return kind
+	}
+	return "query"
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// forward delivers the results of an operation to the client.
+func (c *wsConn) forward(ctx context.Context, id string, responses <-chan interface{}) {
+	defer c.wg.Done()
+
+	failed := false
+	for resp := range responses {
+		// The schema closes the channel once the operation is stopped, keep draining
+		// it until then.
+		if failed || ctx.Err() != nil {
+			continue
+		}
+		resp := resp.(*graphql.Response)
+		if resp.Data == nil && len(resp.Errors) > 0 {
+			// The operation failed without producing a result.
+			payload, _ := json.Marshal(resp.Errors)
+			c.write(&wsMessage{ID: id, Type: "error", Payload: payload})
+			failed = true
+			continue
+		}
+		payload, err := json.Marshal(resp)
+		if err != nil {
+			log.Debug("Failed to encode GraphQL response", "err", err)
+			continue
+		}
+		c.write(&wsMessage{ID: id, Type: c.proto.next, Payload: payload})
+	}
+	// Operations stopped by the client are not completed.
+	if c.remove(id) && !failed {
+		c.write(&wsMessage{ID: id, Type: "complete"})
+	}
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
+
+	if cancel, ok := c.ops[id]; ok {
+		cancel()
+		delete(c.ops, id)
+	}
+}
+
+// remove forgets a finished operation. It returns false if the operation was
+// stopped.
+func (c *wsConn) remove(id string) bool {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
+
+	cancel, ok := c.ops[id]
+	if ok {
+		cancel()
+		delete(c.ops, id)
+	}
+	return ok
+}
+
+// keepAlive sends keep-alive messages until the connection is closed.
+func (c *wsConn) keepAlive() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		if c.write(&wsMessage{Type: c.proto.keepAlive}) != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends a message. The connection is closed if the message can't be written.
+func (c *wsConn) write(msg *wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	err := c.conn.WriteJSON(msg)
+	if err != nil {
+		c.conn.Close()
+	}
+	return err
+}
+
+// close sends a close message with the given code and reason.
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	msg := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+}
@@ -122,6 +122,21 @@ type SyncProgress struct {
 
 	HealingTrienodes uint64 // Number of state trie nodes pending
 	HealingBytecode  uint64 // Number of bytecodes pending
+
+	// "snap sync" estimates of the work left in each phase. Rates are in items
+	// per second and ETAs in seconds. All of them are zero if unknown.
+	AccountsRemaining  uint64 // Estimated number of accounts left to download
+	AccountsRate       uint64 // Number of accounts downloaded per second
+	AccountsETA        uint64 // Estimated time left to download all accounts
+	StorageRemaining   uint64 // Estimated number of storage slots left to download
+	StorageRate        uint64 // Number of storage slots downloaded per second
+	StorageETA         uint64 // Estimated time left to download all storage slots
+	BytecodesRemaining uint64 // Estimated number of bytecodes left to download
+	BytecodesRate      uint64 // Number of bytecodes downloaded per second
+	BytecodesETA       uint64 // Estimated time left to download all bytecodes
+	HealingRemaining   uint64 // Estimated number of state trie nodes left to heal
+	HealingRate        uint64 // Number of state trie nodes healed per second
+	HealingETA         uint64 // Estimated time left to heal the state trie
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -144,6 +144,18 @@ func (s *EthereumAPI) Syncing() (interface{}, error) {
 		"healedBytecodeBytes": hexutil.Uint64(progress.HealedBytecodeBytes),
 		"healingTrienodes":    hexutil.Uint64(progress.HealingTrienodes),
 		"healingBytecode":     hexutil.Uint64(progress.HealingBytecode),
+		"accountsRemaining":   hexutil.Uint64(progress.AccountsRemaining),
+		"accountsRate":        hexutil.Uint64(progress.AccountsRate),
+		"accountsEta":         hexutil.Uint64(progress.AccountsETA),
+		"storageRemaining":    hexutil.Uint64(progress.StorageRemaining),
+		"storageRate":         hexutil.Uint64(progress.StorageRate),
+		"storageEta":          hexutil.Uint64(progress.StorageETA),
+		"bytecodesRemaining":  hexutil.Uint64(progress.BytecodesRemaining),
+		"bytecodesRate":       hexutil.Uint64(progress.BytecodesRate),
+		"bytecodesEta":        hexutil.Uint64(progress.BytecodesETA),
+		"healingRemaining":    hexutil.Uint64(progress.HealingRemaining),
+		"healingRate":         hexutil.Uint64(progress.HealingRate),
+		"healingEta":          hexutil.Uint64(progress.HealingETA),
 	}, nil
 }
 
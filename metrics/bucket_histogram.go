@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultDurationBuckets are bucket bounds (in seconds) suitable for request latencies.
+var DefaultDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// BucketHistograms count observations into cumulative buckets with fixed upper
+// bounds. Unlike Histogram, they never drop samples and aren't reset, so the
+// buckets of multiple instances and scrapes can be aggregated.
+type BucketHistogram interface {
+	Buckets() []float64 // upper bounds of the buckets
+	Counts() []uint64   // cumulative number of observations <= bound, per bucket
+	Count() uint64      // number of observations
+	Sum() float64       // sum of observed values
+	Snapshot() BucketHistogram
+	Observe(float64)
+}
+
+// GetOrRegisterBucketHistogram returns an existing BucketHistogram or constructs and
+// registers a new StandardBucketHistogram.
+func GetOrRegisterBucketHistogram(name string, r Registry, buckets []float64) BucketHistogram {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return r.GetOrRegister(name, func() BucketHistogram { return NewBucketHistogram(buckets) }).(BucketHistogram)
+}
+
+// NewBucketHistogram constructs a new StandardBucketHistogram with the given
+// bucket bounds.
+func NewBucketHistogram(buckets []float64) BucketHistogram {
+	if !Enabled {
+		return NilBucketHistogram{}
+	}
+	bounds := make([]float64, len(buckets))
+	copy(bounds, buckets)
+	sort.Float64s(bounds)
+	return &StandardBucketHistogram{buckets: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// NewRegisteredBucketHistogram constructs and registers a new StandardBucketHistogram.
+func NewRegisteredBucketHistogram(name string, r Registry, buckets []float64) BucketHistogram {
+	c := NewBucketHistogram(buckets)
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name, c)
+	return c
+}
+
+// BucketHistogramSnapshot is a read-only copy of another BucketHistogram.
+type BucketHistogramSnapshot struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Buckets returns the bucket bounds.
+func (h *BucketHistogramSnapshot) Buckets() []float64 { return h.buckets }
+
+// Counts returns the cumulative bucket counts at the time the snapshot was taken.
+func (h *BucketHistogramSnapshot) Counts() []uint64 { return h.counts }
+
+// Count returns the number of observations at the time the snapshot was taken.
+func (h *BucketHistogramSnapshot) Count() uint64 { return h.count }
+
+// Sum returns the sum of observations at the time the snapshot was taken.
+func (h *BucketHistogramSnapshot) Sum() float64 { return h.sum }
+
+// Snapshot returns the snapshot.
+func (h *BucketHistogramSnapshot) Snapshot() BucketHistogram { return h }
+
+// Observe panics.
+func (*BucketHistogramSnapshot) Observe(float64) {
+	panic("Observe called on a BucketHistogramSnapshot")
+}
+
+// NilBucketHistogram is a no-op BucketHistogram.
+type NilBucketHistogram struct{}
+
+// Buckets is a no-op.
+func (NilBucketHistogram) Buckets() []float64 { return nil }
+
+// Counts is a no-op.
+func (NilBucketHistogram) Counts() []uint64 { return nil }
+
+// Count is a no-op.
+func (NilBucketHistogram) Count() uint64 { return 0 }
+
+// Sum is a no-op.
+func (NilBucketHistogram) Sum() float64 { return 0 }
+
+// Snapshot is a no-op.
+func (NilBucketHistogram) Snapshot() BucketHistogram { return NilBucketHistogram{} }
+
+// Observe is a no-op.
+func (NilBucketHistogram) Observe(float64) {}
+
+// StandardBucketHistogram is the standard implementation of a BucketHistogram.
+type StandardBucketHistogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64 // non-cumulative
+	count   uint64
+	sum     float64
+}
+
+// Buckets returns the bucket bounds.
+func (h *StandardBucketHistogram) Buckets() []float64 { return h.buckets }
+
+// Counts returns the cumulative bucket counts.
+func (h *StandardBucketHistogram) Counts() []uint64 { return h.Snapshot().Counts() }
+
+// Count returns the number of observations.
+func (h *StandardBucketHistogram) Count() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of observations.
+func (h *StandardBucketHistogram) Sum() float64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.sum
+}
+
+// Snapshot returns a read-only copy of the histogram.
+func (h *StandardBucketHistogram) Snapshot() BucketHistogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	var total uint64
+	for i, c := range h.counts {
+		total += c
+		counts[i] = total
+	}
+	return &BucketHistogramSnapshot{buckets: h.buckets, counts: counts, count: h.count, sum: h.sum}
+}
+
+// Observe records a value.
+func (h *StandardBucketHistogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func BenchmarkBucketHistogram(b *testing.B) {
+	h := NewBucketHistogram(DefaultDurationBuckets)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Observe(float64(i%1000) / 100)
+	}
+}
+
+func TestBucketHistogram(t *testing.T) {
+	h := NewBucketHistogram([]float64{10, 1, 5})
+	for _, v := range []float64{0.5, 1, 3, 7, 20, 100} {
+		h.Observe(v)
+	}
+	if b := h.Buckets(); !reflect.DeepEqual(b, []float64{1, 5, 10}) {
+		t.Errorf("h.Buckets(): %v", b)
+	}
+	if c := h.Counts(); !reflect.DeepEqual(c, []uint64{2, 3, 4}) {
+		t.Errorf("h.Counts(): %v", c)
+	}
+	if count := h.Count(); count != 6 {
+		t.Errorf("h.Count(): 6 != %v", count)
+	}
+	if sum := h.Sum(); sum != 131.5 {
+		t.Errorf("h.Sum(): 131.5 != %v", sum)
+	}
+}
+
+func TestBucketHistogramSnapshot(t *testing.T) {
+	h := NewBucketHistogram([]float64{1})
+	h.Observe(0.5)
+	snapshot := h.Snapshot()
+	h.Observe(2)
+	if c := snapshot.Counts(); !reflect.DeepEqual(c, []uint64{1}) || snapshot.Count() != 1 {
+		t.Errorf("snapshot changed: counts %v, count %d", c, snapshot.Count())
+	}
+}
+
+func TestGetOrRegisterBucketHistogram(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredBucketHistogram("foo", r, []float64{1}).Observe(0.5)
+	if h := GetOrRegisterBucketHistogram("foo", r, []float64{1}); h.Count() != 1 {
+		t.Fatal(h)
+	}
+}
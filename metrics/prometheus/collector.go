@@ -29,8 +29,10 @@ var (
 	typeGaugeTpl           = "# TYPE %s gauge\n"
 	typeCounterTpl         = "# TYPE %s counter\n"
 	typeSummaryTpl         = "# TYPE %s summary\n"
+	typeHistogramTpl       = "# TYPE %s histogram\n"
 	keyValueTpl            = "%s %v\n\n"
 	keyQuantileTagValueTpl = "%s {quantile=\"%s\"} %v\n"
+	keyBucketTagValueTpl   = "%s_bucket {le=\"%s\"} %v\n"
 )
 
 // collector is a collection of byte buffers that aggregate Prometheus reports
@@ -98,6 +100,19 @@ func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
 	c.buff.WriteRune('\n')
 }
 
+func (c *collector) addBucketHistogram(name string, m metrics.BucketHistogram) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeHistogramTpl, name))
+	counts := m.Counts()
+	for i, bound := range m.Buckets() {
+		c.buff.WriteString(fmt.Sprintf(keyBucketTagValueTpl, name, strconv.FormatFloat(bound, 'f', -1, 64), counts[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf(keyBucketTagValueTpl, name, "+Inf", m.Count()))
+	c.buff.WriteString(fmt.Sprintf("%s_sum %v\n", name, m.Sum()))
+	c.buff.WriteString(fmt.Sprintf("%s_count %v\n", name, m.Count()))
+	c.buff.WriteRune('\n')
+}
+
 func (c *collector) writeGaugeCounter(name string, value interface{}) {
 	name = mutateKey(name)
 	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
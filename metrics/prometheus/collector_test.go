@@ -58,6 +58,13 @@ func TestCollector(t *testing.T) {
 	emptyResettingTimer := metrics.NewResettingTimer().Snapshot()
 	c.addResettingTimer("test/empty_resetting_timer", emptyResettingTimer)
 
+	bucketHistogram := metrics.NewBucketHistogram([]float64{0.1, 1, 0.5})
+	bucketHistogram.Observe(0.05)
+	bucketHistogram.Observe(0.5)
+	bucketHistogram.Observe(0.7)
+	bucketHistogram.Observe(3)
+	c.addBucketHistogram("test/bucket_histogram", bucketHistogram.Snapshot())
+
 	const expectedOutput = `# TYPE test_counter gauge
 test_counter 12345
 
@@ -100,6 +107,14 @@ test_resetting_timer {quantile="0.50"} 12000000
 test_resetting_timer {quantile="0.95"} 120000000
 test_resetting_timer {quantile="0.99"} 120000000
 
+# TYPE test_bucket_histogram histogram
+test_bucket_histogram_bucket {le="0.1"} 1
+test_bucket_histogram_bucket {le="0.5"} 2
+test_bucket_histogram_bucket {le="1"} 3
+test_bucket_histogram_bucket {le="+Inf"} 4
+test_bucket_histogram_sum 4.25
+test_bucket_histogram_count 4
+
 `
 	exp := c.buff.String()
 	if exp != expectedOutput {
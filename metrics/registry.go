@@ -143,6 +143,10 @@ func (r *StandardRegistry) GetAll() map[string]map[string]interface{} {
 			values["95%"] = ps[2]
 			values["99%"] = ps[3]
 			values["99.9%"] = ps[4]
+		case BucketHistogram:
+			h := metric.Snapshot()
+			values["count"] = h.Count()
+			values["sum"] = h.Sum()
 		case Meter:
 			m := metric.Snapshot()
 			values["count"] = m.Count()
@@ -196,7 +200,7 @@ func (r *StandardRegistry) register(name string, i interface{}) error {
 		return DuplicateMetric(name)
 	}
 	switch i.(type) {
-	case Counter, Gauge, GaugeFloat64, Healthcheck, Histogram, Meter, Timer, ResettingTimer:
+	case Counter, Gauge, GaugeFloat64, Healthcheck, Histogram, Meter, Timer, ResettingTimer, BucketHistogram:
 		r.metrics[name] = i
 	}
 	return nil
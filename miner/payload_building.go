@@ -0,0 +1,230 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// payloadBuildDeadline is the time after the payload's timestamp at which
+	// the background building is abandoned. It corresponds to SECONDS_PER_SLOT
+	// in the mainnet beacon chain configuration.
+	payloadBuildDeadline = 12 * time.Second
+
+	// payloadResolveTimeout is the maximum time a payload retrieval waits for
+	// the first full block if none was built yet, before falling back to the
+	// empty one.
+	payloadResolveTimeout = 500 * time.Millisecond
+)
+
+var (
+	payloadIterationMeter   = metrics.NewRegisteredMeter("miner/payload/iterations", nil)
+	payloadImprovementMeter = metrics.NewRegisteredMeter("miner/payload/improvements", nil)
+	payloadFailureMeter     = metrics.NewRegisteredMeter("miner/payload/failures", nil)
+	payloadTimeoutMeter     = metrics.NewRegisteredMeter("miner/payload/timeouts", nil)
+	payloadBuildTimer       = metrics.NewRegisteredTimer("miner/payload/build", nil)
+)
+
+// BuildPayloadArgs contains the provided parameters for building payload.
+// Check engine-api specification for more details.
+// https://github.com/ethereum/execution-apis/blob/main/src/engine/specification.md#payloadattributesv1
+type BuildPayloadArgs struct {
+	Parent       common.Hash    // The parent block to build payload on top
+	Timestamp    uint64         // The provided timestamp of generated payload
+	FeeRecipient common.Address // The provided recipient address for collecting transaction fee
+	Random       common.Hash    // The provided randomness value
+}
+
+// Payload wraps the built payload(block waiting for sealing). According to the
+// engine-api specification, EL should build the initial version of the payload
+// which has an empty transaction set and then keep update it in order to maximize
+// the revenue. Therefore, the empty-block here is always available and full-block
+// will be set/updated afterwards.
+type Payload struct {
+	id       beacon.PayloadID
+	empty    *types.Block
+	full     *types.Block
+	fullFees *big.Int
+
+	stop    chan struct{} // Closed when the building should be terminated
+	done    chan struct{} // Closed when the background builder exited
+	updated chan struct{} // Closed when the first full block is available
+	lock    sync.Mutex
+}
+
+// newPayload initializes the payload object.
+func newPayload(empty *types.Block, id beacon.PayloadID) *Payload {
+	payload := &Payload{
+		id:      id,
+		empty:   empty,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		updated: make(chan struct{}),
+	}
+	log.Info("Starting work on payload", "id", payload.id)
+	return payload
+}
+
+// update updates the full-block with latest built version, if it pays more fees
+// than the previous best one.
+func (payload *Payload) update(block *types.Block, fees *big.Int, elapsed time.Duration) {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+		return // reject stale update
+	default:
+	}
+	// Ensure the newly provided full block has a higher transaction fee.
+	// In post-merge stage, there is no uncle reward anymore and transaction
+	// fee(apart from the mev revenue) is the only indicator for comparison.
+	if payload.full == nil || fees.Cmp(payload.fullFees) > 0 {
+		first := payload.full == nil
+
+		payload.full = block
+		payload.fullFees = fees
+		payloadImprovementMeter.Mark(1)
+
+		feesInEther := weiToEther(fees)
+		log.Info("Updated payload", "id", payload.id, "number", block.NumberU64(), "hash", block.Hash(),
+			"txs", len(block.Transactions()), "gas", block.GasUsed(), "fees", feesInEther,
+			"root", block.Root(), "elapsed", common.PrettyDuration(elapsed))
+
+		if first {
+			close(payload.updated)
+		}
+	}
+}
+
+// Resolve returns the latest built payload and also terminates the background
+// thread for updating payload. If no full block was built yet, it waits for a
+// short while for the first one, falling back to the empty block otherwise.
+// It's safe to be called multiple times.
+func (payload *Payload) Resolve() *beacon.ExecutableDataV1 {
+	timeout := time.NewTimer(payloadResolveTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-payload.updated:
+	case <-payload.done:
+	case <-timeout.C:
+	}
+	payload.Stop()
+
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	if payload.full != nil {
+		return beacon.BlockToExecutableData(payload.full)
+	}
+	return beacon.BlockToExecutableData(payload.empty)
+}
+
+// ResolveEmpty is basically identical to Resolve, but it expects empty block only.
+// It's only used in tests.
+func (payload *Payload) ResolveEmpty() *beacon.ExecutableDataV1 {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	return beacon.BlockToExecutableData(payload.empty)
+}
+
+// Stop terminates the background building of the payload without resolving it.
+// It's safe to be called multiple times.
+func (payload *Payload) Stop() {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+	default:
+		close(payload.stop)
+	}
+}
+
+// buildPayload builds the payload according to the provided parameters.
+func (w *worker) buildPayload(args *BuildPayloadArgs, id beacon.PayloadID) (*Payload, error) {
+	// Build the initial version with no transaction included. It should be fast
+	// enough to run. The empty payload can at least make sure there is something
+	// to deliver for not missing slot.
+	empty, _, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, true)
+	if err != nil {
+		return nil, err
+	}
+	// Construct a payload object for return.
+	payload := newPayload(empty, id)
+
+	// Spin up a routine for updating the payload in background. This strategy
+	// can maximum the revenue for including transactions with highest fee.
+	go func() {
+		defer close(payload.done)
+
+		build := func() {
+			start := time.Now()
+			block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, false)
+
+			payloadIterationMeter.Mark(1)
+			payloadBuildTimer.UpdateSince(start)
+			if err != nil {
+				payloadFailureMeter.Mark(1)
+				log.Debug("Failed to build payload", "id", payload.id, "err", err)
+				return
+			}
+			payload.update(block, fees, time.Since(start))
+		}
+		// Build the first full version right away, even if the slot is already
+		// over, so that there's always a full block to deliver if possible.
+		build()
+
+		// Setup the timer for re-building the payload and the one for terminating
+		// the process if SECONDS_PER_SLOT have passed since the point in time
+		// identified by the timestamp parameter.
+		timer := time.NewTimer(w.recommit)
+		defer timer.Stop()
+
+		endTimer := time.NewTimer(time.Until(time.Unix(int64(args.Timestamp), 0).Add(payloadBuildDeadline)))
+		defer endTimer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				build()
+				timer.Reset(w.recommit)
+			case <-payload.stop:
+				log.Info("Stopping work on payload", "id", payload.id, "reason", "delivery")
+				return
+			case <-endTimer.C:
+				payloadTimeoutMeter.Mark(1)
+				log.Info("Stopping work on payload", "id", payload.id, "reason", "timeout")
+				return
+			case <-w.exitCh:
+				return
+			}
+		}
+	}()
+	return payload, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func newPostMergeTestWorker(t *testing.T) (*worker, *testWorkerBackend, func()) {
+	config := new(params.ChainConfig)
+	*config = *ethashChainConfig
+	config.TerminalTotalDifficulty = big.NewInt(0)
+
+	engine := ethash.NewFaker()
+	w, b := newTestWorker(t, config, engine, rawdb.NewMemoryDatabase(), 0)
+	return w, b, func() {
+		w.close()
+		engine.Close()
+	}
+}
+
+func TestBuildPayload(t *testing.T) {
+	w, b, cleanup := newPostMergeTestWorker(t)
+	defer cleanup()
+
+	var (
+		timestamp = uint64(time.Now().Unix())
+		recipient = common.HexToAddress("0xdeadbeef")
+		args      = &BuildPayloadArgs{
+			Parent:       b.chain.CurrentBlock().Hash(),
+			Timestamp:    timestamp,
+			Random:       common.Hash{},
+			FeeRecipient: recipient,
+		}
+	)
+	payload, err := w.buildPayload(args, beacon.PayloadID{0x01})
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	verify := func(data *beacon.ExecutableDataV1, txs int) {
+		t.Helper()
+		if data.ParentHash != b.chain.CurrentBlock().Hash() {
+			t.Fatal("Unexpect parent hash")
+		}
+		if data.Random != (common.Hash{}) {
+			t.Fatal("Unexpect random value")
+		}
+		if data.Timestamp != timestamp {
+			t.Fatal("Unexpect timestamp")
+		}
+		if data.FeeRecipient != recipient {
+			t.Fatal("Unexpect fee recipient")
+		}
+		if len(data.Transactions) != txs {
+			t.Fatalf("Unexpect transaction set: have %d, want %d", len(data.Transactions), txs)
+		}
+	}
+	verify(payload.ResolveEmpty(), 0)
+	verify(payload.Resolve(), len(pendingTxs))
+
+	// Ensure resolving terminated the background building
+	select {
+	case <-payload.done:
+	case <-time.After(time.Second):
+		t.Fatal("Payload building not terminated after resolution")
+	}
+	// Ensure later calls return the same version
+	verify(payload.Resolve(), len(pendingTxs))
+}
+
+func TestBuildPayloadImprovement(t *testing.T) {
+	w, b, cleanup := newPostMergeTestWorker(t)
+	defer cleanup()
+
+	args := &BuildPayloadArgs{
+		Parent:    b.chain.CurrentBlock().Hash(),
+		Timestamp: uint64(time.Now().Unix()),
+	}
+	payload, err := w.buildPayload(args, beacon.PayloadID{0x02})
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	// Wait for the first full version, then feed new transactions into the
+	// pool which should be picked up by the next rebuild.
+	select {
+	case <-payload.updated:
+	case <-time.After(time.Second):
+		t.Fatal("First full payload not built in time")
+	}
+	b.txPool.AddLocals(newTxs)
+
+	// Poll the latest full block until a rebuild includes the new transactions.
+	// Resolving would terminate the building, so peek at the block directly.
+	var (
+		want     = len(pendingTxs) + len(newTxs)
+		deadline = time.After(w.recommit + 5*time.Second)
+		poll     = time.NewTicker(10 * time.Millisecond)
+	)
+	defer poll.Stop()
+
+	for {
+		payload.lock.Lock()
+		have := len(payload.full.Transactions())
+		payload.lock.Unlock()
+
+		if have == want {
+			break
+		}
+		select {
+		case <-poll.C:
+		case <-deadline:
+			t.Fatalf("Payload not improved: have %d txs, want %d", have, want)
+		}
+	}
+	if have := len(payload.Resolve().Transactions); have != want {
+		t.Fatalf("Resolved payload mismatch: have %d txs, want %d", have, want)
+	}
+}
+
+func TestBuildPayloadStop(t *testing.T) {
+	w, b, cleanup := newPostMergeTestWorker(t)
+	defer cleanup()
+
+	args := &BuildPayloadArgs{
+		Parent:    b.chain.CurrentBlock().Hash(),
+		Timestamp: uint64(time.Now().Unix()),
+	}
+	payload, err := w.buildPayload(args, beacon.PayloadID{0x03})
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	select {
+	case <-payload.updated:
+	case <-time.After(time.Second):
+		t.Fatal("First full payload not built in time")
+	}
+	// Cancelling the payload should terminate the builder, and any full block
+	// built in the meantime should be rejected.
+	payload.Stop()
+	payload.Stop() // Ensure it's safe to call multiple times
+
+	select {
+	case <-payload.done:
+	case <-time.After(time.Second):
+		t.Fatal("Payload building not terminated after cancellation")
+	}
+	payload.update(payload.empty, new(big.Int).Lsh(common.Big1, 128), 0)
+
+	if txs := len(payload.Resolve().Transactions); txs != len(pendingTxs) {
+		t.Fatalf("Cancelled payload resolved to %d txs, want %d", txs, len(pendingTxs))
+	}
+}
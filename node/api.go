@@ -300,6 +300,26 @@ func (api *adminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerScores retrieves the reputation of all remote nodes tracked by the p2p
+// server, worst scoring first.
+func (api *adminAPI) PeerScores() ([]*p2p.PeerScoreInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerScores(), nil
+}
+
+// NATInfo retrieves the state of the NAT port mappings, including the lease
+// renewal outcomes and the external IP reported by the gateway.
+func (api *adminAPI) NATInfo() (*p2p.NATInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.NATInfo(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *adminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -122,6 +122,27 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch, applied to all
+	// RPC endpoints. Zero means no limit.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of bytes returned from a batched
+	// call, applied to all RPC endpoints. Zero means no limit.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// RPCAccessLog is the file to which HTTP and WebSocket RPC calls are logged as
+	// JSON lines. Access logging is disabled if empty.
+	RPCAccessLog string `toml:",omitempty"`
+
+	// RPCAccessLogSampleRate is the fraction of successful calls which are written
+	// to the access log. Failed calls are always logged.
+	RPCAccessLogSampleRate float64 `toml:",omitempty"`
+
+	// RPCRateLimit configures the rate limits and daily quotas enforced on the
+	// HTTP and WebSocket RPC endpoints. On the authenticated endpoints, clients
+	// are identified by the subject of their JWT token.
+	RPCRateLimit rpc.RateLimitConfig `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -161,6 +182,20 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// H2Host is the host interface on which to start the HTTP/2 streaming RPC
+	// server. If this field is empty, no HTTP/2 streaming endpoint will be started.
+	// Streams are subject to the same virtual host check as HTTP requests.
+	H2Host string `toml:",omitempty"`
+
+	// H2Port is the TCP port number on which to start the HTTP/2 streaming RPC
+	// server. The default zero value is valid and will pick a port number randomly.
+	H2Port int `toml:",omitempty"`
+
+	// H2Modules is a list of API modules to expose via the HTTP/2 streaming RPC
+	// interface. If the module list is empty, all RPC API endpoints designated
+	// public will be exposed.
+	H2Modules []string `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -175,6 +210,10 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables the trace field of transactions. Tracing re-executes
+	// transactions, so it's disabled by default.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -264,10 +303,19 @@ func DefaultWSEndpoint() string {
 	return config.WSEndpoint()
 }
 
+// H2Endpoint resolves the HTTP/2 streaming endpoint based on the configured host
+// interface and port parameters.
+func (c *Config) H2Endpoint() string {
+	if c.H2Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.H2Host, c.H2Port)
+}
+
 // ExtRPCEnabled returns the indicator whether node enables the external
-// RPC(http, ws or graphql).
+// RPC(http, ws, h2 or graphql).
 func (c *Config) ExtRPCEnabled() bool {
-	return c.HTTPHost != "" || c.WSHost != ""
+	return c.HTTPHost != "" || c.WSHost != "" || c.H2Host != ""
 }
 
 // NodeName returns the devp2p node identifier.
@@ -32,6 +32,8 @@ const (
 	DefaultHTTPPort    = 8545        // Default TCP port for the HTTP RPC server
 	DefaultWSHost      = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort      = 8546        // Default TCP port for the websocket RPC server
+	DefaultH2Host      = "localhost" // Default host interface for the HTTP/2 streaming RPC server
+	DefaultH2Port      = 8548        // Default TCP port for the HTTP/2 streaming RPC server
 	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
 	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
 	DefaultAuthHost    = "localhost" // Default host interface for the authenticated apis
@@ -48,17 +50,22 @@ var (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:             DefaultDataDir(),
-	HTTPPort:            DefaultHTTPPort,
-	AuthAddr:            DefaultAuthHost,
-	AuthPort:            DefaultAuthPort,
-	AuthVirtualHosts:    DefaultAuthVhosts,
-	HTTPModules:         []string{"net", "web3"},
-	HTTPVirtualHosts:    []string{"localhost"},
-	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
-	WSPort:              DefaultWSPort,
-	WSModules:           []string{"net", "web3"},
-	GraphQLVirtualHosts: []string{"localhost"},
+	DataDir:                DefaultDataDir(),
+	HTTPPort:               DefaultHTTPPort,
+	AuthAddr:               DefaultAuthHost,
+	AuthPort:               DefaultAuthPort,
+	AuthVirtualHosts:       DefaultAuthVhosts,
+	HTTPModules:            []string{"net", "web3"},
+	HTTPVirtualHosts:       []string{"localhost"},
+	HTTPTimeouts:           rpc.DefaultHTTPTimeouts,
+	BatchRequestLimit:      1000,
+	BatchResponseMaxSize:   25 * 1000 * 1000,
+	RPCAccessLogSampleRate: 1,
+	WSPort:                 DefaultWSPort,
+	WSModules:              []string{"net", "web3"},
+	H2Port:                 DefaultH2Port,
+	H2Modules:              []string{"net", "web3"},
+	GraphQLVirtualHosts:    []string{"localhost"},
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -61,8 +61,10 @@ type Node struct {
 	ws            *httpServer //
 	httpAuth      *httpServer //
 	wsAuth        *httpServer //
+	h2            *h2Server   // HTTP/2 streaming RPC server
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	accessLog     *os.File    // RPC access log, if enabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -156,7 +158,10 @@ func New(conf *Config) (*Node, error) {
 	node.httpAuth = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.wsAuth = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
+	node.h2 = newH2Server(node.log)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	node.ipc.batchItemLimit, node.ipc.batchResponseLimit = conf.BatchRequestLimit, conf.BatchResponseMaxSize
+	node.inprocHandler.SetBatchLimits(conf.BatchRequestLimit, conf.BatchResponseMaxSize)
 
 	return node, nil
 }
@@ -392,9 +397,29 @@ func (n *Node) startRPC() error {
 		}
 	}
 	var (
-		servers   []*httpServer
-		open, all = n.GetAPIs()
+		servers         []*httpServer
+		open, all       = n.GetAPIs()
+		middlewares     []rpc.Middleware
+		authMiddlewares []rpc.Middleware
 	)
+	// The access logger comes first, so that it also records rejected calls.
+	if n.config.RPCAccessLog != "" {
+		f, err := os.OpenFile(n.config.RPCAccessLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("can't open RPC access log: %v", err)
+		}
+		n.accessLog = f
+		logger := rpc.NewAccessLogger(f, n.config.RPCAccessLogSampleRate)
+		middlewares = append(middlewares, logger)
+		authMiddlewares = append(authMiddlewares, logger)
+	}
+	// The rate limiter is shared, so that clients using several transports are
+	// accounted for once. The authenticated endpoints get a limiter of their own,
+	// which tells clients apart by the subject of their JWT token.
+	if n.config.RPCRateLimit.Enabled() {
+		middlewares = append(middlewares, rpc.NewRateLimiter(n.config.RPCRateLimit))
+		authMiddlewares = append(authMiddlewares, rpc.NewRateLimiter(n.config.RPCRateLimit))
+	}
 
 	initHttp := func(server *httpServer, apis []rpc.API, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
@@ -405,6 +430,9 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			middlewares:        middlewares,
+			batchItemLimit:     n.config.BatchRequestLimit,
+			batchResponseLimit: n.config.BatchResponseMaxSize,
 		}); err != nil {
 			return err
 		}
@@ -418,9 +446,12 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(n.rpcAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:            n.config.WSModules,
+			Origins:            n.config.WSOrigins,
+			prefix:             n.config.WSPathPrefix,
+			middlewares:        middlewares,
+			batchItemLimit:     n.config.BatchRequestLimit,
+			batchResponseLimit: n.config.BatchResponseMaxSize,
 		}); err != nil {
 			return err
 		}
@@ -440,6 +471,9 @@ func (n *Node) startRPC() error {
 			Modules:            DefaultAuthModules,
 			prefix:             DefaultAuthPrefix,
 			jwtSecret:          secret,
+			middlewares:        authMiddlewares,
+			batchItemLimit:     n.config.BatchRequestLimit,
+			batchResponseLimit: n.config.BatchResponseMaxSize,
 		}); err != nil {
 			return err
 		}
@@ -450,10 +484,13 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(apis, wsConfig{
-			Modules:   DefaultAuthModules,
-			Origins:   DefaultAuthOrigins,
-			prefix:    DefaultAuthPrefix,
-			jwtSecret: secret,
+			Modules:            DefaultAuthModules,
+			Origins:            DefaultAuthOrigins,
+			prefix:             DefaultAuthPrefix,
+			jwtSecret:          secret,
+			middlewares:        authMiddlewares,
+			batchItemLimit:     n.config.BatchRequestLimit,
+			batchResponseLimit: n.config.BatchResponseMaxSize,
 		}); err != nil {
 			return err
 		}
@@ -475,6 +512,19 @@ func (n *Node) startRPC() error {
 			return err
 		}
 	}
+	// Configure HTTP/2 streaming.
+	if n.config.H2Host != "" {
+		n.h2.setListenAddr(n.config.H2Host, n.config.H2Port)
+		if err := n.h2.start(open, h2Config{
+			Modules:            n.config.H2Modules,
+			Vhosts:             n.config.HTTPVirtualHosts,
+			middlewares:        middlewares,
+			batchItemLimit:     n.config.BatchRequestLimit,
+			batchResponseLimit: n.config.BatchResponseMaxSize,
+		}); err != nil {
+			return err
+		}
+	}
 	// Configure authenticated API
 	if len(open) != len(all) {
 		jwtSecret, err := n.obtainJWTSecret(n.config.JWTSecret)
@@ -510,8 +560,13 @@ func (n *Node) stopRPC() {
 	n.ws.stop()
 	n.httpAuth.stop()
 	n.wsAuth.stop()
+	n.h2.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.accessLog != nil {
+		n.accessLog.Close()
+		n.accessLog = nil
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -668,6 +723,15 @@ func (n *Node) WSEndpoint() string {
 	return "ws://" + n.ws.listenAddr() + n.ws.wsConfig.prefix
 }
 
+// H2Endpoint returns the URL of the HTTP/2 streaming RPC server, or the empty
+// string if it is not running.
+func (n *Node) H2Endpoint() string {
+	if n.config.H2Host == "" {
+		return ""
+	}
+	return "h2c://" + n.h2.listenAddr()
+}
+
 // EventMux retrieves the event multiplexer used by all the network services in
 // the current protocol stack.
 func (n *Node) EventMux() *event.TypeMux {
@@ -17,21 +17,30 @@
 package node
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
 )
 
 var (
@@ -585,6 +594,153 @@ func (test rpcPrefixTest) check(t *testing.T, node *Node) {
 	}
 }
 
+// This test checks that HTTP RPC calls are written to the access log.
+func TestNodeRPCAccessLog(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	node, err := New(&Config{
+		HTTPHost:               "127.0.0.1",
+		RPCAccessLog:           logfile,
+		RPCAccessLogSampleRate: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := rpc.Dial(node.HTTPEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	node.Close()
+
+	content, err := os.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry rpc.AccessLogEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		t.Fatalf("invalid access log %q: %v", content, err)
+	}
+	if entry.Method != "rpc_modules" || entry.Transport != "http" || entry.RemoteAddr == "" {
+		t.Errorf("wrong access log entry: %+v", entry)
+	}
+}
+
+// This test checks that the rate limits are enforced on the authenticated
+// endpoints, telling clients apart by the subject of their JWT token.
+func TestNodeAuthRateLimit(t *testing.T) {
+	secret := make([]byte, 32)
+	secretFile := filepath.Join(t.TempDir(), "jwt.hex")
+	if err := os.WriteFile(secretFile, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node, err := New(&Config{
+		AuthAddr:     "127.0.0.1",
+		JWTSecret:    secretFile,
+		RPCRateLimit: rpc.RateLimitConfig{DailyQuota: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.RegisterAPIs([]rpc.API{{Namespace: "engine", Service: new(NoopLifecycle), Authenticated: true}})
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	url := "http://" + node.httpAuth.listenAddr()
+	request := func(subject string) string {
+		claims := jwt.MapClaims{"iat": time.Now().Unix(), "sub": subject}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		resp := rpcRequest(t, url, "Authorization", "Bearer "+token)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if resp := request("alice"); strings.Contains(resp, "error") {
+		t.Fatalf("first request failed: %s", resp)
+	}
+	if resp := request("bob"); strings.Contains(resp, "error") {
+		t.Fatalf("request of other subject failed: %s", resp)
+	}
+	if resp := request("alice"); !strings.Contains(resp, `"code":-32005`) {
+		t.Fatalf("wrong response for exhausted quota: %s", resp)
+	}
+}
+
+func TestNodeH2Endpoint(t *testing.T) {
+	node, err := New(&Config{H2Host: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	endpoint := node.H2Endpoint()
+	if !strings.HasPrefix(endpoint, "h2c://127.0.0.1:") {
+		t.Fatalf("wrong endpoint %q", endpoint)
+	}
+	client, err := rpc.DialContext(context.Background(), endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := modules["rpc"]; !ok {
+		t.Errorf("rpc module missing: %v", modules)
+	}
+}
+
+func TestNodeH2VirtualHosts(t *testing.T) {
+	node, err := New(&Config{H2Host: "127.0.0.1", HTTPVirtualHosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	// Dial the listener directly, presenting the tested host in the request
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	for _, tt := range []struct {
+		host string
+		code int
+	}{
+		{"127.0.0.1", http.StatusOK},
+		{"localhost", http.StatusOK},
+		{"evil.example.com", http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+node.h2.listenAddr(), strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`))
+		req.Host = tt.host
+		req.Header.Set("content-type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.host, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("%s: status mismatch: have %d, want %d", tt.host, resp.StatusCode, tt.code)
+		}
+	}
+}
+
 func createNode(t *testing.T, httpPort, wsPort int) *Node {
 	conf := &Config{
 		HTTPHost: "127.0.0.1",
@@ -38,16 +38,22 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
-	prefix             string // path prefix on which to mount http handler
-	jwtSecret          []byte // optional JWT secret
+	prefix             string           // path prefix on which to mount http handler
+	jwtSecret          []byte           // optional JWT secret
+	middlewares        []rpc.Middleware // optional call interceptors
+	batchItemLimit     int              // maximum number of requests in a batch
+	batchResponseLimit int              // maximum response size of a batch
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins   []string
-	Modules   []string
-	prefix    string // path prefix on which to mount ws handler
-	jwtSecret []byte // optional JWT secret
+	Origins            []string
+	Modules            []string
+	prefix             string           // path prefix on which to mount ws handler
+	jwtSecret          []byte           // optional JWT secret
+	middlewares        []rpc.Middleware // optional call interceptors
+	batchItemLimit     int              // maximum number of requests in a batch
+	batchResponseLimit int              // maximum response size of a batch
 }
 
 type rpcHandler struct {
@@ -187,8 +193,9 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if ws != nil && isWebsocket(r) {
 		if checkPath(r, h.wsConfig.prefix) {
 			ws.ServeHTTP(w, r)
+			return
 		}
-		return
+		// Websocket requests to other paths may be served by registered handlers.
 	}
 	// if http-rpc is enabled, try to serve request
 	rpc := h.httpHandler.Load().(*rpcHandler)
@@ -284,6 +291,8 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	srv.Use(config.middlewares...)
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseLimit)
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
 		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
@@ -315,6 +324,8 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	srv.Use(config.middlewares...)
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseLimit)
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
 		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
@@ -464,7 +475,9 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		// Websocket upgrades need to hijack the connection, which the gzip
+		// writer doesn't support.
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || isWebsocket(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -485,6 +498,9 @@ type ipcServer struct {
 	log      log.Logger
 	endpoint string
 
+	batchItemLimit     int // maximum number of requests in a batch
+	batchResponseLimit int // maximum response size of a batch
+
 	mu       sync.Mutex
 	listener net.Listener
 	srv      *rpc.Server
@@ -507,6 +523,7 @@ func (is *ipcServer) start(apis []rpc.API) error {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
 		return err
 	}
+	srv.SetBatchLimits(is.batchItemLimit, is.batchResponseLimit)
 	is.log.Info("IPC endpoint opened", "url", is.endpoint)
 	is.listener, is.srv = listener, srv
 	return nil
@@ -526,6 +543,99 @@ func (is *ipcServer) stop() error {
 	return err
 }
 
+// h2Config is the JSON-RPC/HTTP2 streaming configuration.
+type h2Config struct {
+	Modules            []string
+	Vhosts             []string
+	middlewares        []rpc.Middleware // optional call interceptors
+	batchItemLimit     int              // maximum number of requests in a batch
+	batchResponseLimit int              // maximum response size of a batch
+}
+
+// h2Server serves JSON-RPC over HTTP/2 streams on a dedicated listener.
+// Connections are accepted in cleartext (h2c).
+type h2Server struct {
+	log log.Logger
+
+	mu       sync.Mutex
+	endpoint string
+	listener net.Listener
+	server   *http.Server
+	srv      *rpc.Server
+}
+
+func newH2Server(log log.Logger) *h2Server {
+	return &h2Server{log: log}
+}
+
+// setListenAddr configures the listening address of the server.
+func (h *h2Server) setListenAddr(host string, port int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.endpoint = fmt.Sprintf("%s:%d", host, port)
+}
+
+// listenAddr returns the listening address of the server.
+func (h *h2Server) listenAddr() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.listener != nil {
+		return h.listener.Addr().String()
+	}
+	return h.endpoint
+}
+
+// start registers the APIs and starts serving.
+func (h *h2Server) start(apis []rpc.API, config h2Config) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.endpoint == "" || h.listener != nil {
+		return nil // already running or not configured
+	}
+	srv := rpc.NewServer()
+	if err := RegisterApis(apis, config.Modules, srv); err != nil {
+		return err
+	}
+	srv.Use(config.middlewares...)
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseLimit)
+
+	listener, err := net.Listen("tcp", h.endpoint)
+	if err != nil {
+		srv.Stop()
+		return err
+	}
+	// The host check is done for every stream, as cleartext HTTP/2 connections
+	// bypass any handler in front of the h2c upgrade. Streams are long-lived, so
+	// only the request header is subject to a timeout.
+	h.server = &http.Server{
+		Handler:           rpc.NewH2CHandler(newVHostHandler(config.Vhosts, srv.HTTP2StreamHandler())),
+		ReadHeaderTimeout: rpc.DefaultHTTPTimeouts.ReadTimeout,
+		IdleTimeout:       rpc.DefaultHTTPTimeouts.IdleTimeout,
+	}
+	h.listener, h.srv = listener, srv
+	go h.server.Serve(listener)
+	h.log.Info("HTTP/2 streaming RPC enabled", "url", "h2c://"+listener.Addr().String())
+	return nil
+}
+
+// stop shuts down the server and all streams.
+func (h *h2Server) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.listener == nil {
+		return // not running
+	}
+	// Stopping the RPC server ends the streams, so the HTTP server can shut down.
+	h.srv.Stop()
+	h.server.Shutdown(context.Background())
+	h.listener.Close()
+	h.log.Info("HTTP/2 streaming RPC stopped", "endpoint", h.listener.Addr())
+	h.listener, h.server, h.srv = nil, nil, nil
+}
+
 // RegisterApis checks the given modules' availability, generates an allowlist based on the allowed modules,
 // and then registers all of the APIs exposed by the services.
 func RegisterApis(apis []rpc.API, modules []string, srv *rpc.Server) error {
@@ -19,6 +19,7 @@ package node
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -419,3 +420,57 @@ func TestJWT(t *testing.T) {
 	}
 	srv.stop()
 }
+
+// This test checks that authenticated clients are rate limited by JWT subject.
+func TestRateLimitJWTSubject(t *testing.T) {
+	var secret = []byte("secret")
+	limiter := rpc.NewRateLimiter(rpc.RateLimitConfig{DailyQuota: 1})
+	srv := createAndStartServer(t, &httpConfig{jwtSecret: secret, middlewares: []rpc.Middleware{limiter}}, false, nil)
+	defer srv.stop()
+	url := fmt.Sprintf("http://%v", srv.listenAddr())
+
+	request := func(subject string) string {
+		claims := jwt.MapClaims{"iat": time.Now().Unix(), "sub": subject}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		resp := rpcRequest(t, url, "Authorization", "Bearer "+token)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if resp := request("alice"); strings.Contains(resp, "error") {
+		t.Fatalf("first request failed: %s", resp)
+	}
+	if resp := request("bob"); strings.Contains(resp, "error") {
+		t.Fatalf("request of other subject failed: %s", resp)
+	}
+	if resp := request("alice"); !strings.Contains(resp, `"code":-32005`) {
+		t.Fatalf("wrong response for exhausted quota: %s", resp)
+	}
+}
+
+// This test checks that the batch limits are applied to the HTTP endpoint.
+func TestHTTPBatchLimits(t *testing.T) {
+	srv := createAndStartServer(t, &httpConfig{batchItemLimit: 2}, false, nil)
+	defer srv.stop()
+
+	batch := func(n int) string {
+		reqs := make([]string, n)
+		for i := range reqs {
+			reqs[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"rpc_modules"}`, i)
+		}
+		body := strings.NewReader("[" + strings.Join(reqs, ",") + "]")
+		resp, err := http.Post(fmt.Sprintf("http://%v", srv.listenAddr()), "application/json", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+	if resp := batch(2); strings.Contains(resp, "error") {
+		t.Fatalf("batch within limit failed: %s", resp)
+	}
+	if resp := batch(3); strings.Count(resp, `"message":"batch too large"`) != 3 {
+		t.Fatalf("wrong response for oversized batch: %s", resp)
+	}
+}
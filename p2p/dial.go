@@ -79,6 +79,8 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errBanned           = errors.New("node is banned")
+	errLowReputation    = errors.New("node reputation too low")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -134,6 +136,7 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	reputation     *reputation      // peer reputation, ignored if nil
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -242,7 +245,7 @@ loop:
 
 		select {
 		case node := <-nodesCh:
-			if err := d.checkDial(node); err != nil {
+			if err := d.checkDynDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
@@ -410,6 +413,24 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	return nil
 }
 
+// checkDynDial returns an error if node n should not be dialed dynamically. On top
+// of checkDial, it skips banned and badly behaving nodes. Static nodes are exempt
+// from these checks because they were explicitly requested.
+func (d *dialScheduler) checkDynDial(n *enode.Node) error {
+	if err := d.checkDial(n); err != nil {
+		return err
+	}
+	if d.reputation != nil {
+		if d.reputation.banned(n.ID()) {
+			return errBanned
+		}
+		if d.reputation.score(n.ID()) < minDialScore {
+			return errLowReputation
+		}
+	}
+	return nil
+}
+
 // startStaticDials starts n static dial tasks.
 func (d *dialScheduler) startStaticDials(n int) (started int) {
 	for started = 0; started < n && len(d.staticPool) > 0; started++ {
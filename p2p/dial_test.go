@@ -152,6 +152,38 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that banned and badly behaving nodes are not dialed.
+func TestDialSchedReputation(t *testing.T) {
+	t.Parallel()
+
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.0.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "127.0.0.3:30303"),
+	}
+	config := dialConfig{
+		reputation:     newReputation(db, new(mclock.Simulated), log.Root()),
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+	}
+	config.reputation.report(nodes[0].ID(), ReputationInvalidTx)
+	config.reputation.report(nodes[0].ID(), ReputationInvalidTx)
+	db.UpdateBanExpiry(nodes[1].ID(), time.Now().Add(time.Hour))
+
+	runDialTest(t, config, []dialTestRound{
+		{
+			discovered:   nodes,
+			wantNewDials: nodes[2:],
+		},
+		{
+			succeeded: []enode.ID{nodes[2].ID()},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,509 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	topicAdLifetime       = 15 * time.Minute // how long a registration stays in the topic table
+	topicQueueLimit       = 100              // max registrations per topic
+	topicTableLimit       = 5000             // max registrations across all topics
+	topicRegWindow        = 10 * time.Second // time after the wait time during which a ticket is valid
+	topicQueryResultLimit = 16               // applies in TOPICQUERY handler
+
+	topicRegistrarCount = 8                // number of registrars an advertised topic is placed at
+	topicRegAttempts    = 3                // ticket rounds per registrar before giving up on it
+	topicRegRetryDelay  = 30 * time.Second // delay before retrying when no registrar accepted the topic
+	topicSearchInterval = 10 * time.Second // delay between topic search rounds
+)
+
+var (
+	errInvalidTopic   = errors.New("invalid topic")
+	errInvalidTicket  = errors.New("invalid ticket")
+	errTicketMismatch = errors.New("ticket issued to different node")
+	errTicketEarly    = errors.New("ticket used before wait time")
+	errTicketExpired  = errors.New("ticket expired")
+	errTicketWait     = errors.New("ticket wait time too long")
+	errTopicFull      = errors.New("topic queue full")
+	errTopicRejected  = errors.New("topic registration rejected")
+)
+
+// Topic identifies a service advertised through discv5 topic registration. It is
+// the keccak256 hash of the topic name, and also determines where in the DHT the
+// topic is registered.
+type Topic [32]byte
+
+// NewTopic creates the topic identifier for the given name.
+func NewTopic(name string) Topic {
+	return Topic(crypto.Keccak256Hash([]byte(name)))
+}
+
+// String returns the topic in hex.
+func (tp Topic) String() string {
+	return fmt.Sprintf("%x", tp[:])
+}
+
+func topicFromBytes(b []byte) (tp Topic, err error) {
+	if len(b) != len(tp) {
+		return tp, errInvalidTopic
+	}
+	copy(tp[:], b)
+	return tp, nil
+}
+
+// topicAd is a registration in the topic table.
+type topicAd struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+}
+
+// topicTable is the registrar side store of topic advertisements. Every topic has
+// a bounded queue of registrations, with the total number of registrations also
+// bounded. Registrants are admitted through tickets: the wait time of a ticket is
+// the time until a slot frees up for the registrant.
+//
+// The table is only accessed from the dispatch goroutine.
+type topicTable struct {
+	clock  mclock.Clock
+	queues map[Topic][]*topicAd // registrations in order of expiry
+	count  int
+}
+
+func newTopicTable(clock mclock.Clock) *topicTable {
+	return &topicTable{clock: clock, queues: make(map[Topic][]*topicAd)}
+}
+
+// expire drops all registrations which have run out.
+func (tab *topicTable) expire() {
+	now := tab.clock.Now()
+	for topic, queue := range tab.queues {
+		i := 0
+		for i < len(queue) && queue[i].expires <= now {
+			i++
+		}
+		tab.count -= i
+		if i == len(queue) {
+			delete(tab.queues, topic)
+		} else {
+			tab.queues[topic] = queue[i:]
+		}
+	}
+}
+
+// waitTime returns how long the given node has to wait before it can register
+// for the topic.
+func (tab *topicTable) waitTime(topic Topic, id enode.ID) time.Duration {
+	tab.expire()
+
+	now := tab.clock.Now()
+	queue := tab.queues[topic]
+	for _, ad := range queue {
+		if ad.node.ID() == id {
+			return time.Duration(ad.expires - now)
+		}
+	}
+	if len(queue) >= topicQueueLimit {
+		return time.Duration(queue[0].expires - now)
+	}
+	if tab.count >= topicTableLimit {
+		next := mclock.AbsTime(math.MaxInt64)
+		for _, queue := range tab.queues {
+			if queue[0].expires < next {
+				next = queue[0].expires
+			}
+		}
+		return time.Duration(next - now)
+	}
+	return 0
+}
+
+// register adds a registration of node for the topic if there is room for it.
+func (tab *topicTable) register(topic Topic, node *enode.Node) bool {
+	if tab.waitTime(topic, node.ID()) > 0 {
+		return false
+	}
+	tab.queues[topic] = append(tab.queues[topic], &topicAd{
+		node:    node,
+		expires: tab.clock.Now().Add(topicAdLifetime),
+	})
+	tab.count++
+	return true
+}
+
+// nodes returns up to limit random nodes registered for the topic.
+func (tab *topicTable) nodes(topic Topic, limit int) []*enode.Node {
+	tab.expire()
+
+	queue := tab.queues[topic]
+	nodes := make([]*enode.Node, 0, min(limit, len(queue)))
+	for _, i := range rand.Perm(len(queue)) {
+		if len(nodes) >= limit {
+			break
+		}
+		nodes = append(nodes, queue[i].node)
+	}
+	return nodes
+}
+
+// topicTicket is the content of a ticket issued by a registrar. Tickets are opaque
+// to the registrant, they are authenticated by the registrar's ticket key.
+type topicTicket struct {
+	Topic  Topic
+	Node   enode.ID
+	IP     net.IP
+	Issued uint64 // registrar clock at issuance
+	Wait   uint64 // wait time in seconds
+}
+
+// ticketEnvelope is the wire encoding of a ticket.
+type ticketEnvelope struct {
+	Content []byte
+	MAC     []byte
+}
+
+// encodeTicket encodes and authenticates a ticket.
+func (t *UDPv5) encodeTicket(ticket *topicTicket) []byte {
+	content, _ := rlp.EncodeToBytes(ticket)
+	mac := hmac.New(sha256.New, t.ticketKey[:])
+	mac.Write(content)
+	enc, _ := rlp.EncodeToBytes(&ticketEnvelope{Content: content, MAC: mac.Sum(nil)})
+	return enc
+}
+
+// decodeTicket authenticates and decodes a ticket issued by the local node.
+func (t *UDPv5) decodeTicket(data []byte) (*topicTicket, error) {
+	var env ticketEnvelope
+	if err := rlp.DecodeBytes(data, &env); err != nil {
+		return nil, errInvalidTicket
+	}
+	mac := hmac.New(sha256.New, t.ticketKey[:])
+	mac.Write(env.Content)
+	if !hmac.Equal(mac.Sum(nil), env.MAC) {
+		return nil, errInvalidTicket
+	}
+	ticket := new(topicTicket)
+	if err := rlp.DecodeBytes(env.Content, ticket); err != nil {
+		return nil, errInvalidTicket
+	}
+	return ticket, nil
+}
+
+// handleRequestTicket issues a ticket for the requested topic.
+func (t *UDPv5) handleRequestTicket(p *v5wire.RequestTicket, fromID enode.ID, fromAddr *net.UDPAddr) {
+	topic, err := topicFromBytes(p.Topic)
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	wait := uint64(math.Ceil(t.topics.waitTime(topic, fromID).Seconds()))
+	ticket := t.encodeTicket(&topicTicket{
+		Topic:  topic,
+		Node:   fromID,
+		IP:     fromAddr.IP,
+		Issued: uint64(t.clock.Now()),
+		Wait:   wait,
+	})
+	t.sendResponse(fromID, fromAddr, &v5wire.Ticket{ReqID: p.ReqID, Ticket: ticket, WaitTime: uint(wait)})
+}
+
+// handleRegtopic registers the sender in the topic table if its ticket is valid.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	err := t.registerTopicAd(p, fromID, fromAddr)
+	if err != nil {
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	}
+	t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Registered: err == nil})
+}
+
+func (t *UDPv5) registerTopicAd(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) error {
+	ticket, err := t.decodeTicket(p.Ticket)
+	if err != nil {
+		return err
+	}
+	if ticket.Node != fromID || !ticket.IP.Equal(fromAddr.IP) {
+		return errTicketMismatch
+	}
+	var (
+		now   = t.clock.Now()
+		ready = mclock.AbsTime(ticket.Issued).Add(time.Duration(ticket.Wait) * time.Second)
+	)
+	if now < ready {
+		return errTicketEarly
+	}
+	if now > ready.Add(topicRegWindow) {
+		return errTicketExpired
+	}
+	if p.ENR == nil {
+		return errors.New("missing node record")
+	}
+	node, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return err
+	}
+	if node.ID() != fromID {
+		return errors.New("node record of different node")
+	}
+	if !t.topics.register(ticket.Topic, node) {
+		return errTopicFull
+	}
+	return nil
+}
+
+// handleTopicQuery returns the nodes registered for a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	topic, err := topicFromBytes(p.Topic)
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	var nodes []*enode.Node
+	for _, n := range t.topics.nodes(topic, topicQueryResultLimit) {
+		if netutil.CheckRelayIP(fromAddr.IP, n.IP()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// requestTicket calls REQUESTTICKET on a node and waits for the ticket.
+func (t *UDPv5) requestTicket(n *enode.Node, topic Topic) (*v5wire.Ticket, error) {
+	resp := t.call(n, v5wire.TicketMsg, &v5wire.RequestTicket{Topic: topic[:]})
+	defer t.callDone(resp)
+
+	select {
+	case ticket := <-resp.ch:
+		return ticket.(*v5wire.Ticket), nil
+	case err := <-resp.err:
+		return nil, err
+	}
+}
+
+// regtopic calls REGTOPIC on a node and waits for the confirmation.
+func (t *UDPv5) regtopic(n *enode.Node, ticket []byte) (bool, error) {
+	req := &v5wire.Regtopic{Ticket: ticket, ENR: t.Self().Record()}
+	resp := t.call(n, v5wire.RegconfirmationMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case conf := <-resp.ch:
+		return conf.(*v5wire.Regconfirmation).Registered, nil
+	case err := <-resp.err:
+		return false, err
+	}
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for the registered nodes.
+func (t *UDPv5) topicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.call(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic[:]})
+	return t.waitForNodes(resp, nil)
+}
+
+// RegisterTopic starts advertising the local node under the given topic. The node
+// is registered at the registrars closest to the topic and the registrations are
+// renewed until StopRegisterTopic is called.
+func (t *UDPv5) RegisterTopic(topic Topic) {
+	t.topicRegLock.Lock()
+	defer t.topicRegLock.Unlock()
+
+	if _, ok := t.topicRegs[topic]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	t.topicRegs[topic] = cancel
+	go t.topicRegLoop(ctx, topic)
+}
+
+// StopRegisterTopic stops advertising the local node under the given topic.
+// Existing registrations expire on their own.
+func (t *UDPv5) StopRegisterTopic(topic Topic) {
+	t.topicRegLock.Lock()
+	defer t.topicRegLock.Unlock()
+
+	if cancel, ok := t.topicRegs[topic]; ok {
+		cancel()
+		delete(t.topicRegs, topic)
+	}
+}
+
+// topicRegLoop keeps the topic registered at the registrars closest to it.
+func (t *UDPv5) topicRegLoop(ctx context.Context, topic Topic) {
+	for {
+		var (
+			wg         sync.WaitGroup
+			registered int32
+			registrars = t.newLookup(ctx, enode.ID(topic)).run()
+		)
+		if len(registrars) > topicRegistrarCount {
+			registrars = registrars[:topicRegistrarCount]
+		}
+		for _, n := range registrars {
+			wg.Add(1)
+			go func(n *enode.Node) {
+				defer wg.Done()
+				if err := t.registerAt(ctx, n, topic); err != nil {
+					t.log.Trace("Topic registration failed", "topic", topic, "id", n.ID(), "err", err)
+					return
+				}
+				atomic.AddInt32(&registered, 1)
+			}(n)
+		}
+		wg.Wait()
+		t.log.Debug("Registered topic", "topic", topic, "registrars", registered)
+
+		// Renew the registrations before they expire, retry sooner if no registrar
+		// accepted the topic.
+		delay := topicAdLifetime * 3 / 4
+		if registered == 0 {
+			delay = topicRegRetryDelay
+		}
+		select {
+		case <-t.clock.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// registerAt registers the local node for the topic at the registrar n, waiting
+// out the tickets issued by it.
+func (t *UDPv5) registerAt(ctx context.Context, n *enode.Node, topic Topic) error {
+	for i := 0; i < topicRegAttempts; i++ {
+		ticket, err := t.requestTicket(n, topic)
+		if err != nil {
+			return err
+		}
+		wait := time.Duration(ticket.WaitTime) * time.Second
+		if wait > topicAdLifetime {
+			return errTicketWait
+		}
+		if wait > 0 {
+			select {
+			case <-t.clock.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		ok, err := t.regtopic(n, ticket.Ticket)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return errTopicRejected
+}
+
+// TopicSearch returns an iterator over the nodes advertising the given topic. The
+// iterator repeatedly queries the registrars closest to the topic and yields each
+// node only once.
+func (t *UDPv5) TopicSearch(topic Topic) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	it := &topicIterator{
+		ctx:    ctx,
+		cancel: cancel,
+		nodes:  make(chan *enode.Node),
+	}
+	go it.loop(t, topic)
+	return it
+}
+
+// topicIterator is the iterator returned by TopicSearch.
+type topicIterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	nodes  chan *enode.Node
+	cur    *enode.Node
+}
+
+// Next blocks until a node advertising the topic is found or the iterator is closed.
+func (it *topicIterator) Next() bool {
+	select {
+	case n := <-it.nodes:
+		it.cur = n
+		return true
+	case <-it.ctx.Done():
+		it.cur = nil
+		return false
+	}
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	return it.cur
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
+
+func (it *topicIterator) loop(t *UDPv5, topic Topic) {
+	seen := make(map[enode.ID]struct{})
+	for {
+		if t.tab.len() == 0 {
+			select {
+			case <-t.tab.refresh():
+			case <-it.ctx.Done():
+				return
+			}
+		}
+		for _, registrar := range t.newLookup(it.ctx, enode.ID(topic)).run() {
+			nodes, err := t.topicQuery(registrar, topic)
+			if errors.Is(err, errClosed) {
+				return
+			}
+			for _, n := range nodes {
+				if _, ok := seen[n.ID()]; ok || n.ID() == t.Self().ID() {
+					continue
+				}
+				seen[n.ID()] = struct{}{}
+				select {
+				case it.nodes <- n:
+				case <-it.ctx.Done():
+					return
+				}
+			}
+		}
+		select {
+		case <-t.clock.After(topicSearchInterval):
+		case <-it.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestTopicTableLimits(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		tab   = newTopicTable(clock)
+		topic = NewTopic("test")
+	)
+	for i := 0; i < topicQueueLimit; i++ {
+		if !tab.register(topic, unwrapNode(nodeAtDistance(enode.ID{}, 256, net.IP{127, 0, 0, 1}))) {
+			t.Fatalf("registration %d rejected", i)
+		}
+		clock.Run(time.Second)
+	}
+	// The queue is full, new registrants have to wait for the oldest ad to expire.
+	id := enode.ID{1}
+	if wait := tab.waitTime(topic, id); wait != topicAdLifetime-topicQueueLimit*time.Second {
+		t.Fatalf("wrong wait time: %v", wait)
+	}
+	// Other topics are not affected.
+	if wait := tab.waitTime(NewTopic("other"), id); wait != 0 {
+		t.Fatalf("wrong wait time for other topic: %v", wait)
+	}
+	clock.Run(topicAdLifetime - topicQueueLimit*time.Second)
+	if wait := tab.waitTime(topic, id); wait != 0 {
+		t.Fatalf("wrong wait time after expiry: %v", wait)
+	}
+	if len(tab.queues[topic]) != topicQueueLimit-1 || tab.count != topicQueueLimit-1 {
+		t.Fatalf("wrong table size after expiry: %d/%d", len(tab.queues[topic]), tab.count)
+	}
+	// Registered nodes have to wait for their own ad to expire.
+	n := tab.queues[topic][0].node
+	if wait := tab.waitTime(topic, n.ID()); wait != time.Second {
+		t.Fatalf("wrong wait time for registered node: %v", wait)
+	}
+	if got := tab.nodes(topic, topicQueryResultLimit); len(got) != topicQueryResultLimit {
+		t.Fatalf("wrong number of nodes: %d", len(got))
+	}
+}
+
+// This test checks the registrar side of topic registration.
+func TestUDPv5_topicRegistration(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("test")
+		remote = test.getNode(test.remotekey, test.remoteaddr)
+		ticket []byte
+	)
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte("ticket"), Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.WaitTime != 0 {
+			t.Errorf("wrong wait time %d", p.WaitTime)
+		}
+		ticket = p.Ticket
+	})
+	// Tampered tickets are rejected.
+	forged := append([]byte(nil), ticket...)
+	forged[len(forged)-1]++
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("forged"), Ticket: forged, ENR: remote.Node().Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Registered {
+			t.Error("forged ticket accepted")
+		}
+	})
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("reg"), Ticket: ticket, ENR: remote.Node().Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !p.Registered {
+			t.Error("registration rejected")
+		}
+	})
+	// The registered node is returned by TOPICQUERY.
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte("query"), Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Nodes, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if len(p.Nodes) != 1 || p.Nodes[0].Seq() != remote.Node().Seq() {
+			t.Fatalf("wrong nodes in response: %v", p.Nodes)
+		}
+	})
+	// A second registration has to wait for the first one to expire.
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte("ticket2"), Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if want := uint(topicAdLifetime / time.Second); p.WaitTime > want || p.WaitTime < want-1 {
+			t.Errorf("wrong wait time %d", p.WaitTime)
+		}
+	})
+}
+
+// Real sockets, real crypto: this test checks that a registered topic can be
+// found by another node.
+func TestUDPv5_topicSearchE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 3
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	topic := NewTopic("test")
+	if err := nodes[1].registerAt(context.Background(), nodes[0].Self(), topic); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	it := nodes[2].TopicSearch(topic)
+	defer it.Close()
+
+	found := make(chan *enode.Node, 1)
+	go func() {
+		if it.Next() {
+			found <- it.Node()
+		}
+	}()
+	select {
+	case n := <-found:
+		if n.ID() != nodes[1].Self().ID() {
+			t.Fatalf("wrong node found: %v", n.ID())
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("topic search timed out")
+	}
+}
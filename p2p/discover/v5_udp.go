@@ -76,6 +76,12 @@ type UDPv5 struct {
 	trlock     sync.Mutex
 	trhandlers map[string]TalkRequestHandler
 
+	// topic advertisement
+	topics       *topicTable // registrations stored by us, accessed by dispatch
+	ticketKey    [32]byte    // authenticates the tickets issued by us
+	topicRegLock sync.Mutex
+	topicRegs    map[Topic]context.CancelFunc // topics we advertise
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
@@ -149,6 +155,8 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		validSchemes: cfg.ValidSchemes,
 		clock:        cfg.Clock,
 		trhandlers:   make(map[string]TalkRequestHandler),
+		topics:       newTopicTable(cfg.Clock),
+		topicRegs:    make(map[Topic]context.CancelFunc),
 		// channels into dispatch
 		packetInCh:    make(chan ReadPacket, 1),
 		readNextCh:    make(chan struct{}, 1),
@@ -164,6 +172,7 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		closeCtx:       closeCtx,
 		cancelCloseCtx: cancelCloseCtx,
 	}
+	crand.Read(t.ticketKey[:])
 	tab, err := newTable(t, t.db, cfg.Bootnodes, cfg.Log)
 	if err != nil {
 		return nil, err
@@ -714,6 +723,16 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr *net.UDPAddr)
 		t.handleTalkRequest(p, fromID, fromAddr)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.RequestTicket:
+		t.handleRequestTicket(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Regconfirmation:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.handleTopicQuery(p, fromID, fromAddr)
 	}
 }
 
@@ -37,11 +37,13 @@ import (
 
 // Keys in the node database.
 const (
-	dbVersionKey   = "version" // Version of the database to flush if changes
-	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
-	dbLocalPrefix  = "local:"
-	dbDiscoverRoot = "v4"
-	dbDiscv5Root   = "v5"
+	dbVersionKey    = "version" // Version of the database to flush if changes
+	dbNodePrefix    = "n:"      // Identifier to prefix node entries with
+	dbLocalPrefix   = "local:"
+	dbBanPrefix     = "ban:"     // Identifier to prefix temporary node bans with
+	dbTrafficPrefix = "traffic:" // Identifier to prefix accumulated node traffic with
+	dbDiscoverRoot  = "v4"
+	dbDiscv5Root    = "v5"
 
 	// These fields are stored per ID and IP, the full key is "n:<ID>:v4:<IP>:findfail".
 	// Use nodeItemKey to create those keys.
@@ -56,9 +58,10 @@ const (
 )
 
 const (
-	dbNodeExpiration = 24 * time.Hour // Time after which an unseen node should be dropped.
-	dbCleanupCycle   = time.Hour      // Time period for running the expiration task.
-	dbVersion        = 9
+	dbNodeExpiration    = 24 * time.Hour      // Time after which an unseen node should be dropped.
+	dbTrafficExpiration = 30 * 24 * time.Hour // Time after which the traffic of an unseen node is dropped.
+	dbCleanupCycle      = time.Hour           // Time period for running the expiration task.
+	dbVersion           = 9
 )
 
 var (
@@ -73,6 +76,8 @@ type DB struct {
 	lvl    *leveldb.DB   // Interface to the database itself
 	runner sync.Once     // Ensures we can start at most one expirer
 	quit   chan struct{} // Channel to signal the expiring thread to stop
+
+	trafficLock sync.Mutex // Serializes updates of the traffic counters
 }
 
 // OpenDB opens a node database for storing and retrieving infos about known peers in the
@@ -195,6 +200,17 @@ func localItemKey(id ID, field string) []byte {
 	return key
 }
 
+// banKey returns the database key of a node ban. Bans are kept outside of the node
+// prefix so they survive the expiration of the node's discovery data.
+func banKey(id ID) []byte {
+	return append([]byte(dbBanPrefix), id[:]...)
+}
+
+// trafficKey returns the database key of the traffic accumulated with a node.
+func trafficKey(id ID) []byte {
+	return append([]byte(dbTrafficPrefix), id[:]...)
+}
+
 // fetchInt64 retrieves an integer associated with a particular key.
 func (db *DB) fetchInt64(key []byte) int64 {
 	blob, err := db.lvl.Get(key, nil)
@@ -315,6 +331,8 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireBans()
+			db.expireTraffic()
 		case <-db.quit:
 			return
 		}
@@ -360,6 +378,94 @@ func (db *DB) expireNodes() {
 	}
 }
 
+// expireBans deletes all node bans which have already run out.
+func (db *DB) expireBans() {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	now := time.Now().Unix()
+	for it.Next() {
+		if expiry, _ := binary.Varint(it.Value()); expiry <= now {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
+// NodeTraffic is the traffic exchanged with a node over all past connections.
+type NodeTraffic struct {
+	Ingress        uint64 // Bytes received from the node
+	Egress         uint64 // Bytes sent to the node
+	IngressPackets uint64 // Messages received from the node
+	EgressPackets  uint64 // Messages sent to the node
+	Updated        uint64 // Unix time of the last update
+}
+
+// expireTraffic deletes the traffic records of nodes which haven't been connected
+// for a long time.
+func (db *DB) expireTraffic() {
+	db.trafficLock.Lock()
+	defer db.trafficLock.Unlock()
+
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbTrafficPrefix)), nil)
+	defer it.Release()
+
+	threshold := uint64(time.Now().Add(-dbTrafficExpiration).Unix())
+	for it.Next() {
+		var t NodeTraffic
+		if err := rlp.DecodeBytes(it.Value(), &t); err != nil || t.Updated < threshold {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
+// Traffic retrieves the traffic accumulated with a node.
+func (db *DB) Traffic(id ID) NodeTraffic {
+	var t NodeTraffic
+	if blob, err := db.lvl.Get(trafficKey(id), nil); err == nil {
+		rlp.DecodeBytes(blob, &t)
+	}
+	return t
+}
+
+// AddTraffic adds the traffic of a finished connection to the total accumulated
+// with a node.
+func (db *DB) AddTraffic(id ID, t NodeTraffic) error {
+	db.trafficLock.Lock()
+	defer db.trafficLock.Unlock()
+
+	total := db.Traffic(id)
+	total.Ingress += t.Ingress
+	total.Egress += t.Egress
+	total.IngressPackets += t.IngressPackets
+	total.EgressPackets += t.EgressPackets
+	total.Updated = uint64(time.Now().Unix())
+
+	blob, err := rlp.EncodeToBytes(&total)
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(trafficKey(id), blob, nil)
+}
+
+// BanExpiry retrieves the time until which a node is banned. The zero time is
+// returned if the node was never banned.
+func (db *DB) BanExpiry(id ID) time.Time {
+	expiry := db.fetchInt64(banKey(id))
+	if expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(expiry, 0)
+}
+
+// UpdateBanExpiry bans a node until the given time. Passing the zero time lifts
+// the ban.
+func (db *DB) UpdateBanExpiry(id ID, expiry time.Time) error {
+	if expiry.IsZero() {
+		return db.lvl.Delete(banKey(id), nil)
+	}
+	return db.storeInt64(banKey(id), expiry.Unix())
+}
+
 // LastPingReceived retrieves the time of the last ping packet received from
 // a remote node.
 func (db *DB) LastPingReceived(id ID, ip net.IP) time.Time {
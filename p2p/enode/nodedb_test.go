@@ -22,8 +22,11 @@ import (
 	"net"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var keytestID = HexID("51232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
@@ -467,3 +470,84 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// This test checks that node bans are stored and expired independently of the
+// discovery data of the node.
+func TestDBBanExpiry(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		active  = ID{0x01}
+		expired = ID{0x02}
+		until   = time.Now().Add(time.Hour).Truncate(time.Second)
+	)
+	if exp := db.BanExpiry(active); !exp.IsZero() {
+		t.Fatalf("unexpected ban for unknown node: %v", exp)
+	}
+	db.UpdateBanExpiry(active, until)
+	db.UpdateBanExpiry(expired, time.Now().Add(-time.Minute))
+
+	db.expireNodes()
+	db.expireBans()
+
+	if exp := db.BanExpiry(active); !exp.Equal(until) {
+		t.Errorf("wrong ban expiry: have %v, want %v", exp, until)
+	}
+	if exp := db.BanExpiry(expired); !exp.IsZero() {
+		t.Errorf("expired ban still present: %v", exp)
+	}
+	db.UpdateBanExpiry(active, time.Time{})
+	if exp := db.BanExpiry(active); !exp.IsZero() {
+		t.Errorf("lifted ban still present: %v", exp)
+	}
+}
+
+func TestDBTraffic(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	id := ID{0x01}
+	db.AddTraffic(id, NodeTraffic{Ingress: 100, Egress: 10, IngressPackets: 2, EgressPackets: 1})
+	db.AddTraffic(id, NodeTraffic{Ingress: 50, Egress: 5, IngressPackets: 1, EgressPackets: 1})
+
+	have := db.Traffic(id)
+	if have.Ingress != 150 || have.Egress != 15 || have.IngressPackets != 3 || have.EgressPackets != 2 {
+		t.Fatalf("wrong accumulated traffic: %+v", have)
+	}
+	if have.Updated == 0 {
+		t.Fatal("update time not set")
+	}
+	// Stale records are removed by the expirer, fresh ones are kept.
+	db.expireTraffic()
+	if db.Traffic(id).Ingress != 150 {
+		t.Fatal("fresh traffic record expired")
+	}
+	blob, _ := rlp.EncodeToBytes(&NodeTraffic{Ingress: 1, Updated: 1})
+	db.lvl.Put(trafficKey(id), blob, nil)
+	db.expireTraffic()
+	if have := db.Traffic(id); have != (NodeTraffic{}) {
+		t.Fatalf("stale traffic record not expired: %+v", have)
+	}
+}
+
+func TestDBTrafficConcurrent(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		id = ID{0x01}
+		wg sync.WaitGroup
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db.AddTraffic(id, NodeTraffic{Ingress: 1, Egress: 2})
+		}()
+	}
+	wg.Wait()
+	if have := db.Traffic(id); have.Ingress != 50 || have.Egress != 100 {
+		t.Fatalf("lost traffic updates: %+v", have)
+	}
+}
@@ -139,6 +139,10 @@ type Tracker struct {
 	// the real networking RTT, we just need a number to compare peers with.
 	roundtrip time.Duration
 
+	// notify is set when the tracker is added to a set of trackers, to relay
+	// the measurements to the set's OnUpdate callback.
+	notify func(kind uint64, items int)
+
 	lock sync.RWMutex
 }
 
@@ -188,8 +192,18 @@ func roundCapacity(cap float64) int {
 // avoids assigning the peer retrievals that it won't be able to honour.
 func (t *Tracker) Update(kind uint64, elapsed time.Duration, items int) {
 	t.lock.Lock()
-	defer t.lock.Unlock()
+	t.update(kind, elapsed, items)
+	notify := t.notify
+	t.lock.Unlock()
+
+	// The callback may do I/O, so it runs without holding the lock.
+	if notify != nil {
+		notify(kind, items)
+	}
+}
 
+// update applies a measurement to the capacity values. The lock must be held.
+func (t *Tracker) update(kind uint64, elapsed time.Duration, items int) {
 	// If nothing was delivered (timeout / unavailable data), reduce throughput
 	// to minimum
 	if items == 0 {
@@ -238,6 +252,12 @@ type Trackers struct {
 	// purpose is to allow quicker tests. Don't use them in production.
 	OverrideTTLLimit time.Duration
 
+	// OnUpdate is an optional callback invoked after every measurement of a tracked
+	// peer. The number of items is zero for timeouts and unavailable data. It must
+	// be set before any trackers are added. It is invoked without holding any of
+	// the trackers' locks.
+	OnUpdate func(id string, kind uint64, items int)
+
 	log  log.Logger
 	lock sync.RWMutex
 }
@@ -263,6 +283,11 @@ func (t *Trackers) Track(id string, tracker *Tracker) error {
 		return errors.New("already tracking")
 	}
 	t.trackers[id] = tracker
+	if t.OnUpdate != nil {
+		tracker.lock.Lock()
+		tracker.notify = func(kind uint64, items int) { t.OnUpdate(id, kind, items) }
+		tracker.lock.Unlock()
+	}
 	t.detune()
 
 	return nil
@@ -458,9 +483,10 @@ func (t *Trackers) Capacity(id string, kind uint64, targetRTT time.Duration) int
 // track it explicitly outside.
 func (t *Trackers) Update(id string, kind uint64, elapsed time.Duration, items int) {
 	t.lock.RLock()
-	defer t.lock.RUnlock()
+	tracker := t.trackers[id]
+	t.lock.RUnlock()
 
-	if tracker := t.trackers[id]; tracker != nil {
+	if tracker != nil {
 		tracker.Update(kind, elapsed, items)
 	}
 }
@@ -16,7 +16,10 @@
 
 package msgrate
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestCapacityOverflow(t *testing.T) {
 	tracker := NewTracker(nil, 1)
@@ -26,3 +29,30 @@ func TestCapacityOverflow(t *testing.T) {
 		t.Fatalf("Negative: %v", int32(cap))
 	}
 }
+
+func TestTrackersOnUpdate(t *testing.T) {
+	type update struct {
+		id    string
+		kind  uint64
+		items int
+	}
+	var (
+		updates  []update
+		trackers = NewTrackers(nil)
+		tracker  = NewTracker(nil, 1)
+	)
+	trackers.OnUpdate = func(id string, kind uint64, items int) {
+		// The callback must be able to use the trackers.
+		tracker.Capacity(kind, time.Second)
+		trackers.Untrack("unknown")
+		updates = append(updates, update{id, kind, items})
+	}
+	trackers.Track("peer", tracker)
+
+	tracker.Update(1, 1, 10)
+	trackers.Update("peer", 2, 1, 0)
+
+	if len(updates) != 2 || updates[0] != (update{"peer", 1, 10}) || updates[1] != (update{"peer", 2, 0}) {
+		t.Fatalf("wrong updates: %v", updates)
+	}
+}
@@ -37,8 +37,9 @@ type Interface interface {
 	//
 	// protocol is "UDP" or "TCP". Some implementations allow setting
 	// a display name for the mapping. The mapping may be removed by
-	// the gateway when its lifetime ends.
-	AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error
+	// the gateway when its lifetime ends. AddMapping returns the external
+	// port assigned by the gateway, which may differ from the requested one.
+	AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error)
 	DeleteMapping(protocol string, extport, intport int) error
 
 	// This method should return the external (Internet-facing)
@@ -49,6 +50,14 @@ type Interface interface {
 	String() string
 }
 
+// LossDetector is implemented by mechanisms which can tell when the gateway has lost
+// its port mappings, e.g. because it was restarted.
+type LossDetector interface {
+	// MappingsLost reports whether mappings were lost since the last call. Lost
+	// mappings should be requested again right away.
+	MappingsLost() bool
+}
+
 // Parse parses a NAT interface description.
 // The following formats are currently accepted.
 // Note that mechanism names are not case-sensitive.
@@ -59,6 +68,8 @@ type Interface interface {
 //     "upnp"               uses the Universal Plug and Play protocol
 //     "pmp"                uses NAT-PMP with an auto-detected gateway address
 //     "pmp:192.168.0.1"    uses NAT-PMP with the given gateway address
+//     "pcp"                uses the Port Control Protocol with an auto-detected gateway address
+//     "pcp:192.168.0.1"    uses the Port Control Protocol with the given gateway address
 func Parse(spec string) (Interface, error) {
 	var (
 		parts = strings.SplitN(spec, ":", 2)
@@ -85,6 +96,8 @@ func Parse(spec string) (Interface, error) {
 		return UPnP(), nil
 	case "pmp", "natpmp", "nat-pmp":
 		return PMP(ip), nil
+	case "pcp":
+		return PCP(ip), nil
 	default:
 		return nil, fmt.Errorf("unknown mechanism %q", parts[0])
 	}
@@ -104,10 +117,10 @@ func Map(m Interface, c <-chan struct{}, protocol string, extport, intport int,
 		log.Debug("Deleting port mapping")
 		m.DeleteMapping(protocol, extport, intport)
 	}()
-	if err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
+	if p, err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
 		log.Debug("Couldn't add port mapping", "err", err)
 	} else {
-		log.Info("Mapped network port")
+		log.Info("Mapped network port", "mapped", p)
 	}
 	for {
 		select {
@@ -117,7 +130,7 @@ func Map(m Interface, c <-chan struct{}, protocol string, extport, intport int,
 			}
 		case <-refresh.C:
 			log.Trace("Refreshing port mapping")
-			if err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
+			if _, err := m.AddMapping(protocol, extport, intport, name, mapTimeout); err != nil {
 				log.Debug("Couldn't add port mapping", "err", err)
 			}
 			refresh.Reset(mapTimeout)
@@ -135,18 +148,21 @@ func (n ExtIP) String() string              { return fmt.Sprintf("ExtIP(%v)", ne
 
 // These do nothing.
 
-func (ExtIP) AddMapping(string, int, int, string, time.Duration) error { return nil }
-func (ExtIP) DeleteMapping(string, int, int) error                     { return nil }
+func (ExtIP) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+	return uint16(extport), nil
+}
+func (ExtIP) DeleteMapping(string, int, int) error { return nil }
 
 // Any returns a port mapper that tries to discover any supported
 // mechanism on the local network.
 func Any() Interface {
 	// TODO: attempt to discover whether the local machine has an
 	// Internet-class address. Return ExtIP in this case.
-	return startautodisc("UPnP or NAT-PMP", func() Interface {
-		found := make(chan Interface, 2)
+	return startautodisc("UPnP, NAT-PMP or PCP", func() Interface {
+		found := make(chan Interface, 3)
 		go func() { found <- discoverUPnP() }()
 		go func() { found <- discoverPMP() }()
+		go func() { found <- discoverPCP() }()
 		for i := 0; i < cap(found); i++ {
 			if c := <-found; c != nil {
 				return c
@@ -172,6 +188,16 @@ func PMP(gateway net.IP) Interface {
 	return startautodisc("NAT-PMP", discoverPMP)
 }
 
+// PCP returns a port mapper that uses the Port Control Protocol (RFC 6887).
+// The provided gateway address should be the IP of your router. If the given
+// gateway address is nil, PCP will attempt to auto-discover the router.
+func PCP(gateway net.IP) Interface {
+	if gateway != nil {
+		return newPCP(gateway, pcpPort)
+	}
+	return startautodisc("PCP", discoverPCP)
+}
+
 // autodisc represents a port mapping mechanism that is still being
 // auto-discovered. Calls to the Interface methods on this type will
 // wait until the discovery is done and then call the method on the
@@ -193,9 +219,9 @@ func startautodisc(what string, doit func() Interface) Interface {
 	return &autodisc{what: what, doit: doit}
 }
 
-func (n *autodisc) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error {
+func (n *autodisc) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
 	if err := n.wait(); err != nil {
-		return err
+		return 0, err
 	}
 	return n.found.AddMapping(protocol, extport, intport, name, lifetime)
 }
@@ -214,6 +240,16 @@ func (n *autodisc) ExternalIP() (net.IP, error) {
 	return n.found.ExternalIP()
 }
 
+func (n *autodisc) MappingsLost() bool {
+	n.mu.Lock()
+	found := n.found
+	n.mu.Unlock()
+	if d, ok := found.(LossDetector); ok {
+		return d.MappingsLost()
+	}
+	return false
+}
+
 func (n *autodisc) String() string {
 	n.mu.Lock()
 	defer n.mu.Unlock()
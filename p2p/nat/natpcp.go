@@ -0,0 +1,320 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	pcpPort     = 5351 // server port, shared with NAT-PMP
+	pcpVersion  = 2
+	pcpOpMap    = 1
+	pcpResponse = 0x80 // R bit of the opcode field
+
+	pcpHeaderSize = 24
+	pcpMapSize    = 36
+
+	pcpProtoTCP = 6
+	pcpProtoUDP = 17
+
+	pcpInitialTimeout = 250 * time.Millisecond
+	pcpMaxAttempts    = 6
+
+	// The external address is determined by mapping this port for a short time,
+	// unless another mapping reported it recently.
+	pcpProbePort      = 9
+	pcpProbeLifetime  = 2 * time.Minute
+	pcpExternalIPTime = 10 * time.Minute
+)
+
+var (
+	errPCPTimeout        = errors.New("PCP gateway did not respond")
+	errPCPVersion        = errors.New("gateway does not support PCP")
+	errPCPBadResponse    = errors.New("malformed PCP response")
+	errPCPIgnoreResponse = errors.New("unrelated PCP response")
+)
+
+// pcpResultCodes are the names of the PCP result codes, from RFC 6887 section 7.4.
+var pcpResultCodes = []string{
+	"SUCCESS", "UNSUPP_VERSION", "NOT_AUTHORIZED", "MALFORMED_REQUEST",
+	"UNSUPP_OPCODE", "UNSUPP_OPTION", "MALFORMED_OPTION", "NETWORK_FAILURE",
+	"NO_RESOURCES", "UNSUPP_PROTOCOL", "USER_EX_QUOTA", "CANNOT_PROVIDE_EXTERNAL",
+	"ADDRESS_MISMATCH", "EXCESSIVE_REMOTE_PEERS",
+}
+
+// pcpError is a failure result returned by a PCP gateway.
+type pcpError struct {
+	code     byte
+	lifetime uint32 // seconds the failure is expected to persist
+}
+
+func (e pcpError) Error() string {
+	name := fmt.Sprintf("result code %d", e.code)
+	if int(e.code) < len(pcpResultCodes) {
+		name = pcpResultCodes[e.code]
+	}
+	return fmt.Sprintf("PCP request failed: %s", name)
+}
+
+// pcpMapping is the result of a PCP MAP request.
+type pcpMapping struct {
+	lifetime uint32
+	epoch    uint32
+	extPort  uint16
+	extIP    net.IP
+}
+
+// pcp implements the MAP opcode of the Port Control Protocol.
+type pcp struct {
+	gw      net.IP
+	port    int
+	timeout time.Duration // initial retransmission timeout
+
+	// All mappings are created with the same nonce, which the gateway
+	// requires to renew or delete them.
+	nonce [12]byte
+
+	mu        sync.Mutex // serializes requests
+	lastEpoch uint32
+	lastSeen  time.Time
+	lost      bool      // set when the gateway lost its mappings
+	extIP     net.IP    // external address of the last successful mapping
+	extIPTime time.Time // time extIP was received
+}
+
+func newPCP(gw net.IP, port int) *pcp {
+	n := &pcp{gw: gw, port: port, timeout: pcpInitialTimeout}
+	rand.Read(n.nonce[:])
+	return n
+}
+
+func (n *pcp) String() string {
+	return fmt.Sprintf("PCP(%v)", n.gw)
+}
+
+// ExternalIP returns the external address reported with the last mapping. If there
+// is none, it creates a short-lived mapping for the discard port, because PCP has
+// no dedicated request for the address.
+func (n *pcp) ExternalIP() (net.IP, error) {
+	n.mu.Lock()
+	ip, received := n.extIP, n.extIPTime
+	n.mu.Unlock()
+	if ip != nil && time.Since(received) < pcpExternalIPTime {
+		return ip, nil
+	}
+	m, err := n.request(pcpProtoUDP, pcpProbePort, pcpProbePort, uint32(pcpProbeLifetime/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	n.request(pcpProtoUDP, pcpProbePort, 0, 0)
+	return m.extIP, nil
+}
+
+func (n *pcp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+	if lifetime <= 0 {
+		return 0, fmt.Errorf("lifetime must not be <= 0")
+	}
+	proto, err := pcpProtocol(protocol)
+	if err != nil {
+		return 0, err
+	}
+	m, err := n.request(proto, intport, extport, uint32(lifetime/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	return m.extPort, nil
+}
+
+// MappingsLost reports whether the gateway was restarted since the last call, which
+// loses all mappings.
+func (n *pcp) MappingsLost() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	lost := n.lost
+	n.lost = false
+	return lost
+}
+
+func (n *pcp) DeleteMapping(protocol string, extport, intport int) error {
+	proto, err := pcpProtocol(protocol)
+	if err != nil {
+		return err
+	}
+	// Mappings are deleted by requesting a lifetime of zero.
+	_, err = n.request(proto, intport, 0, 0)
+	return err
+}
+
+func pcpProtocol(protocol string) (byte, error) {
+	switch strings.ToUpper(protocol) {
+	case "TCP":
+		return pcpProtoTCP, nil
+	case "UDP":
+		return pcpProtoUDP, nil
+	default:
+		return 0, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+// request performs a MAP request, retransmitting it with exponential backoff
+// until the gateway responds.
+func (n *pcp) request(proto byte, intport, extport int, lifetime uint32) (*pcpMapping, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: n.gw, Port: n.port})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The client address in the request must match the source address of the
+	// packet, otherwise the gateway responds with ADDRESS_MISMATCH.
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	req := make([]byte, pcpHeaderSize+pcpMapSize)
+	req[0] = pcpVersion
+	req[1] = pcpOpMap
+	binary.BigEndian.PutUint32(req[4:8], lifetime)
+	copy(req[8:24], local.To16())
+	copy(req[24:36], n.nonce[:])
+	req[36] = proto
+	binary.BigEndian.PutUint16(req[40:42], uint16(intport))
+	binary.BigEndian.PutUint16(req[42:44], uint16(extport))
+	if local.To4() != nil {
+		copy(req[44:60], net.IPv4zero.To16())
+	}
+
+	buf := make([]byte, 1100)
+	timeout := n.timeout
+	for attempt := 0; attempt < pcpMaxAttempts; attempt++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			nbytes, err := conn.Read(buf)
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			m, err := n.parseResponse(buf[:nbytes], proto, intport)
+			if err == errPCPIgnoreResponse {
+				continue
+			}
+			if err == nil && lifetime > 0 {
+				n.extIP, n.extIPTime = m.extIP, time.Now()
+			}
+			return m, err
+		}
+		timeout *= 2
+	}
+	return nil, errPCPTimeout
+}
+
+func (n *pcp) parseResponse(resp []byte, proto byte, intport int) (*pcpMapping, error) {
+	if len(resp) < pcpHeaderSize {
+		return nil, errPCPBadResponse
+	}
+	// NAT-PMP gateways answer with their own version.
+	if resp[0] != pcpVersion {
+		return nil, errPCPVersion
+	}
+	if resp[1] != pcpResponse|pcpOpMap {
+		return nil, errPCPIgnoreResponse
+	}
+	m := &pcpMapping{
+		lifetime: binary.BigEndian.Uint32(resp[4:8]),
+		epoch:    binary.BigEndian.Uint32(resp[8:12]),
+	}
+	n.checkEpoch(m.epoch)
+	if code := resp[3]; code != 0 {
+		return nil, pcpError{code: code, lifetime: m.lifetime}
+	}
+	if len(resp) < pcpHeaderSize+pcpMapSize {
+		return nil, errPCPBadResponse
+	}
+	if string(resp[24:36]) != string(n.nonce[:]) || resp[36] != proto || int(binary.BigEndian.Uint16(resp[40:42])) != intport {
+		return nil, errPCPIgnoreResponse
+	}
+	m.extPort = binary.BigEndian.Uint16(resp[42:44])
+	m.extIP = net.IP(append([]byte{}, resp[44:60]...))
+	if ip4 := m.extIP.To4(); ip4 != nil {
+		m.extIP = ip4
+	}
+	return m, nil
+}
+
+// checkEpoch detects gateway restarts, which lose all mappings. The epoch of the
+// gateway must advance with the client clock, as described in RFC 6887 section 8.5.
+func (n *pcp) checkEpoch(epoch uint32) {
+	now := time.Now()
+	if !n.lastSeen.IsZero() {
+		var (
+			clientDelta = int64(now.Sub(n.lastSeen) / time.Second)
+			serverDelta = int64(epoch) - int64(n.lastEpoch)
+		)
+		if serverDelta < -1 || clientDelta+2 < serverDelta-serverDelta/16 || serverDelta+2 < clientDelta-clientDelta/16 {
+			log.Debug("PCP gateway lost its mappings", "gateway", n.gw)
+			n.lost = true
+			n.extIP = nil
+		}
+	}
+	n.lastEpoch, n.lastSeen = epoch, now
+}
+
+func discoverPCP() Interface {
+	// run external address lookups on all potential gateways
+	gws := potentialGateways()
+	found := make(chan *pcp, len(gws))
+	for i := range gws {
+		c := newPCP(gws[i], pcpPort)
+		go func() {
+			if _, err := c.ExternalIP(); err != nil {
+				found <- nil
+			} else {
+				found <- c
+			}
+		}()
+	}
+	// return the one that responds first.
+	timeout := time.NewTimer(2 * time.Second)
+	defer timeout.Stop()
+	for range gws {
+		select {
+		case c := <-found:
+			if c != nil {
+				return c
+			}
+		case <-timeout.C:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPCPMapping(t *testing.T) {
+	gw := newFakePCPGateway(t, net.IP{33, 44, 55, 66}, 1000)
+	defer gw.close()
+
+	n := newPCP(net.IP{127, 0, 0, 1}, gw.port())
+	n.timeout = 50 * time.Millisecond
+
+	ip, err := n.ExternalIP()
+	if err != nil {
+		t.Fatal("ExternalIP failed:", err)
+	}
+	if !ip.Equal(gw.extIP) {
+		t.Errorf("wrong external IP %v, want %v", ip, gw.extIP)
+	}
+	if len(gw.mappings()) != 0 {
+		t.Errorf("probe mapping not deleted: %v", gw.mappings())
+	}
+
+	// Map a port. The gateway assigns a different external port.
+	port, err := n.AddMapping("tcp", 30303, 30303, "test", 10*time.Minute)
+	if err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	if port != 31303 {
+		t.Errorf("wrong external port %d, want %d", port, 31303)
+	}
+	if m := gw.mappings(); len(m) != 1 || m[pcpMappingKey{pcpProtoTCP, 30303}] != 31303 {
+		t.Errorf("wrong gateway mappings %v", m)
+	}
+
+	// Renewing the mapping must keep the assigned port.
+	if port, err := n.AddMapping("tcp", int(port), 30303, "test", 10*time.Minute); err != nil || port != 31303 {
+		t.Errorf("renewal failed: port %d, err %v", port, err)
+	}
+
+	// The external address is known from the mapping, no probe is needed.
+	requests := gw.requestCount()
+	if ip, err := n.ExternalIP(); err != nil || !ip.Equal(gw.extIP) || gw.requestCount() != requests {
+		t.Errorf("cached external IP %v, err %v, %d requests", ip, err, gw.requestCount()-requests)
+	}
+
+	// The gateway restarts and loses the mapping, which is detected by the
+	// next response.
+	if n.MappingsLost() {
+		t.Error("mappings lost before gateway restart")
+	}
+	gw.restart()
+	if _, err := n.AddMapping("tcp", int(port), 30303, "test", 10*time.Minute); err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	if !n.MappingsLost() {
+		t.Error("gateway restart not detected")
+	}
+	if n.MappingsLost() {
+		t.Error("gateway restart reported twice")
+	}
+	if err := n.DeleteMapping("tcp", int(port), 30303); err != nil {
+		t.Fatal("DeleteMapping failed:", err)
+	}
+	if len(gw.mappings()) != 0 {
+		t.Errorf("mapping not deleted: %v", gw.mappings())
+	}
+}
+
+func TestPCPErrors(t *testing.T) {
+	gw := newFakePCPGateway(t, net.IP{33, 44, 55, 66}, 0)
+	defer gw.close()
+
+	n := newPCP(net.IP{127, 0, 0, 1}, gw.port())
+	n.timeout = 50 * time.Millisecond
+
+	// Lost requests are retransmitted.
+	gw.setDrop(2)
+	if _, err := n.AddMapping("udp", 30303, 30303, "test", time.Minute); err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+
+	// Mappings of other clients can't be changed.
+	other := newPCP(net.IP{127, 0, 0, 1}, gw.port())
+	if _, err := other.AddMapping("udp", 30303, 30303, "test", time.Minute); err == nil || err.Error() != "PCP request failed: NOT_AUTHORIZED" {
+		t.Errorf("wrong error for foreign mapping: %v", err)
+	}
+
+	// Failures are reported with the result code.
+	gw.setResult(8)
+	if _, err := n.AddMapping("tcp", 30303, 30303, "test", time.Minute); err == nil || err.Error() != "PCP request failed: NO_RESOURCES" {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := n.AddMapping("sctp", 30303, 30303, "test", time.Minute); err == nil {
+		t.Error("no error for unsupported protocol")
+	}
+
+	// Gateways that don't respond time out.
+	gw.setDrop(pcpMaxAttempts)
+	n.timeout = 5 * time.Millisecond
+	if _, err := n.AddMapping("udp", 30303, 30303, "test", time.Minute); err != errPCPTimeout {
+		t.Errorf("wrong error for unresponsive gateway: %v", err)
+	}
+}
+
+type pcpMappingKey struct {
+	proto   byte
+	intport uint16
+}
+
+// fakePCPGateway implements the MAP opcode of a PCP server.
+type fakePCPGateway struct {
+	t    *testing.T
+	conn *net.UDPConn
+
+	extIP      net.IP
+	portOffset uint16
+
+	mu       sync.Mutex
+	start    time.Time
+	epoch    uint32 // epoch at start
+	maps     map[pcpMappingKey]uint16
+	nonces   map[pcpMappingKey]string
+	drop     int  // number of requests to ignore
+	result   byte // result code of responses
+	requests int  // number of handled requests
+}
+
+func newFakePCPGateway(t *testing.T, extIP net.IP, portOffset uint16) *fakePCPGateway {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	gw := &fakePCPGateway{
+		t:          t,
+		conn:       conn,
+		extIP:      extIP,
+		portOffset: portOffset,
+		start:      time.Now(),
+		epoch:      1000,
+		maps:       make(map[pcpMappingKey]uint16),
+		nonces:     make(map[pcpMappingKey]string),
+	}
+	go gw.serve()
+	return gw
+}
+
+func (gw *fakePCPGateway) port() int {
+	return gw.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (gw *fakePCPGateway) close() {
+	gw.conn.Close()
+}
+
+func (gw *fakePCPGateway) setDrop(n int) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	gw.drop = n
+}
+
+func (gw *fakePCPGateway) setResult(code byte) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	gw.result = code
+}
+
+// restart drops all mappings and resets the epoch.
+func (gw *fakePCPGateway) restart() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	gw.start, gw.epoch = time.Now(), 0
+	gw.maps = make(map[pcpMappingKey]uint16)
+	gw.nonces = make(map[pcpMappingKey]string)
+}
+
+func (gw *fakePCPGateway) requestCount() int {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	return gw.requests
+}
+
+func (gw *fakePCPGateway) mappings() map[pcpMappingKey]uint16 {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	m := make(map[pcpMappingKey]uint16)
+	for k, v := range gw.maps {
+		m[k] = v
+	}
+	return m
+}
+
+func (gw *fakePCPGateway) serve() {
+	buf := make([]byte, 1100)
+	for {
+		n, from, err := gw.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if resp := gw.handle(buf[:n], from); resp != nil {
+			gw.conn.WriteToUDP(resp, from)
+		}
+	}
+}
+
+func (gw *fakePCPGateway) handle(req []byte, from *net.UDPAddr) []byte {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.requests++
+	if gw.drop > 0 {
+		gw.drop--
+		return nil
+	}
+	if len(req) != pcpHeaderSize+pcpMapSize || req[0] != pcpVersion || req[1] != pcpOpMap {
+		gw.t.Errorf("invalid request %x", req)
+		return nil
+	}
+	if !net.IP(req[8:24]).Equal(from.IP) {
+		gw.t.Errorf("wrong client address %v, sent from %v", net.IP(req[8:24]), from.IP)
+	}
+	var (
+		lifetime = binary.BigEndian.Uint32(req[4:8])
+		nonce    = string(req[24:36])
+		key      = pcpMappingKey{req[36], binary.BigEndian.Uint16(req[40:42])}
+		extport  = binary.BigEndian.Uint16(req[42:44])
+		result   = gw.result
+	)
+	if n, ok := gw.nonces[key]; ok && n != nonce {
+		result = 2 // NOT_AUTHORIZED
+	}
+	if result == 0 {
+		switch {
+		case lifetime == 0:
+			delete(gw.maps, key)
+			delete(gw.nonces, key)
+		case gw.maps[key] != 0:
+			extport = gw.maps[key]
+		default:
+			extport += gw.portOffset
+			gw.maps[key], gw.nonces[key] = extport, nonce
+		}
+	}
+	resp := make([]byte, pcpHeaderSize+pcpMapSize)
+	resp[0] = pcpVersion
+	resp[1] = pcpResponse | pcpOpMap
+	resp[3] = result
+	binary.BigEndian.PutUint32(resp[4:8], lifetime)
+	binary.BigEndian.PutUint32(resp[8:12], gw.epoch+uint32(time.Since(gw.start)/time.Second))
+	copy(resp[24:44], req[24:44])
+	binary.BigEndian.PutUint16(resp[42:44], extport)
+	copy(resp[44:60], gw.extIP.To16())
+	return resp
+}
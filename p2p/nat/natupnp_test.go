@@ -18,14 +18,19 @@ package nat
 
 import (
 	"fmt"
+	"errors"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/huin/goupnp"
 	"github.com/huin/goupnp/httpu"
 )
 
@@ -179,6 +184,97 @@ func TestUPNP_DDWRT(t *testing.T) {
 	}
 }
 
+func TestUPnPMapping(t *testing.T) {
+	gw := &fakeUPnPGateway{extIP: "33.44.55.66", maps: make(map[string]string)}
+	n := &upnp{
+		dev:     &goupnp.RootDevice{URLBase: url.URL{Scheme: "http", Host: "127.0.0.1:5000"}},
+		service: "IGDv1-IP1",
+		client:  gw,
+	}
+	ip, err := n.ExternalIP()
+	if err != nil {
+		t.Fatal("ExternalIP failed:", err)
+	}
+	if !ip.Equal(net.IP{33, 44, 55, 66}) {
+		t.Errorf("wrong external IP %v", ip)
+	}
+	port, err := n.AddMapping("tcp", 30303, 30303, "test", time.Minute)
+	if err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	if port != 30303 {
+		t.Errorf("wrong external port %d", port)
+	}
+	if client := gw.mapping("TCP", 30303); client != "127.0.0.1:30303" {
+		t.Errorf("wrong mapping target %q", client)
+	}
+	if err := n.DeleteMapping("tcp", 30303, 30303); err != nil {
+		t.Fatal("DeleteMapping failed:", err)
+	}
+	if client := gw.mapping("TCP", 30303); client != "" {
+		t.Errorf("mapping not deleted: %q", client)
+	}
+
+	// Gateway failures must be reported.
+	gw.setFail(true)
+	if _, err := n.AddMapping("udp", 30303, 30303, "test", time.Minute); err == nil {
+		t.Error("no error for failed mapping")
+	}
+	if _, err := n.ExternalIP(); err == nil {
+		t.Error("no error for failed external IP request")
+	}
+}
+
+// fakeUPnPGateway implements the WANIPConnection service of an IGD.
+type fakeUPnPGateway struct {
+	mu    sync.Mutex
+	extIP string
+	maps  map[string]string // protocol/extport -> internal host:port
+	fail  bool
+}
+
+func (gw *fakeUPnPGateway) setFail(fail bool) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	gw.fail = fail
+}
+
+func (gw *fakeUPnPGateway) mapping(protocol string, extport uint16) string {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	return gw.maps[fmt.Sprintf("%s/%d", protocol, extport)]
+}
+
+func (gw *fakeUPnPGateway) GetExternalIPAddress() (string, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.fail {
+		return "", errors.New("gateway failure")
+	}
+	return gw.extIP, nil
+}
+
+func (gw *fakeUPnPGateway) AddPortMapping(remote string, extport uint16, protocol string, intport uint16, client string, enabled bool, desc string, lease uint32) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.fail {
+		return errors.New("gateway failure")
+	}
+	gw.maps[fmt.Sprintf("%s/%d", protocol, extport)] = fmt.Sprintf("%s:%d", client, intport)
+	return nil
+}
+
+func (gw *fakeUPnPGateway) DeletePortMapping(remote string, extport uint16, protocol string) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	delete(gw.maps, fmt.Sprintf("%s/%d", protocol, extport))
+	return nil
+}
+
+func (gw *fakeUPnPGateway) GetNATRSIPStatus() (bool, bool, error) {
+	return false, true, nil
+}
+
 // fakeIGD presents itself as a discoverable UPnP device which sends
 // canned responses to HTTPU and HTTP requests.
 type fakeIGD struct {
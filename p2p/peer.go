@@ -90,6 +90,7 @@ type PeerEvent struct {
 type Peer struct {
 	rw      *conn
 	running map[string]*protoRW
+	traffic *peerTraffic
 	log     log.Logger
 	created mclock.AbsTime
 
@@ -210,9 +211,21 @@ func (p *Peer) Inbound() bool {
 
 func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
+	traffic := newPeerTraffic(conn.node.ID(), protomap, conn.uploadLimits)
+	if tt, ok := conn.transport.(trafficTransport); ok {
+		tt.setTraffic(traffic)
+	}
+	if st, ok := conn.transport.(streamTransport); ok {
+		offsets := make([]uint64, 0, len(protomap))
+		for _, proto := range protomap {
+			offsets = append(offsets, proto.offset)
+		}
+		st.setProtocolOffsets(offsets)
+	}
 	p := &Peer{
 		rw:       conn,
 		running:  protomap,
+		traffic:  traffic,
 		created:  mclock.Now(),
 		disc:     make(chan DiscReason),
 		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
@@ -311,6 +324,9 @@ func (p *Peer) readLoop(errc chan<- error) {
 }
 
 func (p *Peer) handle(msg Msg) error {
+	if msg.Code < baseProtocolLength {
+		p.traffic.ingress("", msg.meterSize)
+	}
 	switch {
 	case msg.Code == pingMsg:
 		msg.Discard()
@@ -330,8 +346,11 @@ func (p *Peer) handle(msg Msg) error {
 		if err != nil {
 			return fmt.Errorf("msg code out of range: %v", msg.Code)
 		}
+		p.traffic.ingress(proto.Name, msg.meterSize)
 		if metrics.Enabled {
-			m := fmt.Sprintf("%s/%s/%d/%#02x", ingressMeterName, proto.Name, proto.Version, msg.Code-proto.offset)
+			m := fmt.Sprintf("%s/%s/%d", ingressMeterName, proto.Name, proto.Version)
+			metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
+			m = fmt.Sprintf("%s/%#02x", m, msg.Code-proto.offset)
 			metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
 			metrics.GetOrRegisterMeter(m+"/packets", nil).Mark(1)
 		}
@@ -479,6 +498,7 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   *PeerTrafficInfo       `json:"traffic"`   // Data exchanged with the peer
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -495,6 +515,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Fullname(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Traffic:   p.traffic.info(),
 	}
 	if p.Node().Seq() > 0 {
 		info.ENR = p.Node().String()
@@ -0,0 +1,235 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package quic implements the endpoint of the experimental devp2p QUIC transport
+// using quic-go.
+//
+// The package is a separate module because quic-go needs a newer Go release than
+// the rest of go-ethereum. To enable the transport, open an endpoint and set it in
+// the server configuration:
+//
+//	endpoint, err := quic.Listen(":30303")
+//	if err != nil {
+//		...
+//	}
+//	config.QUIC = endpoint
+package quic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	quicgo "github.com/quic-go/quic-go"
+)
+
+const (
+	alpn             = "devp2p"
+	handshakeTimeout = 5 * time.Second
+	idleTimeout      = 30 * time.Second
+	keepAlive        = 15 * time.Second
+	maxStreams       = 64 // subprotocol streams per connection
+)
+
+// Endpoint is a UDP socket on which QUIC connections are accepted and dialed.
+// It implements p2p.QUICEndpoint.
+type Endpoint struct {
+	tr       *quicgo.Transport
+	listener *quicgo.Listener
+	tlsConf  *tls.Config
+	conf     *quicgo.Config
+}
+
+// Listen opens an endpoint on the given UDP address.
+func Listen(addr string) (*Endpoint, error) {
+	uaddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", uaddr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := newTLSConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	e := &Endpoint{
+		tr:      &quicgo.Transport{Conn: conn},
+		tlsConf: tlsConf,
+		conf: &quicgo.Config{
+			HandshakeIdleTimeout:  handshakeTimeout,
+			MaxIdleTimeout:        idleTimeout,
+			KeepAlivePeriod:       keepAlive,
+			MaxIncomingStreams:    1,
+			MaxIncomingUniStreams: maxStreams,
+		},
+	}
+	if e.listener, err = e.tr.Listen(e.tlsConf, e.conf); err != nil {
+		e.tr.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// newTLSConfig creates a TLS configuration with an ephemeral self-signed
+// certificate. The certificate is not verified by either side, the identity of
+// the remote node is established by the RLPx handshake on the control stream.
+func newTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		NextProtos:         []string{alpn},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true,
+	}, nil
+}
+
+// LocalAddr returns the address of the socket.
+func (e *Endpoint) LocalAddr() *net.UDPAddr {
+	return e.tr.Conn.LocalAddr().(*net.UDPAddr)
+}
+
+// Accept waits for the next inbound connection.
+func (e *Endpoint) Accept() (p2p.QUICConn, error) {
+	c, err := e.listener.Accept(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c}, nil
+}
+
+// Dial connects to the given address.
+func (e *Endpoint) Dial(ctx context.Context, addr *net.UDPAddr) (p2p.QUICConn, error) {
+	c, err := e.tr.Dial(ctx, addr, e.tlsConf, e.conf)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c}, nil
+}
+
+// Close closes the socket and all connections on it.
+func (e *Endpoint) Close() error {
+	e.listener.Close()
+	return e.tr.Close()
+}
+
+// conn implements p2p.QUICConn.
+type conn struct {
+	c quicgo.Connection
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.c.LocalAddr() }
+func (c *conn) RemoteAddr() net.Addr { return c.c.RemoteAddr() }
+
+func (c *conn) ExportKeyingMaterial(label string, length int) ([]byte, error) {
+	state := c.c.ConnectionState().TLS
+	return state.ExportKeyingMaterial(label, nil, length)
+}
+
+func (c *conn) AcceptStream(ctx context.Context) (p2p.QUICStream, error) {
+	s, err := c.c.AcceptStream(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return stream{s}, nil
+}
+
+func (c *conn) OpenStream(ctx context.Context) (p2p.QUICStream, error) {
+	s, err := c.c.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return stream{s}, nil
+}
+
+func (c *conn) AcceptUniStream(ctx context.Context) (io.Reader, error) {
+	s, err := c.c.AcceptUniStream(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return receiveStream{s}, nil
+}
+
+func (c *conn) OpenUniStream() (p2p.QUICSendStream, error) {
+	s, err := c.c.OpenUniStream()
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return sendStream{s}, nil
+}
+
+func (c *conn) CloseWithError(code uint64, reason string) error {
+	return c.c.CloseWithError(quicgo.ApplicationErrorCode(code), reason)
+}
+
+type stream struct{ quicgo.Stream }
+
+func (s stream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	return n, convertError(err)
+}
+
+func (s stream) Write(b []byte) (int, error) {
+	n, err := s.Stream.Write(b)
+	return n, convertError(err)
+}
+
+type receiveStream struct{ quicgo.ReceiveStream }
+
+func (s receiveStream) Read(b []byte) (int, error) {
+	n, err := s.ReceiveStream.Read(b)
+	return n, convertError(err)
+}
+
+type sendStream struct{ quicgo.SendStream }
+
+func (s sendStream) Write(b []byte) (int, error) {
+	n, err := s.SendStream.Write(b)
+	return n, convertError(err)
+}
+
+// convertError converts the error of a connection closed by the remote end into
+// the disconnect reason it carries.
+func convertError(err error) error {
+	var aerr *quicgo.ApplicationError
+	if errors.As(err, &aerr) && aerr.Remote {
+		return p2p.DiscReason(aerr.ErrorCode)
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package quic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// echoProtocol creates a protocol which answers messages with code 0 by sending
+// the same payload with code 1. Received messages are reported on ch. If initiate
+// is set, the protocol starts by sending its name with code 0.
+func echoProtocol(name string, initiate bool, ch chan<- string) p2p.Protocol {
+	return p2p.Protocol{
+		Name:    name,
+		Version: 1,
+		Length:  4,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if initiate {
+				if err := p2p.Send(rw, 0, name); err != nil {
+					return err
+				}
+			}
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var s string
+				if err := msg.Decode(&s); err != nil {
+					return err
+				}
+				if msg.Code == 0 {
+					if err := p2p.Send(rw, 1, s); err != nil {
+						return err
+					}
+				}
+				ch <- fmt.Sprintf("%s:%d", s, msg.Code)
+			}
+		},
+	}
+}
+
+// countingEndpoint counts the subprotocol streams opened on dialed connections.
+type countingEndpoint struct {
+	*Endpoint
+	streams int32
+}
+
+func (e *countingEndpoint) Dial(ctx context.Context, addr *net.UDPAddr) (p2p.QUICConn, error) {
+	c, err := e.Endpoint.Dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{c, &e.streams}, nil
+}
+
+type countingConn struct {
+	p2p.QUICConn
+	streams *int32
+}
+
+func (c *countingConn) OpenUniStream() (p2p.QUICSendStream, error) {
+	atomic.AddInt32(c.streams, 1)
+	return c.QUICConn.OpenUniStream()
+}
+
+func startServer(t *testing.T, name string, endpoint p2p.QUICEndpoint, protocols []p2p.Protocol, noDial bool) *p2p.Server {
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    1,
+		NoDiscovery: true,
+		NoDial:      noDial,
+		ListenAddr:  "127.0.0.1:0",
+		QUIC:        endpoint,
+		Protocols:   protocols,
+		Logger:      testlog.Logger(t, log.LvlTrace).New("server", name),
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatal("can't start server:", err)
+	}
+	return srv
+}
+
+func listen(t *testing.T) *Endpoint {
+	endpoint, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't open endpoint:", err)
+	}
+	return endpoint
+}
+
+// This test checks that servers which both advertise QUIC connect over it, and that
+// messages of all protocols are delivered on their own streams.
+func TestServerQUIC(t *testing.T) {
+	var (
+		recv1     = make(chan string, 10)
+		recv2     = make(chan string, 10)
+		endpoint1 = &countingEndpoint{Endpoint: listen(t)}
+		srv1      = startServer(t, "1", endpoint1, []p2p.Protocol{echoProtocol("a", true, recv1), echoProtocol("b", true, recv1)}, false)
+		srv2      = startServer(t, "2", listen(t), []p2p.Protocol{echoProtocol("a", false, recv2), echoProtocol("b", false, recv2)}, true)
+	)
+	defer srv1.Stop()
+	defer srv2.Stop()
+
+	var port enr.QUIC
+	if err := srv2.Self().Load(&port); err != nil || port == 0 {
+		t.Fatalf("QUIC port not advertised: %v", err)
+	}
+	if !syncAddPeer(srv1, srv2.Self()) {
+		t.Fatal("peer not connected")
+	}
+	peer := srv1.Peers()[0]
+	if _, ok := peer.RemoteAddr().(*net.UDPAddr); !ok {
+		t.Fatalf("peer connected over %v, want QUIC", peer.RemoteAddr())
+	}
+
+	// Both protocols send a message on start, wait for them and the echoes.
+	want := map[string]bool{"a:0": true, "b:0": true}
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-recv2:
+			if !want[got] {
+				t.Errorf("unexpected message %q", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+	want = map[string]bool{"a:1": true, "b:1": true}
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-recv1:
+			if !want[got] {
+				t.Errorf("unexpected echo %q", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for echoes")
+		}
+	}
+	if streams := atomic.LoadInt32(&endpoint1.streams); streams != 2 {
+		t.Errorf("wrong number of protocol streams: got %d, want 2", streams)
+	}
+}
+
+// This test checks that the disconnect reason is delivered to the remote end.
+func TestDisconnectReason(t *testing.T) {
+	srv1 := startServer(t, "1", listen(t), []p2p.Protocol{echoProtocol("a", false, nil)}, false)
+	srv2 := startServer(t, "2", listen(t), []p2p.Protocol{echoProtocol("a", false, nil)}, true)
+	defer srv1.Stop()
+	defer srv2.Stop()
+
+	if !syncAddPeer(srv1, srv2.Self()) {
+		t.Fatal("peer not connected")
+	}
+	var (
+		ch  = make(chan *p2p.PeerEvent, 10)
+		sub = srv2.SubscribeEvents(ch)
+	)
+	defer sub.Unsubscribe()
+	srv1.Peers()[0].Disconnect(p2p.DiscTooManyPeers)
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-ch:
+			if ev.Type == p2p.PeerEventTypeDrop {
+				if ev.Error != p2p.DiscTooManyPeers.Error() {
+					t.Fatalf("wrong disconnect reason %q", ev.Error)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("peer not dropped")
+		}
+	}
+}
+
+func syncAddPeer(srv *p2p.Server, node *enode.Node) bool {
+	var (
+		ch      = make(chan *p2p.PeerEvent)
+		sub     = srv.SubscribeEvents(ch)
+		timeout = time.After(2 * time.Second)
+	)
+	defer sub.Unsubscribe()
+	srv.AddPeer(node)
+	for {
+		select {
+		case ev := <-ch:
+			if ev.Type == p2p.PeerEventTypeAdd && ev.Peer == node.ID() {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
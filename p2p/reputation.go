@@ -0,0 +1,238 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// Scores decay exponentially towards zero, halving with every reputationHalfLife.
+	// This makes peers recover from occasional hiccups, while persistent offenders
+	// keep accumulating penalties faster than they fade away.
+	reputationHalfLife = 10 * time.Minute
+
+	maxReputationScore = 100  // Upper cap, so good behaviour can't be hoarded indefinitely
+	banReputationScore = -100 // Score at or below which a peer is temporarily banned
+	minDialScore       = -25  // Dynamic dial candidates scoring below this are skipped
+	reputationBanTime  = time.Hour
+
+	// maxTrackedScores is the number of peers to track scores for before near-zero
+	// entries are dropped to keep the memory use bounded.
+	maxTrackedScores = 4096
+)
+
+// ReputationEvent is a peer behaviour worth remembering, reported by the
+// subprotocols to the server.
+type ReputationEvent uint8
+
+const (
+	ReputationGoodDelivery    ReputationEvent = iota // Requested data was delivered
+	ReputationUselessResponse                        // Response was empty, stale or otherwise unusable
+	ReputationTimeout                                // Request was not answered in time
+	ReputationInvalidBlock                           // Peer relayed an invalid block or header
+	ReputationInvalidTx                              // Peer relayed an invalid transaction or announcement
+	numReputationEvents
+)
+
+// reputationWeights is the score adjustment applied for each event.
+var reputationWeights = [numReputationEvents]float64{
+	ReputationGoodDelivery:    1,
+	ReputationUselessResponse: -5,
+	ReputationTimeout:         -2,
+	ReputationInvalidBlock:    -50,
+	ReputationInvalidTx:       -20,
+}
+
+// String implements fmt.Stringer.
+func (ev ReputationEvent) String() string {
+	switch ev {
+	case ReputationGoodDelivery:
+		return "goodDelivery"
+	case ReputationUselessResponse:
+		return "uselessResponse"
+	case ReputationTimeout:
+		return "timeout"
+	case ReputationInvalidBlock:
+		return "invalidBlock"
+	case ReputationInvalidTx:
+		return "invalidTx"
+	default:
+		return "unknown"
+	}
+}
+
+// PeerScoreInfo represents a short summary of the reputation of a remote peer.
+type PeerScoreInfo struct {
+	ID          string            `json:"id"`                    // Unique node identifier
+	Score       float64           `json:"score"`                 // Current (decayed) score of the peer
+	BannedUntil *time.Time        `json:"bannedUntil,omitempty"` // Expiry of the active ban, if any
+	Events      map[string]uint64 `json:"events"`                // Number of reported events by type
+}
+
+// peerScore is the reputation state tracked for a single remote node.
+type peerScore struct {
+	value   float64
+	updated mclock.AbsTime
+	events  [numReputationEvents]uint64
+}
+
+// reputation tracks the decaying scores of remote nodes and bans the ones which
+// misbehave persistently. Bans are stored in the node database so they outlive
+// restarts of the server.
+type reputation struct {
+	clock mclock.Clock
+	db    *enode.DB
+	log   log.Logger
+
+	// Bans are persisted as wall clock times, which are derived from the clock
+	// relative to the time the tracker was created.
+	epoch      time.Time
+	epochClock mclock.AbsTime
+
+	lock   sync.Mutex
+	scores map[enode.ID]*peerScore
+}
+
+func newReputation(db *enode.DB, clock mclock.Clock, log log.Logger) *reputation {
+	return &reputation{
+		clock:      clock,
+		db:         db,
+		log:        log,
+		epoch:      time.Now(),
+		epochClock: clock.Now(),
+		scores:     make(map[enode.ID]*peerScore),
+	}
+}
+
+// now returns the current wall clock time according to the clock.
+func (r *reputation) now() time.Time {
+	return r.epoch.Add(time.Duration(r.clock.Now() - r.epochClock))
+}
+
+// decay returns the value of the score at the given time.
+func (s *peerScore) decay(now mclock.AbsTime) float64 {
+	elapsed := time.Duration(now - s.updated)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(reputationHalfLife))
+}
+
+// report records an event for the given node and returns whether the node got
+// banned because of it.
+func (r *reputation) report(id enode.ID, ev ReputationEvent) bool {
+	if ev >= numReputationEvents {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.clock.Now()
+	s := r.scores[id]
+	if s == nil {
+		if len(r.scores) >= maxTrackedScores {
+			r.prune(now)
+		}
+		s = &peerScore{updated: now}
+		r.scores[id] = s
+	}
+	s.value = math.Min(s.decay(now)+reputationWeights[ev], maxReputationScore)
+	s.updated = now
+	s.events[ev]++
+
+	if s.value > banReputationScore {
+		return false
+	}
+	// The peer crossed the ban threshold, ban it and restart it from the threshold
+	// so that it can't immediately get banned again after the ban expires.
+	until := r.now().Add(reputationBanTime)
+	if err := r.db.UpdateBanExpiry(id, until); err != nil {
+		r.log.Warn("Failed to store peer ban", "id", id, "err", err)
+	}
+	r.log.Debug("Banned misbehaving peer", "id", id, "event", ev, "until", until)
+	s.value = banReputationScore / 2
+	return true
+}
+
+// prune drops the tracked scores which have decayed close to zero. It's called
+// with the lock held.
+func (r *reputation) prune(now mclock.AbsTime) {
+	for id, s := range r.scores {
+		if math.Abs(s.decay(now)) < 1 {
+			delete(r.scores, id)
+		}
+	}
+}
+
+// score returns the current score of the given node.
+func (r *reputation) score(id enode.ID) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if s := r.scores[id]; s != nil {
+		return s.decay(r.clock.Now())
+	}
+	return 0
+}
+
+// banned reports whether the given node is currently banned.
+func (r *reputation) banned(id enode.ID) bool {
+	return r.now().Before(r.db.BanExpiry(id))
+}
+
+// infos returns the reputation summary of all tracked nodes, sorted by score
+// in ascending order.
+func (r *reputation) infos() []*PeerScoreInfo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now   = r.clock.Now()
+		infos = make([]*PeerScoreInfo, 0, len(r.scores))
+	)
+	for id, s := range r.scores {
+		info := &PeerScoreInfo{
+			ID:     id.String(),
+			Score:  s.decay(now),
+			Events: make(map[string]uint64),
+		}
+		if until := r.db.BanExpiry(id); r.now().Before(until) {
+			info.BannedUntil = &until
+		}
+		for ev, count := range s.events {
+			if count > 0 {
+				info.Events[ReputationEvent(ev).String()] = count
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Score != infos[j].Score {
+			return infos[i].Score < infos[j].Score
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestReputationDecay(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		clock = new(mclock.Simulated)
+		rep   = newReputation(db, clock, log.Root())
+		id    = enode.ID{1}
+	)
+	rep.report(id, ReputationUselessResponse)
+	rep.report(id, ReputationUselessResponse)
+	if score := rep.score(id); score != -10 {
+		t.Fatalf("wrong score: have %v, want -10", score)
+	}
+	clock.Run(reputationHalfLife)
+	if score := rep.score(id); math.Abs(score+5) > 1e-9 {
+		t.Fatalf("wrong decayed score: have %v, want -5", score)
+	}
+	// Good behaviour is capped.
+	for i := 0; i < 2*maxReputationScore; i++ {
+		rep.report(id, ReputationGoodDelivery)
+	}
+	if score := rep.score(id); score != maxReputationScore {
+		t.Fatalf("wrong capped score: have %v, want %v", score, maxReputationScore)
+	}
+}
+
+func TestReputationBan(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		clock = new(mclock.Simulated)
+		rep   = newReputation(db, clock, log.Root())
+		id    = enode.ID{1}
+	)
+	if rep.report(id, ReputationInvalidBlock) {
+		t.Fatal("node banned too early")
+	}
+	if rep.banned(id) {
+		t.Fatal("node banned too early")
+	}
+	if !rep.report(id, ReputationInvalidBlock) {
+		t.Fatal("node not banned")
+	}
+	// The ban must be visible to a fresh tracker on the same database.
+	if !newReputation(db, new(mclock.Simulated), log.Root()).banned(id) {
+		t.Fatal("ban not persisted")
+	}
+	infos := rep.infos()
+	if len(infos) != 1 || infos[0].BannedUntil == nil || infos[0].Events["invalidBlock"] != 2 {
+		t.Fatalf("wrong score infos: %+v", infos[0])
+	}
+	// Bans expire according to the server clock.
+	clock.Run(reputationBanTime)
+	if rep.banned(id) {
+		t.Fatal("ban not expired")
+	}
+}
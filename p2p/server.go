@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"sort"
 	"sync"
@@ -23,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -64,6 +66,14 @@ type Config struct {
 	// Zero defaults to preset values.
 	MaxPendingPeers int `toml:",omitempty"`
 
+	// MaxUploadRate limits the total upload bandwidth of all peer connections,
+	// in bytes per second. Zero means unlimited.
+	MaxUploadRate int `toml:",omitempty"`
+
+	// MaxPeerUploadRate limits the upload bandwidth of each peer connection,
+	// in bytes per second. Zero means unlimited.
+	MaxPeerUploadRate int `toml:",omitempty"`
+
 	// DialRatio controls the ratio of inbound to dialed connections.
 	// Example: a DialRatio of 2 allows 1/2 of connections to be dialed.
 	// Setting DialRatio to zero defaults it to 3.
@@ -124,6 +134,13 @@ type Config struct {
 	// for TCP and DiscAddr for the UDP discovery protocol.
 	DiscAddr string
 
+	// If QUIC is set to a non-nil value, the server additionally accepts
+	// connections over the experimental QUIC transport on the given endpoint.
+	// The port is advertised in the node record, and remote nodes advertising
+	// a QUIC port themselves are dialed over QUIC first. The endpoint is closed
+	// when the server stops.
+	QUIC QUICEndpoint `toml:"-"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -166,12 +183,19 @@ type Server struct {
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	ntab      *discover.UDPv4
-	DiscV5    *discover.UDPv5
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	nodedb     *enode.DB
+	reputation *reputation
+	uploadRate *rate.Limiter // shared by all connections, nil if unlimited
+	localnode  *enode.LocalNode
+	ntab       *discover.UDPv4
+	DiscV5     *discover.UDPv5
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
+
+	// State of the NAT port mapping loop.
+	portMappingRegister chan *portMapping
+	natLock             sync.Mutex
+	natInfo             *NATInfo
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -183,7 +207,11 @@ type Server struct {
 	checkpointPostHandshake chan *conn
 	checkpointAddPeer       chan *conn
 
-	// State of run loop and listenLoop.
+	// State of run loop.
+	evictions map[enode.ID]enode.ID // inbound node -> peer evicted to make room for it
+
+	// Shared by listenLoop and quicListenLoop.
+	inboundLock    sync.Mutex
 	inboundHistory expHeap
 }
 
@@ -214,6 +242,8 @@ type conn struct {
 	cont  chan error // The run loop uses cont to signal errors to SetupConn.
 	caps  []Cap      // valid after the protocol handshake
 	name  string     // valid after the protocol handshake
+
+	uploadLimits []*rate.Limiter // applied to all messages sent to the peer
 }
 
 type transport interface {
@@ -388,6 +418,9 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.QUIC != nil {
+		srv.QUIC.Close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
@@ -444,7 +477,7 @@ func (srv *Server) Start() (err error) {
 		return errors.New("Server.PrivateKey must be set to a non-nil key")
 	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		srv.newTransport = newTransport
 	}
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
@@ -457,15 +490,21 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.evictions = make(map[enode.ID]enode.ID)
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
+	srv.reputation = newReputation(srv.nodedb, srv.clock, srv.log)
+	srv.uploadRate = newUploadLimiter(srv.MaxUploadRate)
 	if srv.ListenAddr != "" {
 		if err := srv.setupListening(); err != nil {
 			return err
 		}
 	}
+	if srv.QUIC != nil {
+		srv.setupQUIC()
+	}
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -499,24 +538,12 @@ func (srv *Server) setupLocalNode() error {
 			srv.localnode.Set(e)
 		}
 	}
-	switch srv.NAT.(type) {
-	case nil:
-		// No NAT interface, do nothing.
-	case nat.ExtIP:
+	if ip, ok := srv.NAT.(nat.ExtIP); ok {
 		// ExtIP doesn't block, set the IP right away.
-		ip, _ := srv.NAT.ExternalIP()
-		srv.localnode.SetStaticIP(ip)
-	default:
-		// Ask the router about the IP. This takes a while and blocks startup,
-		// do it in the background.
-		srv.loopWG.Add(1)
-		go func() {
-			defer srv.loopWG.Done()
-			if ip, err := srv.NAT.ExternalIP(); err == nil {
-				srv.localnode.SetStaticIP(ip)
-			}
-		}()
+		srv.localnode.SetStaticIP(net.IP(ip))
 	}
+	// Ask the router about the IP in the background, it takes a while.
+	srv.setupPortMapping()
 	return nil
 }
 
@@ -555,14 +582,8 @@ func (srv *Server) setupDiscovery() error {
 	}
 	realaddr := conn.LocalAddr().(*net.UDPAddr)
 	srv.log.Debug("UDP listener up", "addr", realaddr)
-	if srv.NAT != nil {
-		if !realaddr.IP.IsLoopback() {
-			srv.loopWG.Add(1)
-			go func() {
-				nat.Map(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "ethereum discovery")
-				srv.loopWG.Done()
-			}()
-		}
+	if srv.NAT != nil && !realaddr.IP.IsLoopback() {
+		srv.mapPort("udp", realaddr.Port, "ethereum discovery", srv.localnode.SetFallbackUDP)
 	}
 	srv.localnode.SetFallbackUDP(realaddr.Port)
 
@@ -617,6 +638,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		reputation:     srv.reputation,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -626,6 +648,9 @@ func (srv *Server) setupDialScheduler() {
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	if srv.QUIC != nil {
+		config.dialer = srv.wrapQUICDialer(config.dialer)
+	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
@@ -664,11 +689,9 @@ func (srv *Server) setupListening() error {
 	if tcp, ok := listener.Addr().(*net.TCPAddr); ok {
 		srv.localnode.Set(enr.TCP(tcp.Port))
 		if !tcp.IP.IsLoopback() && srv.NAT != nil {
-			srv.loopWG.Add(1)
-			go func() {
-				nat.Map(srv.NAT, srv.quit, "tcp", tcp.Port, tcp.Port, "ethereum p2p")
-				srv.loopWG.Done()
-			}()
+			srv.mapPort("tcp", tcp.Port, "ethereum p2p", func(port int) {
+				srv.localnode.Set(enr.TCP(port))
+			})
 		}
 	}
 
@@ -765,6 +788,11 @@ running:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
+			for id, evicted := range srv.evictions {
+				if evicted == pd.ID() {
+					delete(srv.evictions, id)
+				}
+			}
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
@@ -798,9 +826,11 @@ running:
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn) && !c.is(staticDialedConn) && srv.reputation.banned(c.node.ID()):
+		return DiscUselessPeer
+	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers && !srv.evictInbound(peers, c):
 		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns() && !srv.evictInbound(peers, c):
 		return DiscTooManyPeers
 	case peers[c.node.ID()] != nil:
 		return DiscAlreadyConnected
@@ -811,6 +841,43 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 	}
 }
 
+// evictInbound tries to make room for the inbound connection c by disconnecting
+// the worst scoring inbound peer. Only untrusted peers with a negative score below
+// the score of c are considered. The slot of the evicted peer stays reserved for
+// c until the peer is removed, so repeated checks of c don't evict more peers.
+func (srv *Server) evictInbound(peers map[enode.ID]*Peer, c *conn) bool {
+	id := c.node.ID()
+	if !c.is(inboundConn) || peers[id] != nil || id == srv.localnode.ID() {
+		return false
+	}
+	if _, ok := srv.evictions[id]; ok {
+		return true
+	}
+	reserved := make(map[enode.ID]bool, len(srv.evictions))
+	for _, evicted := range srv.evictions {
+		reserved[evicted] = true
+	}
+	var (
+		worst      *Peer
+		worstScore = math.Min(srv.reputation.score(id), 0)
+	)
+	for pid, p := range peers {
+		if !p.Inbound() || p.rw.is(trustedConn) || reserved[pid] {
+			continue
+		}
+		if score := srv.reputation.score(pid); score < worstScore {
+			worst, worstScore = p, score
+		}
+	}
+	if worst == nil {
+		return false
+	}
+	srv.log.Debug("Evicting low reputation peer", "id", worst.ID(), "score", worstScore, "for", id)
+	srv.evictions[id] = worst.ID()
+	worst.Disconnect(DiscTooManyPeers)
+	return true
+}
+
 func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	// Drop connections with no matching protocols.
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
@@ -902,6 +969,8 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 		return fmt.Errorf("not in netrestrict list")
 	}
 	// Reject Internet peers that try too often.
+	srv.inboundLock.Lock()
+	defer srv.inboundLock.Unlock()
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
 	if !netutil.IsLAN(remoteIP) && srv.inboundHistory.contains(remoteIP.String()) {
@@ -916,6 +985,12 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 // or the handshakes have failed.
 func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *enode.Node) error {
 	c := &conn{fd: fd, flags: flags, cont: make(chan error)}
+	if srv.uploadRate != nil {
+		c.uploadLimits = append(c.uploadLimits, srv.uploadRate)
+	}
+	if lim := newUploadLimiter(srv.MaxPeerUploadRate); lim != nil {
+		c.uploadLimits = append(c.uploadLimits, lim)
+	}
 	if dialDest == nil {
 		c.transport = srv.newTransport(fd, nil)
 	} else {
@@ -1033,6 +1108,14 @@ func (srv *Server) runPeer(p *Peer) {
 	// Run the per-peer main loop.
 	remoteRequested, err := p.run()
 
+	// Add the traffic of the connection to the totals of the node. This needs
+	// to happen before the drop is announced, as the main loop closes the node
+	// database once all peers are gone.
+	if err := srv.nodedb.AddTraffic(p.ID(), p.traffic.total.stats().toNodeTraffic()); err != nil {
+		p.log.Debug("Failed to store peer traffic", "err", err)
+	}
+	p.traffic.close()
+
 	// Announce disconnect on the main loop to update the peer set.
 	// The main loop waits for existing peers to be sent on srv.delpeer
 	// before returning, so this send should not select on srv.quit.
@@ -1095,13 +1178,39 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	return info
 }
 
+// ReportPeer records a reputation event for the given node. Peers whose score
+// drops below the ban threshold get disconnected and refused for a while, unless
+// they are trusted.
+func (srv *Server) ReportPeer(id enode.ID, ev ReputationEvent) {
+	if srv.reputation == nil || !srv.reputation.report(id, ev) {
+		return
+	}
+	// Reports may arrive from within subprotocol locks, don't wait for the run loop.
+	go srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		if p := peers[id]; p != nil && !p.rw.is(trustedConn) {
+			p.Disconnect(DiscUselessPeer)
+		}
+	})
+}
+
+// PeerScores returns the reputation of all tracked nodes, worst scoring first.
+func (srv *Server) PeerScores() []*PeerScoreInfo {
+	if srv.reputation == nil {
+		return nil
+	}
+	return srv.reputation.infos()
+}
+
 // PeersInfo returns an array of metadata objects describing connected peers.
 func (srv *Server) PeersInfo() []*PeerInfo {
 	// Gather all the generic and sub-protocol specific infos
 	infos := make([]*PeerInfo, 0, srv.PeerCount())
 	for _, peer := range srv.Peers() {
 		if peer != nil {
-			infos = append(infos, peer.Info())
+			info := peer.Info()
+			lifetime := info.Traffic.Session.add(srv.nodedb.Traffic(peer.ID()))
+			info.Traffic.Lifetime = &lifetime
+			infos = append(infos, info)
 		}
 	}
 	// Sort the result array alphabetically by node identifier
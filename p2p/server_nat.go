@@ -0,0 +1,262 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/nat"
+)
+
+const (
+	portMapDuration        = 10 * time.Minute
+	portMapRefreshInterval = 8 * time.Minute
+	portMapRetryInterval   = 5 * time.Minute
+	extipCheckInterval     = 5 * time.Minute
+	extipRetryInterval     = 2 * time.Minute
+)
+
+// NATInfo describes the port mappings maintained on the NAT gateway and the
+// external address reported by it.
+type NATInfo struct {
+	Mechanism   string            `json:"mechanism"`
+	ExternalIP  string            `json:"externalIP,omitempty"`
+	IPChanges   int               `json:"ipChanges"`         // external IP changes seen
+	LastIPCheck time.Time         `json:"lastIPCheck"`       // time of the last external IP request
+	IPError     string            `json:"ipError,omitempty"` // error of the last external IP request
+	Mappings    []*NATMappingInfo `json:"mappings"`
+}
+
+// NATMappingInfo describes the state of a port mapping and its lease renewals.
+type NATMappingInfo struct {
+	Name         string    `json:"name"`
+	Protocol     string    `json:"protocol"`
+	InternalPort int       `json:"internalPort"`
+	ExternalPort int       `json:"externalPort"`        // assigned by the gateway
+	Mapped       bool      `json:"mapped"`              // whether the lease is still valid
+	Renewals     uint64    `json:"renewals"`            // successful mapping requests
+	Failures     uint64    `json:"failures"`            // failed mapping requests
+	LastError    string    `json:"lastError,omitempty"` // error of the last mapping request
+	LastAttempt  time.Time `json:"lastAttempt"`
+	NextAttempt  time.Time `json:"nextAttempt"`
+}
+
+// portMapping is a port the server wants to be reachable from the Internet.
+type portMapping struct {
+	protocol string
+	name     string
+	port     int
+	setPort  func(int) // updates the local node record with the external port
+
+	extPort  int // external port advertised in the local node record
+	expires  mclock.AbsTime
+	nextTime mclock.AbsTime
+	info     NATMappingInfo
+}
+
+// NATInfo returns the state of the NAT port mappings.
+func (srv *Server) NATInfo() *NATInfo {
+	srv.natLock.Lock()
+	defer srv.natLock.Unlock()
+
+	if srv.natInfo == nil {
+		return &NATInfo{Mechanism: "none", Mappings: []*NATMappingInfo{}}
+	}
+	info := *srv.natInfo
+	info.Mappings = make([]*NATMappingInfo, len(srv.natInfo.Mappings))
+	for i, m := range srv.natInfo.Mappings {
+		cpy := *m
+		info.Mappings[i] = &cpy
+	}
+	return &info
+}
+
+// setupPortMapping starts the port mapping loop if NAT is configured.
+func (srv *Server) setupPortMapping() {
+	if srv.NAT == nil {
+		return
+	}
+	srv.portMappingRegister = make(chan *portMapping)
+	srv.natInfo = &NATInfo{Mechanism: srv.NAT.String(), Mappings: []*NATMappingInfo{}}
+	srv.loopWG.Add(1)
+	go srv.portMappingLoop()
+}
+
+// mapPort requests a port mapping for the given local port. setPort is called
+// when the gateway assigns a different external port.
+func (srv *Server) mapPort(protocol string, port int, name string, setPort func(int)) {
+	m := &portMapping{protocol: protocol, name: name, port: port, setPort: setPort}
+	// The loop may be busy asking the gateway, so don't block startup.
+	go func() {
+		select {
+		case srv.portMappingRegister <- m:
+		case <-srv.quit:
+		}
+	}()
+}
+
+// portMappingLoop manages port mappings for UDP and TCP and keeps the external
+// IP in the local node record up to date.
+func (srv *Server) portMappingLoop() {
+	defer srv.loopWG.Done()
+
+	var (
+		mappings  = make(map[string]*portMapping)
+		timer     = srv.clock.NewTimer(0)
+		extip     net.IP
+		extipNext mclock.AbsTime
+	)
+	defer func() {
+		timer.Stop()
+		for _, m := range mappings {
+			if m.expires > srv.clock.Now() {
+				srv.log.Debug("Deleting port mapping", "proto", m.protocol, "extport", m.extPort, "intport", m.port)
+				srv.NAT.DeleteMapping(m.protocol, m.extPort, m.port)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-srv.quit:
+			return
+		case m := <-srv.portMappingRegister:
+			m.extPort = m.port
+			m.info = NATMappingInfo{Name: m.name, Protocol: m.protocol, InternalPort: m.port}
+			m.nextTime = srv.clock.Now()
+			mappings[fmt.Sprintf("%s/%d", m.protocol, m.port)] = m
+		case <-timer.C():
+		}
+
+		now := srv.clock.Now()
+		if now >= extipNext {
+			ip, err := srv.NAT.ExternalIP()
+			srv.updateNATInfo(func(info *NATInfo) {
+				info.LastIPCheck = time.Now()
+				info.IPError = ""
+				if err != nil {
+					info.IPError = err.Error()
+				}
+			})
+			if err != nil {
+				srv.log.Debug("Couldn't get external IP", "interface", srv.NAT, "err", err)
+				extipNext = now.Add(extipRetryInterval)
+			} else {
+				if !ip.Equal(extip) {
+					if extip != nil {
+						srv.log.Info("NAT external IP changed", "old", extip, "new", ip)
+					} else {
+						srv.log.Debug("Got external IP from NAT", "ip", ip)
+					}
+					srv.localnode.SetStaticIP(ip)
+					srv.updateNATInfo(func(info *NATInfo) {
+						if extip != nil {
+							info.IPChanges++
+						}
+						info.ExternalIP = ip.String()
+					})
+					extip = ip
+				}
+				extipNext = now.Add(extipCheckInterval)
+			}
+		}
+		for _, m := range mappings {
+			if now >= m.nextTime {
+				srv.renewPortMapping(m, now)
+			}
+		}
+		// A restarted gateway loses all mappings, request them again right away.
+		if d, ok := srv.NAT.(nat.LossDetector); ok && d.MappingsLost() {
+			srv.log.Info("NAT gateway lost port mappings", "interface", srv.NAT)
+			for _, m := range mappings {
+				m.expires = now
+				srv.renewPortMapping(m, now)
+			}
+		}
+
+		// Publish the mapping state and schedule the next action.
+		next := extipNext
+		infos := make([]*NATMappingInfo, 0, len(mappings))
+		for _, m := range mappings {
+			m.info.Mapped = m.expires > now
+			if m.nextTime < next {
+				next = m.nextTime
+			}
+			info := m.info
+			infos = append(infos, &info)
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			if infos[i].Protocol != infos[j].Protocol {
+				return infos[i].Protocol < infos[j].Protocol
+			}
+			return infos[i].InternalPort < infos[j].InternalPort
+		})
+		srv.updateNATInfo(func(info *NATInfo) { info.Mappings = infos })
+		timer.Reset(next.Sub(now))
+	}
+}
+
+// renewPortMapping requests the given mapping from the gateway.
+func (srv *Server) renewPortMapping(m *portMapping, now mclock.AbsTime) {
+	log := srv.log.New("proto", m.protocol, "extport", m.extPort, "intport", m.port, "interface", srv.NAT)
+
+	p, err := srv.NAT.AddMapping(m.protocol, m.extPort, m.port, m.name, portMapDuration)
+	m.info.LastAttempt = time.Now()
+	if err != nil {
+		m.info.Failures++
+		m.info.LastError = err.Error()
+		m.nextTime = now.Add(portMapRetryInterval)
+		m.info.NextAttempt = m.info.LastAttempt.Add(portMapRetryInterval)
+		if m.expires > now {
+			log.Warn("Couldn't renew port mapping", "err", err, "expires", common.PrettyDuration(m.expires.Sub(now)))
+		} else {
+			log.Debug("Couldn't add port mapping", "err", err)
+		}
+		return
+	}
+	if m.expires <= now {
+		log.Info("Mapped network port", "mapped", p)
+	} else {
+		log.Trace("Renewed port mapping")
+	}
+	m.info.Renewals++
+	m.info.LastError = ""
+	m.info.ExternalPort = int(p)
+	m.expires = now.Add(portMapDuration)
+	m.nextTime = now.Add(portMapRefreshInterval)
+	m.info.NextAttempt = m.info.LastAttempt.Add(portMapRefreshInterval)
+
+	// Advertise the port assigned by the gateway.
+	if int(p) != m.extPort {
+		m.extPort = int(p)
+		if m.setPort != nil {
+			m.setPort(m.extPort)
+		}
+	}
+}
+
+// updateNATInfo modifies the published NAT state.
+func (srv *Server) updateNATInfo(fn func(*NATInfo)) {
+	srv.natLock.Lock()
+	defer srv.natLock.Unlock()
+	fn(srv.natInfo)
+}
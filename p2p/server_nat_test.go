@@ -0,0 +1,191 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestServerPortMapping(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		natm  = &fakeNAT{ip: net.IP{33, 44, 55, 66}, mapped: make(map[int]int), portOffset: 1}
+		key   = newkey()
+		db, _ = enode.OpenDB("")
+	)
+	srv := &Server{
+		Config: Config{PrivateKey: key, NAT: natm, clock: clock},
+		quit:   make(chan struct{}),
+		log:    testlog.Logger(t, log.LvlTrace),
+	}
+	srv.localnode = enode.NewLocalNode(db, key)
+	srv.setupPortMapping()
+	srv.mapPort("tcp", 30303, "test", func(port int) { srv.localnode.Set(enr.TCP(port)) })
+
+	// The port is mapped and the record updated with the assigned port.
+	info := waitNATInfo(t, srv, func(info *NATInfo) bool {
+		return len(info.Mappings) == 1 && info.Mappings[0].Mapped
+	})
+	if info.ExternalIP != "33.44.55.66" || info.Mappings[0].ExternalPort != 30304 {
+		t.Errorf("wrong NAT info: %+v %+v", info, info.Mappings[0])
+	}
+	if n := srv.localnode.Node(); !n.IP().Equal(natm.externalIP()) || n.TCP() != 30304 {
+		t.Errorf("wrong local node endpoint %v:%d", n.IP(), n.TCP())
+	}
+
+	// The gateway changes its IP and fails to renew the mapping.
+	natm.set(net.IP{1, 2, 3, 4}, errors.New("gateway failure"))
+	clock.WaitForTimers(1)
+	clock.Run(portMapRefreshInterval)
+	info = waitNATInfo(t, srv, func(info *NATInfo) bool {
+		return info.IPChanges == 1 && info.Mappings[0].Failures == 1
+	})
+	if m := info.Mappings[0]; !m.Mapped || m.LastError != "gateway failure" {
+		t.Errorf("wrong mapping state after failed renewal: %+v", m)
+	}
+	if ip := srv.localnode.Node().IP(); !ip.Equal(net.IP{1, 2, 3, 4}) {
+		t.Errorf("local node IP not updated: %v", ip)
+	}
+
+	// The lease expires.
+	clock.WaitForTimers(1)
+	clock.Run(portMapRetryInterval)
+	info = waitNATInfo(t, srv, func(info *NATInfo) bool { return info.Mappings[0].Failures == 2 })
+	if info.Mappings[0].Mapped {
+		t.Error("mapping still valid after lease expiry")
+	}
+
+	// The gateway recovers.
+	natm.set(net.IP{1, 2, 3, 4}, nil)
+	clock.WaitForTimers(1)
+	clock.Run(portMapRetryInterval)
+	info = waitNATInfo(t, srv, func(info *NATInfo) bool { return info.Mappings[0].Renewals == 2 })
+	if m := info.Mappings[0]; !m.Mapped || m.LastError != "" {
+		t.Errorf("wrong mapping state after recovery: %+v", m)
+	}
+
+	// The gateway restarts and loses the mapping. It is requested again as soon as
+	// the loss is detected, without waiting for the renewal.
+	natm.restart()
+	clock.WaitForTimers(1)
+	clock.Run(extipCheckInterval)
+	info = waitNATInfo(t, srv, func(info *NATInfo) bool { return info.Mappings[0].Renewals == 3 })
+	if m := natm.mappings(); len(m) != 1 {
+		t.Errorf("mapping not restored: %v", m)
+	}
+
+	// Mappings are deleted on shutdown.
+	close(srv.quit)
+	srv.loopWG.Wait()
+	if len(natm.mappings()) != 0 {
+		t.Errorf("mappings not deleted: %v", natm.mappings())
+	}
+}
+
+func waitNATInfo(t *testing.T, srv *Server, cond func(*NATInfo) bool) *NATInfo {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if info := srv.NATInfo(); cond(info) {
+			return info
+		}
+	}
+	t.Fatalf("timeout waiting for NAT state, have %+v", srv.NATInfo())
+	return nil
+}
+
+// fakeNAT is a NAT gateway which maps ports with a fixed offset.
+type fakeNAT struct {
+	mu         sync.Mutex
+	ip         net.IP
+	err        error
+	mapped     map[int]int
+	portOffset int
+	lost       bool
+}
+
+func (n *fakeNAT) set(ip net.IP, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ip, n.err = ip, err
+}
+
+// restart drops all mappings.
+func (n *fakeNAT) restart() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.mapped = make(map[int]int)
+	n.lost = true
+}
+
+func (n *fakeNAT) externalIP() net.IP {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ip
+}
+
+func (n *fakeNAT) mappings() map[int]int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	m := make(map[int]int)
+	for k, v := range n.mapped {
+		m[k] = v
+	}
+	return m
+}
+
+func (n *fakeNAT) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return 0, n.err
+	}
+	n.mapped[intport] = intport + n.portOffset
+	return uint16(intport + n.portOffset), nil
+}
+
+func (n *fakeNAT) DeleteMapping(protocol string, extport, intport int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.mapped, intport)
+	return nil
+}
+
+func (n *fakeNAT) ExternalIP() (net.IP, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ip, nil
+}
+
+func (n *fakeNAT) MappingsLost() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	lost := n.lost
+	n.lost = false
+	return lost
+}
+
+func (n *fakeNAT) String() string { return "fake" }
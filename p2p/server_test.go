@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/sha256"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"net"
@@ -284,6 +285,77 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+func TestServerReputation(t *testing.T) {
+	remoteKey := newkey()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remoteKey.PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	// Fill up the peer set and make one of the peers misbehave.
+	var ids []enode.ID
+	for i := 0; i < 10; i++ {
+		ids = append(ids, randomID())
+		if err := srv.checkpoint(newconn(ids[i]), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	srv.ReportPeer(ids[0], ReputationUselessResponse)
+
+	// A neutral inbound connection should evict the misbehaving peer, but only
+	// that one.
+	candidate := randomID()
+	if err := srv.checkpoint(newconn(candidate), srv.checkpointPostHandshake); err != nil {
+		t.Fatalf("unexpected error for evicting conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn(candidate), srv.checkpointPostHandshake); err != nil {
+		t.Fatalf("unexpected error for repeated check of evicting conn: %v", err)
+	}
+	// Once the evicted peer is gone and the candidate took its slot, there is no
+	// other peer to evict.
+	for deadline := time.Now().Add(5 * time.Second); srv.PeerCount() != 9; {
+		if time.Now().After(deadline) {
+			t.Fatal("evicted peer not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := srv.checkpoint(newconn(candidate), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add evicting conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn(randomID()), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Fatalf("wrong error for second conn: %v", err)
+	}
+	// Ban a node by reporting it repeatedly, it must not be accepted anymore.
+	banned := randomID()
+	for i := 0; i < 3; i++ {
+		srv.ReportPeer(banned, ReputationInvalidBlock)
+	}
+	if !srv.reputation.banned(banned) {
+		t.Fatal("node not banned")
+	}
+	if err := srv.checkpoint(newconn(banned), srv.checkpointPostHandshake); err != DiscUselessPeer {
+		t.Fatalf("wrong error for banned conn: %v", err)
+	}
+	if scores := srv.PeerScores(); len(scores) != 2 || scores[0].ID != banned.String() || scores[0].BannedUntil == nil {
+		t.Fatalf("wrong peer scores: %v", scores)
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -605,3 +677,37 @@ func syncAddPeer(srv *Server, node *enode.Node) bool {
 		}
 	}
 }
+
+// echoProtocol creates a protocol which answers messages with code 0 by sending
+// the same payload with code 1. Received messages are reported on ch. If initiate
+// is set, the protocol starts by sending its name with code 0.
+func echoProtocol(name string, initiate bool, ch chan<- string) Protocol {
+	return Protocol{
+		Name:    name,
+		Version: 1,
+		Length:  4,
+		Run: func(p *Peer, rw MsgReadWriter) error {
+			if initiate {
+				if err := Send(rw, 0, name); err != nil {
+					return err
+				}
+			}
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var s string
+				if err := msg.Decode(&s); err != nil {
+					return err
+				}
+				if msg.Code == 0 {
+					if err := Send(rw, 1, s); err != nil {
+						return err
+					}
+				}
+				ch <- fmt.Sprintf("%s:%d", s, msg.Code)
+			}
+		},
+	}
+}
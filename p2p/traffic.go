@@ -0,0 +1,252 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"golang.org/x/time/rate"
+)
+
+// uploadBurst is the amount of data a rate limited connection may send at once.
+// Larger messages are allowed, but delay the following ones accordingly.
+const uploadBurst = 256 * 1024
+
+// TrafficStats is the amount of data exchanged with a peer.
+type TrafficStats struct {
+	Ingress        uint64 `json:"ingress"`        // Bytes received
+	Egress         uint64 `json:"egress"`         // Bytes sent
+	IngressPackets uint64 `json:"ingressPackets"` // Messages received
+	EgressPackets  uint64 `json:"egressPackets"`  // Messages sent
+}
+
+// PeerTrafficInfo is the traffic summary of a connected peer.
+type PeerTrafficInfo struct {
+	Session   TrafficStats            `json:"session"`            // Traffic of the current connection
+	Protocols map[string]TrafficStats `json:"protocols"`          // Traffic of the current connection by subprotocol
+	Lifetime  *TrafficStats           `json:"lifetime,omitempty"` // Traffic of all connections to the peer, including the current one
+}
+
+// trafficCounter counts the traffic of a connection or a subprotocol.
+type trafficCounter struct {
+	ingress, egress               uint64
+	ingressPackets, egressPackets uint64
+}
+
+func (c *trafficCounter) addIngress(size uint32) {
+	atomic.AddUint64(&c.ingress, uint64(size))
+	atomic.AddUint64(&c.ingressPackets, 1)
+}
+
+func (c *trafficCounter) addEgress(size uint32) {
+	atomic.AddUint64(&c.egress, uint64(size))
+	atomic.AddUint64(&c.egressPackets, 1)
+}
+
+func (c *trafficCounter) stats() TrafficStats {
+	return TrafficStats{
+		Ingress:        atomic.LoadUint64(&c.ingress),
+		Egress:         atomic.LoadUint64(&c.egress),
+		IngressPackets: atomic.LoadUint64(&c.ingressPackets),
+		EgressPackets:  atomic.LoadUint64(&c.egressPackets),
+	}
+}
+
+// peerTraffic accounts the traffic of a peer connection and enforces its upload
+// limits. The limits are applied on the write path of the transport, which blocks
+// before a message is sent until the limiters allow its size.
+type peerTraffic struct {
+	total     trafficCounter
+	protocols map[string]*trafficCounter // immutable after creation
+	limiters  []*rate.Limiter
+
+	ctx    context.Context // canceled when the connection is closed
+	cancel context.CancelFunc
+
+	// Per-peer metrics, registered while the peer is connected.
+	ingressMeter, egressMeter     string
+	ingressCounter, egressCounter metrics.Counter
+}
+
+func newPeerTraffic(id enode.ID, protocols map[string]*protoRW, limiters []*rate.Limiter) *peerTraffic {
+	t := &peerTraffic{
+		protocols: make(map[string]*trafficCounter, len(protocols)),
+		limiters:  limiters,
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	for name := range protocols {
+		t.protocols[name] = new(trafficCounter)
+	}
+	if metrics.Enabled {
+		t.ingressMeter = fmt.Sprintf("%s/peer/%x", ingressMeterName, id[:8])
+		t.egressMeter = fmt.Sprintf("%s/peer/%x", egressMeterName, id[:8])
+		t.ingressCounter = metrics.GetOrRegisterCounter(t.ingressMeter, nil)
+		t.egressCounter = metrics.GetOrRegisterCounter(t.egressMeter, nil)
+	}
+	return t
+}
+
+// ingress accounts a message received for the given subprotocol. Base protocol
+// messages are passed with an empty protocol name.
+func (t *peerTraffic) ingress(proto string, size uint32) {
+	if t == nil {
+		return
+	}
+	t.total.addIngress(size)
+	if c := t.protocols[proto]; c != nil {
+		c.addIngress(size)
+	}
+	if t.ingressCounter != nil {
+		t.ingressCounter.Inc(int64(size))
+	}
+}
+
+// egress accounts a message sent for the given subprotocol.
+func (t *peerTraffic) egress(proto string, size uint32) {
+	if t == nil {
+		return
+	}
+	t.total.addEgress(size)
+	if c := t.protocols[proto]; c != nil {
+		c.addEgress(size)
+	}
+	if t.egressCounter != nil {
+		t.egressCounter.Inc(int64(size))
+	}
+}
+
+// throttle blocks until all limiters allow the given amount of data. It must not
+// be called with the transport's write lock held. The wait is aborted when the
+// connection is closed.
+func (t *peerTraffic) throttle(size int) error {
+	if t == nil || len(t.limiters) == 0 {
+		return nil
+	}
+	var (
+		now          = time.Now()
+		delay        time.Duration
+		reservations []*rate.Reservation
+	)
+	for _, lim := range t.limiters {
+		// Messages may exceed the burst size, reserve them piecewise. Every
+		// reservation queues after the previous one, so the last delay is the
+		// total wait.
+		for left := size; left > 0; left -= lim.Burst() {
+			n := left
+			if n > lim.Burst() {
+				n = lim.Burst()
+			}
+			r := lim.ReserveN(now, n)
+			reservations = append(reservations, r)
+			if d := r.DelayFrom(now); d > delay {
+				delay = d
+			}
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.ctx.Done():
+		// Hand the bandwidth back, other peers may be waiting for the
+		// shared limiter. Reservations only return their tokens if no
+		// later ones exist, so cancel them in reverse order.
+		for i := len(reservations) - 1; i >= 0; i-- {
+			reservations[i].Cancel()
+		}
+		return net.ErrClosed
+	}
+}
+
+// stop aborts pending and future waits for the upload limits. It is called when
+// the connection is closed.
+func (t *peerTraffic) stop() {
+	if t != nil {
+		t.cancel()
+	}
+}
+
+// info returns the traffic summary of the current connection.
+func (t *peerTraffic) info() *PeerTrafficInfo {
+	info := &PeerTrafficInfo{
+		Session:   t.total.stats(),
+		Protocols: make(map[string]TrafficStats, len(t.protocols)),
+	}
+	for name, c := range t.protocols {
+		info.Protocols[name] = c.stats()
+	}
+	return info
+}
+
+// close stops throttling and unregisters the per-peer metrics.
+func (t *peerTraffic) close() {
+	t.cancel()
+	if t.ingressMeter != "" {
+		metrics.DefaultRegistry.Unregister(t.ingressMeter)
+		metrics.DefaultRegistry.Unregister(t.egressMeter)
+	}
+}
+
+// newUploadLimiter creates a limiter for the given rate in bytes per second. It
+// returns nil if the rate is zero, i.e. unlimited.
+func newUploadLimiter(bytesPerSecond int) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := uploadBurst
+	if bytesPerSecond > burst {
+		burst = bytesPerSecond
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// trafficTransport is implemented by transports which account the traffic of
+// their connection and enforce its upload limits.
+type trafficTransport interface {
+	setTraffic(t *peerTraffic)
+}
+
+// toNodeTraffic converts traffic statistics into their database representation.
+func (s TrafficStats) toNodeTraffic() enode.NodeTraffic {
+	return enode.NodeTraffic{
+		Ingress:        s.Ingress,
+		Egress:         s.Egress,
+		IngressPackets: s.IngressPackets,
+		EgressPackets:  s.EgressPackets,
+	}
+}
+
+// add returns the sum of two traffic statistics.
+func (s TrafficStats) add(t enode.NodeTraffic) TrafficStats {
+	return TrafficStats{
+		Ingress:        s.Ingress + t.Ingress,
+		Egress:         s.Egress + t.Egress,
+		IngressPackets: s.IngressPackets + t.IngressPackets,
+		EgressPackets:  s.EgressPackets + t.EgressPackets,
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"golang.org/x/time/rate"
+)
+
+func TestPeerTrafficThrottle(t *testing.T) {
+	const limit = 4 * 1024 * 1024
+
+	protos := map[string]*protoRW{"a": {}}
+	traffic := newPeerTraffic(enode.ID{}, protos, []*rate.Limiter{newUploadLimiter(limit)})
+
+	// The first message fits into the burst, the second one has to wait for
+	// half of its size to be replenished.
+	start := time.Now()
+	traffic.throttle(limit / 2)
+	traffic.egress("a", limit/2)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("message within burst delayed by %v", elapsed)
+	}
+	traffic.throttle(limit)
+	traffic.egress("a", limit)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("message exceeding the limit not delayed enough: %v", elapsed)
+	}
+	traffic.ingress("", 10)
+
+	info := traffic.info()
+	want := TrafficStats{Ingress: 10, Egress: limit/2 + limit, IngressPackets: 1, EgressPackets: 2}
+	if info.Session != want {
+		t.Errorf("wrong session traffic: have %+v, want %+v", info.Session, want)
+	}
+	want.Ingress, want.IngressPackets = 0, 0
+	if info.Protocols["a"] != want {
+		t.Errorf("wrong protocol traffic: have %+v, want %+v", info.Protocols["a"], want)
+	}
+}
+
+func TestPeerTrafficThrottleStop(t *testing.T) {
+	const limit = 1024 * 1024
+
+	limiter := newUploadLimiter(limit)
+	traffic := newPeerTraffic(enode.ID{}, nil, []*rate.Limiter{limiter})
+	traffic.throttle(limit)
+
+	// A message of ten times the limit waits for ten seconds, stopping must
+	// abort the wait and return the reserved bandwidth.
+	errc := make(chan error, 1)
+	go func() { errc <- traffic.throttle(10 * limit) }()
+	time.Sleep(50 * time.Millisecond)
+	traffic.stop()
+	select {
+	case err := <-errc:
+		if err != net.ErrClosed {
+			t.Fatalf("wrong error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait not aborted")
+	}
+	now := time.Now()
+	if delay := limiter.ReserveN(now, limit/4).DelayFrom(now); delay > time.Second {
+		t.Fatalf("reserved bandwidth not returned, next message delayed by %v", delay)
+	}
+	if err := traffic.throttle(1); err != net.ErrClosed {
+		t.Fatalf("wrong error after stop: %v", err)
+	}
+}
+
+// This test checks that the traffic of peers is reported by PeersInfo and added
+// to the node totals when the peer disconnects.
+func TestServerTraffic(t *testing.T) {
+	var (
+		recv1 = make(chan string, 10)
+		recv2 = make(chan string, 10)
+	)
+	srv1 := &Server{Config: Config{
+		PrivateKey:  newkey(),
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Protocols:   []Protocol{echoProtocol("a", true, recv1), echoProtocol("b", false, recv1)},
+		Logger:      testlog.Logger(t, log.LvlTrace).New("server", "1"),
+	}}
+	srv2 := &Server{Config: Config{
+		PrivateKey:        newkey(),
+		MaxPeers:          1,
+		NoDiscovery:       true,
+		NoDial:            true,
+		ListenAddr:        "127.0.0.1:0",
+		MaxPeerUploadRate: 1024 * 1024,
+		Protocols:         []Protocol{echoProtocol("a", false, recv2), echoProtocol("b", false, recv2)},
+		Logger:            testlog.Logger(t, log.LvlTrace).New("server", "2"),
+	}}
+	srv1.Start()
+	defer srv1.Stop()
+	srv2.Start()
+	defer srv2.Stop()
+
+	if !syncAddPeer(srv1, srv2.Self()) {
+		t.Fatal("peer not connected")
+	}
+	// Wait for the message of protocol "a" to be echoed.
+	for _, ch := range []chan string{recv2, recv1} {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+	infos := srv1.PeersInfo()
+	if len(infos) != 1 {
+		t.Fatalf("wrong number of peers: %d", len(infos))
+	}
+	traffic := infos[0].Traffic
+	if a := traffic.Protocols["a"]; a.EgressPackets != 1 || a.IngressPackets != 1 || a.Egress == 0 || a.Ingress == 0 {
+		t.Errorf("wrong traffic of protocol a: %+v", a)
+	}
+	if b := traffic.Protocols["b"]; b != (TrafficStats{}) {
+		t.Errorf("wrong traffic of protocol b: %+v", b)
+	}
+	if traffic.Session.Egress < traffic.Protocols["a"].Egress {
+		t.Errorf("session egress %d below protocol egress", traffic.Session.Egress)
+	}
+	if traffic.Lifetime == nil || *traffic.Lifetime != traffic.Session {
+		t.Errorf("wrong lifetime traffic: %+v", traffic.Lifetime)
+	}
+
+	// Disconnect and check the traffic was stored.
+	var (
+		ch  = make(chan *PeerEvent, 1)
+		sub = srv1.SubscribeEvents(ch)
+	)
+	defer sub.Unsubscribe()
+	srv1.RemovePeer(srv2.Self())
+	for ev := range ch {
+		if ev.Type == PeerEventTypeDrop {
+			break
+		}
+	}
+	if stored := srv1.nodedb.Traffic(srv2.Self().ID()); stored.Egress < traffic.Session.Egress || stored.EgressPackets == 0 {
+		t.Errorf("wrong stored traffic: %+v", stored)
+	}
+}
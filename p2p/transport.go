@@ -49,6 +49,7 @@ type rlpxTransport struct {
 	rmu, wmu sync.Mutex
 	wbuf     bytes.Buffer
 	conn     *rlpx.Conn
+	traffic  *peerTraffic // accounting and upload limits, set once the peer is created
 }
 
 func newRLPX(conn net.Conn, dialDest *ecdsa.PublicKey) transport {
@@ -79,6 +80,11 @@ func (t *rlpxTransport) ReadMsg() (Msg, error) {
 }
 
 func (t *rlpxTransport) WriteMsg(msg Msg) error {
+	// Wait for the upload limits before taking the lock, closing the
+	// connection must not be held up by the wait.
+	if err := t.traffic.throttle(int(msg.Size)); err != nil {
+		return err
+	}
 	t.wmu.Lock()
 	defer t.wmu.Unlock()
 
@@ -97,15 +103,34 @@ func (t *rlpxTransport) WriteMsg(msg Msg) error {
 
 	// Set metrics.
 	msg.meterSize = size
+	markEgress(msg)
+	t.traffic.egress(msg.meterCap.Name, size)
+	return nil
+}
+
+func (t *rlpxTransport) setTraffic(traffic *peerTraffic) {
+	t.traffic = traffic
+}
+
+// streamTransport is implemented by transports which carry subprotocols on
+// separate streams. They need to know where each protocol's message codes start.
+type streamTransport interface {
+	setProtocolOffsets(offsets []uint64)
+}
+
+// markEgress updates the egress meters of the subprotocol the message belongs to.
+func markEgress(msg Msg) {
 	if metrics.Enabled && msg.meterCap.Name != "" { // don't meter non-subprotocol messages
-		m := fmt.Sprintf("%s/%s/%d/%#02x", egressMeterName, msg.meterCap.Name, msg.meterCap.Version, msg.meterCode)
+		m := fmt.Sprintf("%s/%s/%d", egressMeterName, msg.meterCap.Name, msg.meterCap.Version)
+		metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
+		m = fmt.Sprintf("%s/%#02x", m, msg.meterCode)
 		metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
 		metrics.GetOrRegisterMeter(m+"/packets", nil).Mark(1)
 	}
-	return nil
 }
 
 func (t *rlpxTransport) close(err error) {
+	t.traffic.stop()
 	t.wmu.Lock()
 	defer t.wmu.Unlock()
 
@@ -0,0 +1,546 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/golang/snappy"
+)
+
+// The QUIC transport is an experimental alternative to plain RLPx over TCP.
+//
+// A QUIC connection starts with a bidirectional control stream opened by the
+// dialer. The control stream runs a regular RLPx session: the encryption handshake
+// proves the node identities, and the devp2p protocol handshake negotiates the
+// capabilities just like over TCP. Right after the encryption handshake, both ends
+// exchange the TLS keying material of the QUIC session over the authenticated RLPx
+// session, binding the QUIC connection to the node keys.
+//
+// Base protocol messages (ping, pong, disconnect) keep using the control stream.
+// Every negotiated subprotocol gets a unidirectional stream in each direction, so
+// a large or slowly delivered message of one protocol (e.g. a snap response) can't
+// hold up the messages of another (e.g. eth block propagation).
+//
+// The QUIC implementation itself is provided by the user through the QUICEndpoint
+// interface. Package github.com/ethereum/go-ethereum/p2p/quic implements it using
+// quic-go. It lives in a separate module because quic-go needs a newer Go release
+// than the rest of the code base.
+
+const (
+	quicBindingLabel  = "EXPORTER-devp2p-quic-binding"
+	quicBindingMsg    = 0x0f // sent on the control stream before the protocol handshake
+	maxQUICFrameSize  = 0xffffff
+	quicReadQueueSize = 16
+)
+
+var (
+	errQUICBinding       = errors.New("QUIC session binding mismatch")
+	errQUICFrameTooLarge = errors.New("QUIC message frame too large")
+)
+
+// QUICEndpoint is a UDP socket on which QUIC connections are accepted and dialed.
+type QUICEndpoint interface {
+	// LocalAddr returns the address of the socket.
+	LocalAddr() *net.UDPAddr
+	// Accept waits for the next inbound connection. It fails once the endpoint
+	// is closed.
+	Accept() (QUICConn, error)
+	// Dial connects to the given address.
+	Dial(ctx context.Context, addr *net.UDPAddr) (QUICConn, error)
+	Close() error
+}
+
+// QUICConn is a QUIC connection. Once the remote end has closed the connection
+// with an error code, operations on the connection and its streams must fail with
+// the DiscReason of that code.
+type QUICConn interface {
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+
+	// ExportKeyingMaterial exports keying material of the TLS session of the
+	// connection, as defined in RFC 5705.
+	ExportKeyingMaterial(label string, length int) ([]byte, error)
+
+	// The control stream is the only bidirectional stream.
+	AcceptStream(ctx context.Context) (QUICStream, error)
+	OpenStream(ctx context.Context) (QUICStream, error)
+
+	// Subprotocols use unidirectional streams.
+	AcceptUniStream(ctx context.Context) (io.Reader, error)
+	OpenUniStream() (QUICSendStream, error)
+
+	// CloseWithError closes the connection, sending the error code and reason
+	// to the remote end.
+	CloseWithError(code uint64, reason string) error
+}
+
+// QUICSendStream is a unidirectional QUIC stream.
+type QUICSendStream interface {
+	io.Writer
+	SetWriteDeadline(t time.Time) error
+}
+
+// QUICStream is a bidirectional QUIC stream.
+type QUICStream interface {
+	io.Reader
+	QUICSendStream
+	SetReadDeadline(t time.Time) error
+	SetDeadline(t time.Time) error
+}
+
+// acceptQUICControl waits for the dialer to open the control stream of the given
+// inbound connection.
+func acceptQUICControl(qconn QUICConn) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	stream, err := qconn.AcceptStream(ctx)
+	if err != nil {
+		qconn.CloseWithError(uint64(DiscNetworkError), "")
+		return nil, err
+	}
+	return &quicConn{QUICStream: stream, conn: qconn}, nil
+}
+
+// dialQUIC connects to the given address and opens the control stream.
+func dialQUIC(ctx context.Context, e QUICEndpoint, addr *net.UDPAddr) (net.Conn, error) {
+	qconn, err := e.Dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := qconn.OpenStream(ctx)
+	if err != nil {
+		qconn.CloseWithError(uint64(DiscNetworkError), "")
+		return nil, err
+	}
+	return &quicConn{QUICStream: stream, conn: qconn}, nil
+}
+
+// quicConn is the control stream of a QUIC connection. It implements net.Conn so
+// QUIC connections can go through the same setup path as TCP connections.
+type quicConn struct {
+	QUICStream
+	conn QUICConn
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// Close tears down the whole QUIC connection, not just the control stream.
+func (c *quicConn) Close() error {
+	return c.conn.CloseWithError(uint64(DiscNetworkError), "")
+}
+
+// newTransport creates the transport for the given connection. QUIC connections
+// get the multi-stream transport, everything else runs plain RLPx.
+func newTransport(conn net.Conn, dialDest *ecdsa.PublicKey) transport {
+	if qc, ok := conn.(*quicConn); ok {
+		return newQUICTransport(qc, dialDest)
+	}
+	return newRLPX(conn, dialDest)
+}
+
+// quicRead is a message or error read from any of the streams of a connection.
+type quicRead struct {
+	msg Msg
+	err error
+}
+
+// quicTransport is the transport of QUIC connections. The handshakes and the base
+// protocol run as RLPx over the control stream, subprotocols use a stream each.
+type quicTransport struct {
+	*rlpxTransport // control stream
+
+	conn   QUICConn
+	snappy bool // set by the protocol handshake, before any stream is used
+
+	smu     sync.Mutex
+	offsets []uint64 // start codes of the matched subprotocols, ascending
+	streams map[int]*quicSendStream
+
+	in        chan quicRead
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newQUICTransport(conn *quicConn, dialDest *ecdsa.PublicKey) transport {
+	return &quicTransport{
+		rlpxTransport: newRLPX(conn, dialDest).(*rlpxTransport),
+		conn:          conn.conn,
+		streams:       make(map[int]*quicSendStream),
+		in:            make(chan quicRead, quicReadQueueSize),
+		closing:       make(chan struct{}),
+	}
+}
+
+func (t *quicTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	pub, err := t.rlpxTransport.doEncHandshake(prv)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure both ends see the same QUIC session. A man in the middle could
+	// relay the RLPx handshake, but it can't make the two TLS sessions it
+	// terminates export the same keying material.
+	binding, err := t.conn.ExportKeyingMaterial(quicBindingLabel, 32)
+	if err != nil {
+		return nil, err
+	}
+	werr := make(chan error, 1)
+	go func() {
+		_, err := t.rlpxTransport.conn.Write(quicBindingMsg, binding)
+		werr <- err
+	}()
+	code, data, _, err := t.rlpxTransport.conn.Read()
+	if err != nil {
+		<-werr
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, err
+	}
+	if code != quicBindingMsg || !bytes.Equal(data, binding) {
+		return nil, errQUICBinding
+	}
+	return pub, nil
+}
+
+func (t *quicTransport) doProtoHandshake(our *protoHandshake) (*protoHandshake, error) {
+	their, err := t.rlpxTransport.doProtoHandshake(our)
+	if err != nil {
+		return nil, err
+	}
+	t.snappy = their.Version >= snappyProtocolVersion
+
+	// From here on, messages can arrive on any stream.
+	go t.readControl()
+	go t.acceptStreams()
+	return their, nil
+}
+
+func (t *quicTransport) setProtocolOffsets(offsets []uint64) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	t.offsets = append([]uint64(nil), offsets...)
+	sort.Slice(t.offsets, func(i, j int) bool { return t.offsets[i] < t.offsets[j] })
+}
+
+func (t *quicTransport) ReadMsg() (Msg, error) {
+	select {
+	case r := <-t.in:
+		return r.msg, r.err
+	case <-t.closing:
+		return Msg{}, net.ErrClosed
+	}
+}
+
+func (t *quicTransport) WriteMsg(msg Msg) error {
+	stream, err := t.sendStream(msg.Code)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return t.rlpxTransport.WriteMsg(msg)
+	}
+	if err := t.traffic.throttle(int(msg.Size)); err != nil {
+		return err
+	}
+	size, err := stream.write(msg, t.snappy)
+	if err != nil {
+		return err
+	}
+	msg.meterSize = size
+	markEgress(msg)
+	t.traffic.egress(msg.meterCap.Name, size)
+	return nil
+}
+
+// sendStream returns the stream carrying the given message code, opening it if
+// needed. It returns nil for base protocol messages, which use the control stream.
+func (t *quicTransport) sendStream(code uint64) (*quicSendStream, error) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	index := sort.Search(len(t.offsets), func(i int) bool { return t.offsets[i] > code }) - 1
+	if index < 0 {
+		return nil, nil
+	}
+	if s := t.streams[index]; s != nil {
+		return s, nil
+	}
+	s, err := t.conn.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	t.streams[index] = &quicSendStream{stream: s}
+	return t.streams[index], nil
+}
+
+func (t *quicTransport) close(err error) {
+	t.closeOnce.Do(func() {
+		close(t.closing)
+		t.traffic.stop()
+		// The disconnect reason travels in the connection close frame, where it
+		// can't get lost behind data still buffered on the streams.
+		code := DiscNetworkError
+		if r, ok := err.(DiscReason); ok {
+			code = r
+		}
+		t.conn.CloseWithError(uint64(code), fmt.Sprint(err))
+	})
+}
+
+// deliver queues a message or error for ReadMsg. It returns false if the
+// transport is closed.
+func (t *quicTransport) deliver(msg Msg, err error) bool {
+	select {
+	case t.in <- quicRead{msg, err}:
+		return err == nil
+	case <-t.closing:
+		return false
+	}
+}
+
+// readControl reads base protocol messages from the control stream.
+func (t *quicTransport) readControl() {
+	for {
+		msg, err := t.rlpxTransport.ReadMsg()
+		if !t.deliver(msg, err) {
+			return
+		}
+	}
+}
+
+// acceptStreams accepts the subprotocol streams opened by the remote end.
+func (t *quicTransport) acceptStreams() {
+	for {
+		stream, err := t.conn.AcceptUniStream(context.Background())
+		if err != nil {
+			t.deliver(Msg{}, err)
+			return
+		}
+		go t.readStream(stream)
+	}
+}
+
+// readStream reads subprotocol messages from a stream until it is closed.
+func (t *quicTransport) readStream(stream io.Reader) {
+	r := bufio.NewReader(stream)
+	for {
+		msg, err := readQUICFrame(r, t.snappy)
+		if err == io.EOF {
+			return // stream ended cleanly
+		}
+		if !t.deliver(msg, err) {
+			return
+		}
+	}
+}
+
+// quicSendStream is the outgoing stream of a single subprotocol. Messages are
+// framed as the message code and payload size, both uvarint encoded, followed by
+// the (possibly snappy compressed) payload.
+type quicSendStream struct {
+	mu     sync.Mutex
+	stream QUICSendStream
+	buf    []byte
+}
+
+func (s *quicSendStream) write(msg Msg, compress bool) (uint32, error) {
+	payload, err := io.ReadAll(io.LimitReader(msg.Payload, int64(msg.Size)))
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) > maxQUICFrameSize {
+		return 0, errQUICFrameTooLarge
+	}
+	if compress {
+		payload = snappy.Encode(nil, payload)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = appendUvarint(s.buf[:0], msg.Code)
+	s.buf = appendUvarint(s.buf, uint64(len(payload)))
+	s.buf = append(s.buf, payload...)
+
+	s.stream.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	if _, err := s.stream.Write(s.buf); err != nil {
+		return 0, err
+	}
+	return uint32(len(s.buf)), nil
+}
+
+// readQUICFrame reads a single message from a subprotocol stream. It returns
+// io.EOF if the stream ends before the next message.
+func readQUICFrame(r *bufio.Reader, compressed bool) (Msg, error) {
+	code, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, unexpectedEOF(err)
+	}
+	if size > maxQUICFrameSize {
+		return Msg{}, errQUICFrameTooLarge
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Msg{}, unexpectedEOF(err)
+	}
+	wireSize := uint32(size) + uint32(uvarintSize(code)+uvarintSize(size))
+	if compressed {
+		actualSize, err := snappy.DecodedLen(data)
+		if err != nil {
+			return Msg{}, err
+		}
+		if actualSize > maxQUICFrameSize {
+			return Msg{}, errQUICFrameTooLarge
+		}
+		if data, err = snappy.Decode(nil, data); err != nil {
+			return Msg{}, err
+		}
+	}
+	return Msg{
+		ReceivedAt: time.Now(),
+		Code:       code,
+		Size:       uint32(len(data)),
+		meterSize:  wireSize,
+		Payload:    bytes.NewReader(data),
+	}, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func uvarintSize(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], x)]...)
+}
+
+// setupQUIC advertises the QUIC endpoint and starts accepting connections on it.
+func (srv *Server) setupQUIC() {
+	addr := srv.QUIC.LocalAddr()
+	srv.localnode.Set(enr.QUIC(addr.Port))
+	if !addr.IP.IsLoopback() && srv.NAT != nil {
+		srv.mapPort("udp", addr.Port, "ethereum quic", func(port int) {
+			srv.localnode.Set(enr.QUIC(port))
+		})
+	}
+
+	srv.loopWG.Add(1)
+	go srv.quicListenLoop()
+}
+
+// quicListenLoop runs in its own goroutine and accepts inbound QUIC connections.
+func (srv *Server) quicListenLoop() {
+	srv.log.Debug("QUIC listener up", "addr", srv.QUIC.LocalAddr())
+
+	// The slots channel limits accepts of new connections.
+	tokens := defaultMaxPendingPeers
+	if srv.MaxPendingPeers > 0 {
+		tokens = srv.MaxPendingPeers
+	}
+	slots := make(chan struct{}, tokens)
+	for i := 0; i < tokens; i++ {
+		slots <- struct{}{}
+	}
+	defer srv.loopWG.Done()
+	defer func() {
+		for i := 0; i < cap(slots); i++ {
+			<-slots
+		}
+	}()
+
+	for {
+		<-slots
+		qconn, err := srv.QUIC.Accept()
+		if err != nil {
+			srv.log.Debug("QUIC accept error", "err", err)
+			slots <- struct{}{}
+			return
+		}
+		if err := srv.checkInboundConn(netutil.AddrIP(qconn.RemoteAddr())); err != nil {
+			srv.log.Debug("Rejected inbound QUIC connection", "addr", qconn.RemoteAddr(), "err", err)
+			qconn.CloseWithError(uint64(DiscUselessPeer), err.Error())
+			slots <- struct{}{}
+			continue
+		}
+		srv.log.Trace("Accepted QUIC connection", "addr", qconn.RemoteAddr())
+		go func() {
+			if fd, err := acceptQUICControl(qconn); err == nil {
+				srv.SetupConn(fd, inboundConn, nil)
+			}
+			slots <- struct{}{}
+		}()
+	}
+}
+
+// quicDialer is a NodeDialer which prefers the QUIC transport for nodes that
+// advertise it in their record. Other nodes, and nodes which can't be reached over
+// QUIC, are dialed using the fallback dialer.
+type quicDialer struct {
+	endpoint QUICEndpoint
+	fallback NodeDialer
+	log      log.Logger
+}
+
+// wrapQUICDialer wraps the given dialer to prefer the QUIC endpoint of the server.
+func (srv *Server) wrapQUICDialer(fallback NodeDialer) NodeDialer {
+	return quicDialer{endpoint: srv.QUIC, fallback: fallback, log: srv.log}
+}
+
+func (d quicDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	var port enr.QUIC
+	if dest.IP() != nil && dest.Load(&port) == nil && port != 0 {
+		addr := &net.UDPAddr{IP: dest.IP(), Port: int(port)}
+		qctx, cancel := context.WithTimeout(ctx, defaultDialTimeout)
+		conn, err := dialQUIC(qctx, d.endpoint, addr)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		d.log.Trace("QUIC dial failed, falling back to TCP", "id", dest.ID(), "addr", addr, "err", err)
+	}
+	return d.fallback.Dial(ctx, dest)
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+type recordingDialer struct {
+	dialed []enode.ID
+}
+
+func (d *recordingDialer) Dial(ctx context.Context, n *enode.Node) (net.Conn, error) {
+	d.dialed = append(d.dialed, n.ID())
+	return nil, errors.New("fallback")
+}
+
+// unreachableQUICEndpoint is a QUIC endpoint which fails all dials.
+type unreachableQUICEndpoint struct {
+	QUICEndpoint
+	dialed []*net.UDPAddr
+}
+
+func (e *unreachableQUICEndpoint) Dial(ctx context.Context, addr *net.UDPAddr) (QUICConn, error) {
+	e.dialed = append(e.dialed, addr)
+	return nil, errors.New("unreachable")
+}
+
+// This test checks that the dialer falls back to TCP when the QUIC endpoint of the
+// remote node can't be reached, and that nodes without a QUIC port go straight to TCP.
+func TestQUICDialFallback(t *testing.T) {
+	var (
+		endpoint = new(unreachableQUICEndpoint)
+		fallback = new(recordingDialer)
+		dialer   = quicDialer{endpoint: endpoint, fallback: fallback, log: log.Root()}
+		key      = newkey()
+		tcpOnly  = enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+		withQUIC = newNodeWithQUIC(30304)
+	)
+	if _, err := dialer.Dial(context.Background(), tcpOnly); err == nil || err.Error() != "fallback" {
+		t.Fatalf("wrong error for TCP-only node: %v", err)
+	}
+	if len(endpoint.dialed) != 0 {
+		t.Fatalf("TCP-only node dialed over QUIC: %v", endpoint.dialed)
+	}
+	if _, err := dialer.Dial(context.Background(), withQUIC); err == nil || err.Error() != "fallback" {
+		t.Fatalf("wrong error for unreachable QUIC node: %v", err)
+	}
+	if len(endpoint.dialed) != 1 || endpoint.dialed[0].Port != 30304 {
+		t.Fatalf("wrong QUIC dials: %v", endpoint.dialed)
+	}
+	if len(fallback.dialed) != 2 || fallback.dialed[0] != tcpOnly.ID() || fallback.dialed[1] != withQUIC.ID() {
+		t.Fatalf("wrong fallback dials: %v", fallback.dialed)
+	}
+}
+
+func newNodeWithQUIC(port int) *enode.Node {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+	ln := enode.NewLocalNode(db, newkey())
+	ln.SetStaticIP(net.IP{127, 0, 0, 1})
+	ln.Set(enr.TCP(30303))
+	ln.Set(enr.QUIC(port))
+	return ln.Node()
+}
+
+// This test checks that messages written to a subprotocol stream are read back
+// with their code, size and payload intact.
+func TestQUICFrame(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var (
+			buf     bytes.Buffer
+			stream  = &quicSendStream{stream: nopDeadlineWriter{&buf}}
+			payload = bytes.Repeat([]byte{0x42}, 300)
+		)
+		size, err := stream.write(Msg{Code: 0x21, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)}, compress)
+		if err != nil {
+			t.Fatalf("compress=%t: write failed: %v", compress, err)
+		}
+		if int(size) != buf.Len() {
+			t.Fatalf("compress=%t: wrong written size %d, have %d bytes", compress, size, buf.Len())
+		}
+		r := bufio.NewReader(&buf)
+		msg, err := readQUICFrame(r, compress)
+		if err != nil {
+			t.Fatalf("compress=%t: read failed: %v", compress, err)
+		}
+		data, _ := io.ReadAll(msg.Payload)
+		if msg.Code != 0x21 || msg.Size != uint32(len(payload)) || msg.meterSize != size || !bytes.Equal(data, payload) {
+			t.Fatalf("compress=%t: wrong message: code %#x size %d wire size %d", compress, msg.Code, msg.Size, msg.meterSize)
+		}
+		if _, err := readQUICFrame(r, compress); err != io.EOF {
+			t.Fatalf("compress=%t: wrong error at end of stream: %v", compress, err)
+		}
+	}
+}
+
+type nopDeadlineWriter struct{ io.Writer }
+
+func (nopDeadlineWriter) SetWriteDeadline(time.Time) error { return nil }
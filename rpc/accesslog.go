@@ -0,0 +1,128 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// AccessLogEntry is a line of the RPC access log.
+type AccessLogEntry struct {
+	Time        time.Time `json:"time"`
+	Method      string    `json:"method"`
+	Params      string    `json:"params"`     // digest of the call parameters
+	ParamsSize  int       `json:"paramsSize"` // size of the call parameters
+	Duration    float64   `json:"duration"`   // serving time in seconds
+	RespSize    int       `json:"respSize"`   // size of the result
+	ErrorCode   int       `json:"errorCode,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Transport   string    `json:"transport"`
+	RemoteAddr  string    `json:"remoteAddr,omitempty"`
+	AuthSubject string    `json:"authSubject,omitempty"`
+}
+
+// AccessLogger is a middleware which writes every call served by the server as a
+// JSON line. Successful calls are sampled, failed calls are always logged.
+type AccessLogger struct {
+	sampleRate float64
+	now        func() time.Time
+
+	mu   sync.Mutex
+	enc  *json.Encoder
+	rand *rand.Rand
+}
+
+// NewAccessLogger creates an access logging middleware writing to w. The sample rate
+// is the fraction of successful calls which are logged, between zero and one.
+func NewAccessLogger(w io.Writer, sampleRate float64) *AccessLogger {
+	return &AccessLogger{
+		sampleRate: sampleRate,
+		now:        time.Now,
+		enc:        json.NewEncoder(w),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// HandleCall implements Middleware.
+func (l *AccessLogger) HandleCall(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+	start := l.now()
+	result, err := next(ctx, call)
+	if err == nil && !l.sample() {
+		return result, err
+	}
+	digest := sha256.Sum256(call.Params)
+	entry := &AccessLogEntry{
+		Time:        start.UTC(),
+		Method:      call.Method,
+		Params:      hex.EncodeToString(digest[:8]),
+		ParamsSize:  len(call.Params),
+		Duration:    l.now().Sub(start).Seconds(),
+		Transport:   call.Peer.Transport,
+		RemoteAddr:  call.Peer.RemoteAddr,
+		AuthSubject: call.Peer.AuthSubject,
+	}
+	if err != nil {
+		entry.ErrorCode = defaultErrorCode
+		if ec, ok := err.(Error); ok {
+			entry.ErrorCode = ec.ErrorCode()
+		}
+		entry.Error = err.Error()
+	} else {
+		entry.RespSize = resultSize(result)
+	}
+	l.write(entry)
+	return result, err
+}
+
+// sample decides whether a successful call is logged.
+func (l *AccessLogger) sample() bool {
+	if l.sampleRate >= 1 {
+		return true
+	}
+	if l.sampleRate <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rand.Float64() < l.sampleRate
+}
+
+func (l *AccessLogger) write(entry *AccessLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(entry); err != nil {
+		log.Debug("Failed to write RPC access log", "err", err)
+	}
+}
+
+// resultSize returns the encoded size of a call result.
+func resultSize(result interface{}) int {
+	if raw, ok := result.(json.RawMessage); ok {
+		return len(raw)
+	}
+	enc, _ := json.Marshal(result)
+	return len(enc)
+}
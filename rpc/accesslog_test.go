@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	server := newTestServer()
+	defer server.Stop()
+	logger := NewAccessLogger(&buf, 1)
+	clock := newFakeClock("2022-08-01T10:00:00Z")
+	logger.now = func() time.Time {
+		clock.run(10 * time.Millisecond)
+		return clock.now()
+	}
+	server.Use(logger)
+
+	client := DialInProc(server)
+	defer client.Close()
+	if err := client.Call(nil, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	client.Call(nil, "test_returnError")
+	client.Call(nil, "test_echo", "y", 2)
+
+	entries := readAccessLog(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("wrong number of log entries: %d", len(entries))
+	}
+	e := entries[0]
+	if e.Method != "test_echo" || e.Transport != "ipc" || e.ErrorCode != 0 || e.Duration != 0.01 {
+		t.Errorf("wrong entry for successful call: %+v", e)
+	}
+	if want := len(`{"String":"x","Int":1,"Args":null}`); e.RespSize != want {
+		t.Errorf("wrong response size %d, want %d", e.RespSize, want)
+	}
+	if len(e.Params) != 16 || e.ParamsSize != len(`["x",1]`) {
+		t.Errorf("wrong params digest %q, size %d", e.Params, e.ParamsSize)
+	}
+	if entries[2].Params == e.Params {
+		t.Error("different params have the same digest")
+	}
+	if e := entries[1]; e.ErrorCode != 444 || e.Error != "testError" || e.RespSize != 0 {
+		t.Errorf("wrong entry for failed call: %+v", e)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	server := newTestServer()
+	defer server.Stop()
+	server.Use(NewAccessLogger(&buf, 0))
+
+	client := DialInProc(server)
+	defer client.Close()
+	for i := 0; i < 10; i++ {
+		client.Call(nil, "test_echo", "x", i)
+	}
+	client.Call(nil, "test_returnError")
+
+	// Only failed calls are logged with a zero sample rate.
+	entries := readAccessLog(t, &buf)
+	if len(entries) != 1 || entries[0].Method != "test_returnError" {
+		t.Fatalf("wrong log entries: %+v", entries)
+	}
+}
+
+func readAccessLog(t *testing.T, buf *bytes.Buffer) []AccessLogEntry {
+	var entries []AccessLogEntry
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		var e AccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
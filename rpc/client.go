@@ -82,6 +82,9 @@ type Client struct {
 	// This function, if non-nil, is called when the connection is lost.
 	reconnectFunc reconnectFunc
 
+	// Set by EnableReconnect. Subscriptions survive reconnects if non-nil.
+	reconnectCfg *ReconnectConfig
+
 	// writeConn is used for writing to the connection on the caller's goroutine. It should
 	// only be accessed outside of dispatch, with the write lock held. The write lock is
 	// taken by sending on reqInit and released by sending on reqSent.
@@ -177,6 +180,8 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 		return DialHTTP(rawurl)
 	case "ws", "wss":
 		return DialWebsocket(ctx, rawurl, "")
+	case "h2c", "h2":
+		return DialHTTP2(ctx, rawurl)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
@@ -430,7 +435,14 @@ func (c *Client) ShhSubscribe(ctx context.Context, channel interface{}, args ...
 // before considering the subscriber dead. The subscription Err channel will receive
 // ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel or ensure
 // that the channel usually has at least one reader to prevent this issue.
+//
+// If the client is in reconnecting mode (see EnableReconnect), the subscription is
+// re-established when the connection is lost.
 func (c *Client) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
+	return c.subscribe(ctx, namespace, channel, nil, args)
+}
+
+func (c *Client) subscribe(ctx context.Context, namespace string, channel interface{}, resumer Resumer, args []interface{}) (*ClientSubscription, error) {
 	// Check type of channel first.
 	chanVal := reflect.ValueOf(channel)
 	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir()&reflect.SendDir == 0 {
@@ -442,7 +454,14 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	if c.isHTTP {
 		return nil, ErrNotificationsUnsupported
 	}
+	if c.ReconnectEnabled() {
+		return c.subscribeReconnecting(ctx, namespace, chanVal, resumer, args)
+	}
+	return c.newSubscription(ctx, namespace, chanVal, args)
+}
 
+// newSubscription creates a subscription on the server.
+func (c *Client) newSubscription(ctx context.Context, namespace string, channel reflect.Value, args []interface{}) (*ClientSubscription, error) {
 	msg, err := c.newMessage(namespace+subscribeMethodSuffix, args...)
 	if err != nil {
 		return nil, err
@@ -450,7 +469,7 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	op := &requestOp{
 		ids:  []json.RawMessage{msg.ID},
 		resp: make(chan *jsonrpcMessage),
-		sub:  newClientSubscription(c, namespace, chanVal),
+		sub:  newClientSubscription(c, namespace, channel),
 	}
 
 	// Send the subscription request.
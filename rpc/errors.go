@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // HTTPError is returned by client operations when the HTTP status code of the
 // response is not a 2xx status.
@@ -54,10 +57,17 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(LimitExceededError)
+	_ Error = new(responseTooLargeError)
 )
 
 const defaultErrorCode = -32000
 
+const (
+	errMsgBatchTooLarge    = "batch too large"
+	errMsgResponseTooLarge = "response too large"
+)
+
 type methodNotFoundError struct{ method string }
 
 func (e *methodNotFoundError) ErrorCode() int { return -32601 }
@@ -101,3 +111,30 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// the responses of a batch exceeded the size limit
+type responseTooLargeError struct{}
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string { return errMsgResponseTooLarge }
+
+// LimitExceededError is returned when a call is rejected because the client has
+// exceeded a rate limit or quota.
+type LimitExceededError struct {
+	Reason     string
+	RetryAfter time.Duration // zero if retrying later won't help
+}
+
+func (e *LimitExceededError) ErrorCode() int { return -32005 }
+
+func (e *LimitExceededError) Error() string { return e.Reason }
+
+// ErrorData returns the number of seconds after which the call can be retried.
+func (e *LimitExceededError) ErrorData() interface{} {
+	if e.RetryAfter <= 0 {
+		return nil
+	}
+	secs := (e.RetryAfter + time.Second - 1) / time.Second
+	return map[string]interface{}{"retryAfter": uint64(secs)}
+}
@@ -102,6 +102,15 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		return
 	}
 
+	// Reject batches with too many items, without running any of the calls.
+	limits := h.reg.limits()
+	if limits.itemLimit > 0 && len(msgs) > limits.itemLimit {
+		h.startCallProc(func(cp *callProc) {
+			h.respondWithError(cp, msgs, &invalidRequestError{errMsgBatchTooLarge})
+		})
+		return
+	}
+
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
 	for _, msg := range msgs {
@@ -114,10 +123,22 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
+		for i, msg := range calls {
+			// Stop executing calls once the responses are too large.
+			if limits.responseMaxSize > 0 && size > limits.responseMaxSize {
+				answers = append(answers, errorResponses(calls[i:], &responseTooLargeError{})...)
+				break
+			}
 			if answer := h.handleCallMsg(cp, msg); answer != nil {
 				answers = append(answers, answer)
+				size += len(answer.Result)
+				if answer.Error != nil {
+					size += len(answer.Error.Message)
+				}
 			}
 		}
 		h.addSubscriptions(cp.notifiers)
@@ -130,6 +151,31 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// respondWithError answers all calls of a batch with the given error.
+func (h *handler) respondWithError(cp *callProc, msgs []*jsonrpcMessage, err error) {
+	if answers := errorResponses(msgs, err); len(answers) > 0 {
+		h.conn.writeJSON(cp.ctx, answers)
+	}
+}
+
+// errorResponses answers all calls in msgs with the given error. Invalid messages get
+// the same response as if they had been handled.
+func errorResponses(msgs []*jsonrpcMessage, err error) []*jsonrpcMessage {
+	answers := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		switch {
+		case msg.isNotification():
+		case msg.isCall():
+			answers = append(answers, msg.errorResponse(err))
+		case msg.hasValidID():
+			answers = append(answers, msg.errorResponse(&invalidRequestError{"invalid request"}))
+		default:
+			answers = append(answers, errorMessage(&invalidRequestError{"invalid request"}))
+		}
+	}
+	return answers
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -315,8 +361,33 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
-// handleCall processes method calls.
+// handleCall processes method calls, passing them through the middlewares of the server.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	mws := h.reg.middlewareList()
+	if len(mws) == 0 {
+		return h.dispatchCall(cp, msg)
+	}
+	final := func(ctx context.Context, call *Call) (interface{}, error) {
+		inner := &callProc{ctx: ctx}
+		req := *msg
+		req.Params = call.Params
+		answer := h.dispatchCall(inner, &req)
+		cp.notifiers = append(cp.notifiers, inner.notifiers...)
+		if answer.Error != nil {
+			return nil, answer.Error
+		}
+		return answer.Result, nil
+	}
+	call := &Call{Method: msg.Method, Params: msg.Params, Peer: PeerInfoFromContext(cp.ctx)}
+	result, err := chainMiddlewares(mws, final)(cp.ctx, call)
+	if err != nil {
+		return msg.errorResponse(err)
+	}
+	return msg.response(result)
+}
+
+// dispatchCall runs the callback of a method call.
+func (h *handler) dispatchCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -345,8 +416,10 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		} else {
 			successfulRequestGauge.Inc(1)
 		}
-		rpcServingTimer.UpdateSince(start)
-		updateServeTimeHistogram(msg.Method, answer.Error == nil, time.Since(start))
+		elapsed := time.Since(start)
+		rpcServingTimer.Update(elapsed)
+		updateServeTimeHistogram(msg.Method, answer.Error == nil, elapsed)
+		updateLatencyHistogram(msg.Method, elapsed)
 	}
 	return answer
 }
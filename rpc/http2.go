@@ -0,0 +1,270 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// The HTTP/2 stream transport carries JSON-RPC messages over a single long-lived
+// HTTP/2 request, in the same way gRPC implements bidirectional streaming. The client
+// sends newline-delimited messages in the request body while the server streams its
+// messages, including subscription notifications, in the response body.
+//
+// Endpoints are dialed with the h2c:// (cleartext HTTP/2) and h2:// (HTTP/2 over TLS)
+// URL schemes.
+
+var errHTTP2Required = errors.New("JSON-RPC streaming requires HTTP/2")
+
+// HTTP2StreamHandler returns a handler that serves JSON-RPC over HTTP/2 streams. The
+// handler only accepts HTTP/2 requests. To serve cleartext HTTP/2 connections, wrap it
+// with NewH2CHandler.
+func (s *Server) HTTP2StreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, errHTTP2Required.Error(), http.StatusHTTPVersionNotSupported)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		// Send the response headers right away, so the client can start
+		// sending requests.
+		w.Header().Set("content-type", contentType)
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		conn := &http2ServerConn{body: r.Body, w: w, remote: r.RemoteAddr}
+		info := PeerInfo{Transport: "http2", RemoteAddr: r.RemoteAddr}
+		info.HTTP.Version = r.Proto
+		info.HTTP.Host = r.Host
+		info.HTTP.Origin = r.Header.Get("Origin")
+		info.HTTP.UserAgent = r.Header.Get("User-Agent")
+		info.AuthSubject = authSubjectFromContext(r.Context())
+		s.ServeCodec(newHTTP2Codec(conn, info), 0)
+	})
+}
+
+// NewH2CHandler wraps an HTTP/2 stream handler to also accept cleartext HTTP/2
+// connections.
+func NewH2CHandler(h http.Handler) http.Handler {
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
+// http2ServerConn is the server end of a stream. It makes sure the response isn't
+// written after the handler has returned.
+type http2ServerConn struct {
+	body   io.ReadCloser
+	remote string
+
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	deadline time.Time
+	closed   bool
+}
+
+func (c *http2ServerConn) Read(b []byte) (int, error) {
+	return c.body.Read(b)
+}
+
+// Write sends a message and flushes it to the client.
+func (c *http2ServerConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	return writeHTTP2Response(c.w, b, c.deadline)
+}
+
+func (c *http2ServerConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *http2ServerConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return c.body.Close()
+}
+
+func (c *http2ServerConn) RemoteAddr() string {
+	return c.remote
+}
+
+// http2ClientConn is the client end of a stream.
+type http2ClientConn struct {
+	reqBody *io.PipeWriter
+	resp    *http.Response
+	cancel  context.CancelFunc
+	remote  string
+}
+
+func (c *http2ClientConn) Read(b []byte) (int, error) {
+	return c.resp.Body.Read(b)
+}
+
+func (c *http2ClientConn) Write(b []byte) (int, error) {
+	return c.reqBody.Write(b)
+}
+
+// SetWriteDeadline does nothing, writes are aborted when the stream is closed.
+func (c *http2ClientConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (c *http2ClientConn) Close() error {
+	c.reqBody.Close()
+	c.cancel()
+	return c.resp.Body.Close()
+}
+
+func (c *http2ClientConn) RemoteAddr() string {
+	return c.remote
+}
+
+// http2Codec is a JSON codec on an HTTP/2 stream.
+type http2Codec struct {
+	*jsonCodec
+	info PeerInfo
+}
+
+// http2Conn is one end of an HTTP/2 stream.
+type http2Conn interface {
+	io.ReadWriter
+	deadlineCloser
+}
+
+func newHTTP2Codec(conn http2Conn, info PeerInfo) ServerCodec {
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+	dec.UseNumber()
+	return &http2Codec{
+		jsonCodec: NewFuncCodec(conn, enc.Encode, dec.Decode).(*jsonCodec),
+		info:      info,
+	}
+}
+
+func (c *http2Codec) peerInfo() PeerInfo {
+	return c.info
+}
+
+// DialHTTP2 creates a new RPC client that streams JSON-RPC messages over HTTP/2. The
+// endpoint URL must use the h2c:// or h2:// scheme.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialHTTP2(ctx context.Context, endpoint string) (*Client, error) {
+	return DialHTTP2WithConfig(ctx, endpoint, nil)
+}
+
+// DialHTTP2WithConfig creates a new HTTP/2 stream client using the given TLS
+// configuration for h2:// endpoints.
+func DialHTTP2WithConfig(ctx context.Context, endpoint string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http2.Transport{TLSClientConfig: tlsConfig}
+	switch u.Scheme {
+	case "h2":
+		u.Scheme = "https"
+	case "h2c":
+		u.Scheme = "http"
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.DialTimeout(network, addr, defaultDialTimeout)
+		}
+	default:
+		return nil, fmt.Errorf("invalid HTTP/2 stream URL scheme %q", u.Scheme)
+	}
+	client := &http.Client{Transport: transport}
+	target := u.String()
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		conn, err := openHTTP2Stream(ctx, client, target)
+		if err != nil {
+			return nil, err
+		}
+		return newHTTP2Codec(conn, PeerInfo{Transport: "http2", RemoteAddr: target}), nil
+	})
+}
+
+// openHTTP2Stream starts the streaming request and waits for the response headers.
+func openHTTP2Stream(ctx context.Context, client *http.Client, target string) (*http2ClientConn, error) {
+	// The stream outlives the dial context.
+	streamCtx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodPost, target, pr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("content-type", contentType)
+	req.Header.Set("accept", contentType)
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Do(req)
+		done <- result{resp, err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			cancel()
+			return nil, res.err
+		}
+		if res.resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(res.resp.Body, 1024))
+			res.resp.Body.Close()
+			cancel()
+			return nil, HTTPError{StatusCode: res.resp.StatusCode, Status: res.resp.Status, Body: body}
+		}
+		log.Trace("Opened HTTP/2 RPC stream", "url", target)
+		return &http2ClientConn{reqBody: pw, resp: res.resp, cancel: cancel, remote: target}, nil
+	case <-ctx.Done():
+		cancel()
+		pw.Close()
+		return nil, ctx.Err()
+	}
+}
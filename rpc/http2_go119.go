@@ -0,0 +1,35 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !go1.20
+// +build !go1.20
+
+package rpc
+
+import (
+	"net/http"
+	"time"
+)
+
+// writeHTTP2Response writes b to the response stream and flushes it to the client.
+// Response write deadlines can only be set since Go 1.20, so the deadline is ignored.
+func writeHTTP2Response(w http.ResponseWriter, b []byte, deadline time.Time) (int, error) {
+	n, err := w.Write(b)
+	if err == nil {
+		w.(http.Flusher).Flush()
+	}
+	return n, err
+}
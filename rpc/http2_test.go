@@ -0,0 +1,201 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func newHTTP2TestServer(t *testing.T) (*Server, string) {
+	server := newTestServer()
+	httpsrv := httptest.NewServer(NewH2CHandler(server.HTTP2StreamHandler()))
+	t.Cleanup(func() {
+		server.Stop()
+		httpsrv.Close()
+	})
+	return server, "h2c://" + strings.TrimPrefix(httpsrv.URL, "http://")
+}
+
+func TestHTTP2Calls(t *testing.T) {
+	_, url := newHTTP2TestServer(t)
+	client, err := DialContext(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (echoResult{"hello", 10, &echoArgs{"world"}}); !reflect.DeepEqual(result, want) {
+		t.Errorf("wrong result %+v, want %+v", result, want)
+	}
+
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"a", 1}, Result: new(echoResult)},
+		{Method: "test_returnError", Result: new(interface{})},
+		{Method: "no_such_method", Result: new(interface{})},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&echoResult{"a", 1, nil}); !reflect.DeepEqual(batch[0].Result, want) {
+		t.Errorf("wrong batch result %+v, want %+v", batch[0].Result, want)
+	}
+	if batch[1].Error == nil || batch[2].Error == nil {
+		t.Errorf("expected errors for failing batch calls, got %v, %v", batch[1].Error, batch[2].Error)
+	}
+
+	// Calls from the server to the client use the same stream.
+	if err := client.RegisterName("nftest2", new(notificationTestService)); err != nil {
+		t.Fatal(err)
+	}
+	var echo int
+	if err := client.Call(&echo, "test_callMeBack", "nftest2_echo", []interface{}{5}); err != nil {
+		t.Fatal(err)
+	}
+	if echo != 5 {
+		t.Errorf("wrong callback result %d", echo)
+	}
+}
+
+// This runs the server test scripts over an HTTP/2 stream.
+func TestHTTP2Scripts(t *testing.T) {
+	files, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal("where'd my testdata go?")
+	}
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		path := filepath.Join("testdata", f.Name())
+		name := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		t.Run(name, func(t *testing.T) {
+			_, url := newHTTP2TestServer(t)
+			client := &http.Client{Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			}}
+			conn, err := openHTTP2Stream(context.Background(), client, "http"+strings.TrimPrefix(url, "h2c"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			runTestScriptOn(t, path, conn)
+		})
+	}
+}
+
+func TestHTTP2Subscription(t *testing.T) {
+	_, url := newHTTP2TestServer(t)
+	client, err := DialHTTP2(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nc := make(chan int)
+	sub, err := client.Subscribe(ctx, "nftest", nc, "someSubscription", 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	for i := 0; i < 5; i++ {
+		select {
+		case v := <-nc:
+			if v != 1+i {
+				t.Fatalf("wrong notification %d, want %d", v, 1+i)
+			}
+		case err := <-sub.Err():
+			t.Fatal("subscription error:", err)
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for notification")
+		}
+	}
+}
+
+func TestHTTP2PeerInfo(t *testing.T) {
+	_, url := newHTTP2TestServer(t)
+	client, err := DialHTTP2(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var info PeerInfo
+	if err := client.Call(&info, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if info.Transport != "http2" {
+		t.Errorf("wrong transport %q", info.Transport)
+	}
+	if info.HTTP.Version != "HTTP/2.0" {
+		t.Errorf("wrong HTTP version %q", info.HTTP.Version)
+	}
+	if info.RemoteAddr == "" {
+		t.Error("remote address not set")
+	}
+}
+
+// This checks that plain HTTP/1.1 requests are rejected.
+func TestHTTP2RejectHTTP1(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(NewH2CHandler(server.HTTP2StreamHandler()))
+	defer httpsrv.Close()
+
+	resp, err := http.Post(httpsrv.URL, contentType, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_echo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusHTTPVersionNotSupported {
+		t.Errorf("wrong status %d", resp.StatusCode)
+	}
+}
+
+func TestHTTP2DialError(t *testing.T) {
+	if _, err := DialHTTP2(context.Background(), "http://localhost:8548"); err == nil {
+		t.Error("expected error for wrong URL scheme")
+	}
+
+	// Dialing a server without HTTP/2 support fails.
+	httpsrv := httptest.NewServer(http.NotFoundHandler())
+	defer httpsrv.Close()
+	_, err := DialHTTP2(context.Background(), "h2c://"+strings.TrimPrefix(httpsrv.URL, "http://"))
+	if err == nil {
+		t.Fatal("expected dial error")
+	}
+}
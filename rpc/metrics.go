@@ -32,6 +32,9 @@ var (
 	serveTimeHistName = "rpc/duration"
 
 	rpcServingTimer = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	// latencyHistName is the prefix of the per-method latency histograms.
+	latencyHistName = "rpc/latency"
 )
 
 // updateServeTimeHistogram tracks the serving time of a remote RPC call.
@@ -48,3 +51,10 @@ func updateServeTimeHistogram(method string, success bool, elapsed time.Duration
 	}
 	metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(elapsed.Microseconds())
 }
+
+// updateLatencyHistogram tracks the latency of a method in fixed buckets, which
+// can be aggregated by Prometheus across scrapes and nodes.
+func updateLatencyHistogram(method string, elapsed time.Duration) {
+	h := fmt.Sprintf("%s/%s", latencyHistName, method)
+	metrics.GetOrRegisterBucketHistogram(h, nil, metrics.DefaultDurationBuckets).Observe(elapsed.Seconds())
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Call is a method call passing through the middleware chain of a Server.
+type Call struct {
+	Method string
+	Params json.RawMessage // positional arguments, may be modified by middlewares
+	Peer   PeerInfo        // the client connection
+}
+
+// CallHandler executes a method call. The returned result is encoded as JSON
+// and sent to the client.
+type CallHandler func(ctx context.Context, call *Call) (interface{}, error)
+
+// Middleware intercepts the method calls served by a Server. Implementations
+// can inspect or modify the call, invoke next to continue processing it, or reject
+// it by returning an error without calling next. Errors implementing the Error
+// and DataError interfaces are sent to the client with their code and data.
+type Middleware interface {
+	HandleCall(ctx context.Context, call *Call, next CallHandler) (interface{}, error)
+}
+
+// MiddlewareFunc is an adapter to use ordinary functions as Middleware.
+type MiddlewareFunc func(ctx context.Context, call *Call, next CallHandler) (interface{}, error)
+
+// HandleCall implements Middleware.
+func (f MiddlewareFunc) HandleCall(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+	return f(ctx, call, next)
+}
+
+// Use adds middlewares to the server. They run around every method call, including
+// subscription requests, in the order in which they were added: the first middleware
+// sees the call first and the result last.
+func (s *Server) Use(mw ...Middleware) {
+	s.services.mu.Lock()
+	defer s.services.mu.Unlock()
+
+	// Copy the list, handlers may be iterating over the current one.
+	list := make([]Middleware, 0, len(s.services.middlewares)+len(mw))
+	list = append(list, s.services.middlewares...)
+	s.services.middlewares = append(list, mw...)
+}
+
+// chainMiddlewares wraps the final handler with the given middlewares.
+func chainMiddlewares(mws []Middleware, final CallHandler) CallHandler {
+	next := final
+	for i := len(mws) - 1; i >= 0; i-- {
+		mw, inner := mws[i], next
+		next = func(ctx context.Context, call *Call) (interface{}, error) {
+			return mw.HandleCall(ctx, call, inner)
+		}
+	}
+	return next
+}
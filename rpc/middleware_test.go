@@ -0,0 +1,139 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	record := func(name string) Middleware {
+		return MiddlewareFunc(func(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+			mu.Lock()
+			log = append(log, name+" before "+call.Method)
+			mu.Unlock()
+			res, err := next(ctx, call)
+			mu.Lock()
+			log = append(log, name+" after "+call.Method)
+			mu.Unlock()
+			return res, err
+		})
+	}
+	rewrite := MiddlewareFunc(func(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+		call.Params = json.RawMessage(`["rewritten", 2, {"S": "x"}]`)
+		return next(ctx, call)
+	})
+	server.Use(record("a"), record("b"))
+	server.Use(rewrite)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "hello", 1, &echoArgs{"y"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (echoResult{"rewritten", 2, &echoArgs{"x"}}); !reflect.DeepEqual(result, want) {
+		t.Errorf("wrong result %+v, want %+v", result, want)
+	}
+	want := []string{"a before test_echo", "b before test_echo", "b after test_echo", "a after test_echo"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("wrong middleware calls:\nhave %q\nwant %q", log, want)
+	}
+}
+
+func TestMiddlewareReject(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.Use(MiddlewareFunc(func(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+		if call.Method == "test_echo" {
+			return nil, &LimitExceededError{Reason: "too many calls", RetryAfter: 1500 * time.Millisecond}
+		}
+		return next(ctx, call)
+	}))
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Errors of the method itself pass through the middleware.
+	err := client.Call(nil, "test_returnError")
+	if re, ok := err.(Error); !ok || re.ErrorCode() != 444 {
+		t.Errorf("wrong error from method: %v", err)
+	}
+
+	// Rejected calls get the limit error code and retry delay.
+	err = client.Call(nil, "test_echo", "hello", 1, nil)
+	if err == nil || err.Error() != "too many calls" {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if code := err.(Error).ErrorCode(); code != -32005 {
+		t.Errorf("wrong error code %d", code)
+	}
+	data, _ := json.Marshal(err.(DataError).ErrorData())
+	if string(data) != `{"retryAfter":2}` {
+		t.Errorf("wrong error data %s", data)
+	}
+}
+
+func TestMiddlewareSubscription(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	var calls []string
+	server.Use(MiddlewareFunc(func(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+		calls = append(calls, call.Method)
+		return next(ctx, call)
+	}))
+	client := DialInProc(server)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nc := make(chan int)
+	sub, err := client.Subscribe(ctx, "nftest", nc, "someSubscription", 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-nc:
+			if v != 10+i {
+				t.Fatalf("wrong notification %d, want %d", v, 10+i)
+			}
+		case err := <-sub.Err():
+			t.Fatal("subscription error:", err)
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for notification")
+		}
+	}
+	sub.Unsubscribe()
+	if want := []string{"nftest_subscribe", "nftest_unsubscribe"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong calls %q, want %q", calls, want)
+	}
+}
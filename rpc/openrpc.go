@@ -0,0 +1,439 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// discoverMethod is the service discovery method defined by OpenRPC. It is served
+	// by RPCService.Discover.
+	discoverMethod = "rpc.discover"
+
+	openRPCVersion = "1.2.6"
+)
+
+// OpenRPCDocument is an OpenRPC service description.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a method. Subscriptions are described as one method per
+// subscription name, carrying the x-subscription extension.
+type OpenRPCMethod struct {
+	Name           string               `json:"name"`
+	Params         []*OpenRPCContent    `json:"params"`
+	Result         *OpenRPCContent      `json:"result"`
+	ParamStructure string               `json:"paramStructure"`
+	Subscription   *OpenRPCSubscription `json:"x-subscription,omitempty"`
+}
+
+// OpenRPCSubscription marks a method as a subscription. Subscriptions are created by
+// calling Method with Name as the first parameter, and cancelled with Unsubscribe.
+type OpenRPCSubscription struct {
+	Name        string `json:"name"`
+	Method      string `json:"method"`
+	Unsubscribe string `json:"unsubscribe"`
+}
+
+// OpenRPCContent is an OpenRPC content descriptor.
+type OpenRPCContent struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of named types, which are referenced from
+// method descriptions.
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// JSONSchema is the subset of JSON Schema used in OpenRPC documents.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+// Schemas of well-known types, which don't follow from their Go definition.
+var (
+	quantitySchema = &JSONSchema{Title: "Quantity", Type: "string", Pattern: "^0x(0|[1-9a-f][0-9a-f]*)$"}
+	bytesSchema    = &JSONSchema{Title: "Bytes", Type: "string", Pattern: "^0x([0-9a-fA-F]{2})*$"}
+	addressSchema  = &JSONSchema{Title: "Address", Type: "string", Pattern: "^0x[0-9a-fA-F]{40}$"}
+	hashSchema     = &JSONSchema{Title: "Hash", Type: "string", Pattern: "^0x[0-9a-fA-F]{64}$"}
+	blockTagSchema = &JSONSchema{Title: "BlockTag", Type: "string", Enum: []string{"earliest", "latest", "pending", "finalized"}}
+
+	blockNumberSchema = &JSONSchema{Title: "BlockNumber", OneOf: []*JSONSchema{quantitySchema, blockTagSchema}}
+
+	wellKnownSchemas = map[reflect.Type]*JSONSchema{
+		reflect.TypeOf(common.Address{}):  addressSchema,
+		reflect.TypeOf(common.Hash{}):     hashSchema,
+		reflect.TypeOf(hexutil.Big{}):     quantitySchema,
+		reflect.TypeOf(hexutil.Uint64(0)): quantitySchema,
+		reflect.TypeOf(hexutil.Uint(0)):   quantitySchema,
+		reflect.TypeOf(hexutil.Bytes{}):   bytesSchema,
+		reflect.TypeOf(big.Int{}):         {Type: "integer"},
+		reflect.TypeOf(time.Time{}):       {Type: "string", Format: "date-time"},
+		reflect.TypeOf(json.RawMessage{}): {},
+		reflect.TypeOf(BlockNumber(0)):    blockNumberSchema,
+		reflect.TypeOf(ID("")):            {Title: "SubscriptionID", Type: "string"},
+		reflect.TypeOf(BlockNumberOrHash{}): {Title: "BlockNumberOrHash", OneOf: []*JSONSchema{
+			blockNumberSchema,
+			hashSchema,
+			{Type: "object", Properties: map[string]*JSONSchema{
+				"blockNumber":      blockNumberSchema,
+				"blockHash":        hashSchema,
+				"requireCanonical": {Type: "boolean"},
+			}},
+		}},
+	}
+
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Discover returns the OpenRPC description of the server. It is served as the
+// rpc.discover method.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	return s.server.OpenRPCDocument()
+}
+
+// OpenRPCDocument returns the OpenRPC description of all methods and subscriptions
+// registered on the server.
+func (s *Server) OpenRPCDocument() *OpenRPCDocument {
+	s.services.mu.Lock()
+	defer s.services.mu.Unlock()
+
+	var (
+		gen = newSchemaGenerator()
+		doc = &OpenRPCDocument{
+			OpenRPC: openRPCVersion,
+			Info:    OpenRPCInfo{Title: "go-ethereum JSON-RPC API", Version: "1.0"},
+		}
+	)
+	// Types are visited in a stable order, so component names are deterministic.
+	for _, svcname := range sortedKeys(s.services.services) {
+		svc := s.services.services[svcname]
+		for _, name := range sortedKeys(svc.callbacks) {
+			doc.Methods = append(doc.Methods, gen.method(svc.name+serviceMethodSeparator+name, svc.callbacks[name]))
+		}
+		for _, name := range sortedKeys(svc.subscriptions) {
+			doc.Methods = append(doc.Methods, gen.subscription(svc.name, name, svc.subscriptions[name]))
+		}
+	}
+	// rpc.discover doesn't follow the naming of the other methods.
+	if cb := s.services.services[MetadataApi].callbacks["discover"]; cb != nil {
+		doc.Methods = append(doc.Methods, gen.method(discoverMethod, cb))
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool {
+		return doc.Methods[i].Name < doc.Methods[j].Name
+	})
+	doc.Components.Schemas = gen.components
+	return doc
+}
+
+// schemaGenerator derives JSON schemas from Go types. Named struct types are added to
+// the document components and referenced.
+type schemaGenerator struct {
+	components map[string]*JSONSchema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: make(map[string]*JSONSchema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (g *schemaGenerator) method(name string, cb *callback) *OpenRPCMethod {
+	m := &OpenRPCMethod{
+		Name:           name,
+		Params:         g.params(cb.argTypes, 0),
+		ParamStructure: "by-position",
+		Result:         &OpenRPCContent{Name: "result", Schema: &JSONSchema{Type: "null"}},
+	}
+	if fntype := cb.fn.Type(); fntype.NumOut() > 0 && cb.errPos != 0 {
+		m.Result.Schema = g.schema(fntype.Out(0))
+	}
+	return m
+}
+
+func (g *schemaGenerator) subscription(namespace, name string, cb *callback) *OpenRPCMethod {
+	subscribe := namespace + subscribeMethodSuffix
+	params := []*OpenRPCContent{{
+		Name:     "subscription",
+		Required: true,
+		Schema:   &JSONSchema{Type: "string", Enum: []string{name}},
+	}}
+	return &OpenRPCMethod{
+		Name:           subscribe + serviceMethodSeparator + name,
+		Params:         append(params, g.params(cb.argTypes, 1)...),
+		ParamStructure: "by-position",
+		Result:         &OpenRPCContent{Name: "subscriptionId", Schema: g.schema(reflect.TypeOf(ID("")))},
+		Subscription: &OpenRPCSubscription{
+			Name:        name,
+			Method:      subscribe,
+			Unsubscribe: namespace + unsubscribeMethodSuffix,
+		},
+	}
+}
+
+// params describes positional parameters. Trailing pointer parameters may be
+// omitted by callers.
+func (g *schemaGenerator) params(types []reflect.Type, offset int) []*OpenRPCContent {
+	params := make([]*OpenRPCContent, len(types))
+	optional := true
+	for i := len(types) - 1; i >= 0; i-- {
+		optional = optional && types[i].Kind() == reflect.Ptr
+		params[i] = &OpenRPCContent{
+			Name:     fmt.Sprintf("arg%d", i+offset),
+			Required: !optional,
+			Schema:   g.schema(types[i]),
+		}
+	}
+	return params
+}
+
+// schema returns the schema of values of type t.
+func (g *schemaGenerator) schema(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := wellKnownSchemas[t]; ok {
+		return s
+	}
+	if implements(t, textMarshalerType) {
+		return &JSONSchema{Type: "string"}
+	}
+	if implements(t, jsonMarshalerType) {
+		if t.Name() == "" {
+			return marshalerSchema(t)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Format: "byte"} // base64
+		}
+		return &JSONSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &JSONSchema{Type: "array", Items: g.schema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + g.component(t)}
+	default:
+		// Interfaces can hold any value.
+		return &JSONSchema{}
+	}
+}
+
+// component adds a named struct type or a type with custom JSON encoding to the
+// components, returning its name.
+func (g *schemaGenerator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		// Qualify types with the same name from different packages.
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	g.components[name] = nil // reserve the name, the type may be recursive
+	if implements(t, jsonMarshalerType) {
+		g.components[name] = marshalerSchema(t)
+	} else {
+		g.components[name] = g.structSchema(t)
+	}
+	return name
+}
+
+// implements reports whether values of type t or pointers to them implement the
+// given interface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+// marshalerSchema describes a type with a custom MarshalJSON method, such as the
+// gencodec generated encodings of core types. Its encoding doesn't follow from the
+// Go definition, so the schema is derived from the encoding of the zero value, with
+// its pointer fields allocated. Types which can't encode their zero value are
+// described as holding any value.
+func marshalerSchema(t reflect.Type) (s *JSONSchema) {
+	defer func() {
+		if recover() != nil {
+			s = &JSONSchema{}
+		}
+	}()
+	zero := reflect.New(t)
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			f := zero.Elem().Field(i)
+			if f.Kind() == reflect.Ptr && f.CanSet() {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+		}
+	}
+	var v interface{}
+	enc, err := json.Marshal(zero.Interface())
+	if err == nil {
+		err = json.Unmarshal(enc, &v)
+	}
+	if err != nil {
+		return &JSONSchema{}
+	}
+	return valueSchema(v)
+}
+
+// valueSchema describes the decoded JSON value v. Hex strings are assumed to hold
+// values of the well-known hex encoded types.
+func valueSchema(v interface{}) *JSONSchema {
+	switch v := v.(type) {
+	case bool:
+		return &JSONSchema{Type: "boolean"}
+	case float64:
+		return &JSONSchema{Type: "number"}
+	case string:
+		switch {
+		case !strings.HasPrefix(v, "0x"):
+			return &JSONSchema{Type: "string"}
+		case v == "0x0":
+			return quantitySchema
+		case len(v) == 2+2*common.AddressLength:
+			return addressSchema
+		case len(v) == 2+2*common.HashLength:
+			return hashSchema
+		default:
+			return bytesSchema
+		}
+	case []interface{}:
+		return &JSONSchema{Type: "array"}
+	case map[string]interface{}:
+		s := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema, len(v))}
+		for name, field := range v {
+			s.Properties[name] = valueSchema(field)
+		}
+		return s
+	default:
+		// null, the type of non-zero values is unknown.
+		return &JSONSchema{}
+	}
+}
+
+// structSchema describes the JSON object encoding of a struct.
+func (g *schemaGenerator) structSchema(t reflect.Type) *JSONSchema {
+	s := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *JSONSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft) // fields of embedded structs are promoted
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",string,") {
+			s.Properties[name] = &JSONSchema{Type: "string"}
+		} else {
+			s.Properties[name] = g.schema(f.Type)
+		}
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map with string keys in sorted order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type schemaTestEmbedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type schemaTestStruct struct {
+	schemaTestEmbedded
+	Address  common.Address      `json:"address"`
+	Value    *hexutil.Big        `json:"value,omitempty"`
+	Count    uint64              `json:"count,string"`
+	Data     []byte              `json:"data"`
+	Children []*schemaTestStruct `json:"children"`
+	Ignored  int                 `json:"-"`
+	internal int
+}
+
+// schemaTestLog has a custom JSON encoding, like the gencodec generated ones.
+type schemaTestLog struct {
+	Address common.Address
+	Number  uint64
+	Data    []byte
+	Removed bool
+}
+
+func (l schemaTestLog) MarshalJSON() ([]byte, error) {
+	type log struct {
+		Address common.Address `json:"address"`
+		Number  hexutil.Uint64 `json:"blockNumber"`
+		Data    hexutil.Bytes  `json:"data"`
+		Removed bool           `json:"removed"`
+	}
+	return json.Marshal(log{l.Address, hexutil.Uint64(l.Number), l.Data, l.Removed})
+}
+
+// schemaTestTx can't encode its zero value.
+type schemaTestTx struct {
+	inner *schemaTestLog
+}
+
+func (tx *schemaTestTx) MarshalJSON() ([]byte, error) {
+	return tx.inner.MarshalJSON()
+}
+
+func TestOpenRPCSchema(t *testing.T) {
+	gen := newSchemaGenerator()
+	tests := []struct {
+		typ  reflect.Type
+		want string
+	}{
+		{reflect.TypeOf(true), `{"type":"boolean"}`},
+		{reflect.TypeOf(uint16(0)), `{"type":"integer"}`},
+		{reflect.TypeOf(""), `{"type":"string"}`},
+		{reflect.TypeOf([]int{}), `{"type":"array","items":{"type":"integer"}}`},
+		{reflect.TypeOf([2]string{}), `{"type":"array","items":{"type":"string"},"minItems":2,"maxItems":2}`},
+		{reflect.TypeOf(map[string]bool{}), `{"type":"object","additionalProperties":{"type":"boolean"}}`},
+		{reflect.TypeOf(new(interface{})).Elem(), `{}`},
+		{reflect.TypeOf(&common.Hash{}), `{"title":"Hash","type":"string","pattern":"^0x[0-9a-fA-F]{64}$"}`},
+		{reflect.TypeOf(hexutil.Uint64(0)), `{"title":"Quantity","type":"string","pattern":"^0x(0|[1-9a-f][0-9a-f]*)$"}`},
+		{reflect.TypeOf(LatestBlockNumber), `{"title":"BlockNumber","oneOf":[{"title":"Quantity","type":"string","pattern":"^0x(0|[1-9a-f][0-9a-f]*)$"},{"title":"BlockTag","type":"string","enum":["earliest","latest","pending","finalized"]}]}`},
+		{reflect.TypeOf(schemaTestStruct{}), `{"$ref":"#/components/schemas/schemaTestStruct"}`},
+		{reflect.TypeOf(&schemaTestLog{}), `{"$ref":"#/components/schemas/schemaTestLog"}`},
+		{reflect.TypeOf(schemaTestTx{}), `{"$ref":"#/components/schemas/schemaTestTx"}`},
+	}
+	for _, test := range tests {
+		enc, _ := json.Marshal(gen.schema(test.typ))
+		if string(enc) != test.want {
+			t.Errorf("wrong schema for %v:\nhave %s\nwant %s", test.typ, enc, test.want)
+		}
+	}
+
+	// Named structs are described in the components.
+	enc, _ := json.Marshal(gen.components["schemaTestStruct"])
+	want := `{"type":"object","properties":{` +
+		`"address":{"title":"Address","type":"string","pattern":"^0x[0-9a-fA-F]{40}$"},` +
+		`"children":{"type":"array","items":{"$ref":"#/components/schemas/schemaTestStruct"}},` +
+		`"count":{"type":"string"},` +
+		`"data":{"type":"string","format":"byte"},` +
+		`"embedded":{"type":"string"},` +
+		`"value":{"title":"Quantity","type":"string","pattern":"^0x(0|[1-9a-f][0-9a-f]*)$"}},` +
+		`"required":["address","children","count","data","embedded"]}`
+	if string(enc) != want {
+		t.Errorf("wrong component schema:\nhave %s\nwant %s", enc, want)
+	}
+
+	// Types with custom encoding are described by the encoding of their zero value.
+	enc, _ = json.Marshal(gen.components["schemaTestLog"])
+	want = `{"type":"object","properties":{` +
+		`"address":{"title":"Address","type":"string","pattern":"^0x[0-9a-fA-F]{40}$"},` +
+		`"blockNumber":{"title":"Quantity","type":"string","pattern":"^0x(0|[1-9a-f][0-9a-f]*)$"},` +
+		`"data":{"title":"Bytes","type":"string","pattern":"^0x([0-9a-fA-F]{2})*$"},` +
+		`"removed":{"type":"boolean"}}}`
+	if string(enc) != want {
+		t.Errorf("wrong schema of custom encoding:\nhave %s\nwant %s", enc, want)
+	}
+	if enc, _ = json.Marshal(gen.components["schemaTestTx"]); string(enc) != `{}` {
+		t.Errorf("wrong schema of failing encoding: %s", enc)
+	}
+}
+
+func TestOpenRPCDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc.discover"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenRPC != openRPCVersion {
+		t.Errorf("wrong version %q", doc.OpenRPC)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, m := range doc.Methods {
+		methods[m.Name] = m
+	}
+	for _, name := range []string{"rpc.discover", "rpc_modules", "test_echo", "test_sleep", "nftest_subscribe_someSubscription"} {
+		if methods[name] == nil {
+			t.Errorf("method %s missing", name)
+		}
+	}
+
+	// Check parameters and result of test_echo(str string, i int, args *echoArgs).
+	echo := methods["test_echo"]
+	if echo == nil {
+		t.FailNow()
+	}
+	enc, _ := json.Marshal(echo)
+	want := `{"name":"test_echo","params":[` +
+		`{"name":"arg0","required":true,"schema":{"type":"string"}},` +
+		`{"name":"arg1","required":true,"schema":{"type":"integer"}},` +
+		`{"name":"arg2","schema":{"$ref":"#/components/schemas/echoArgs"}}],` +
+		`"result":{"name":"result","schema":{"$ref":"#/components/schemas/echoResult"}},` +
+		`"paramStructure":"by-position"}`
+	if string(enc) != want {
+		t.Errorf("wrong test_echo description:\nhave %s\nwant %s", enc, want)
+	}
+	if doc.Components.Schemas["echoResult"] == nil {
+		t.Error("echoResult schema missing")
+	}
+
+	// Subscriptions are marked.
+	sub := methods["nftest_subscribe_someSubscription"]
+	if sub == nil {
+		t.FailNow()
+	}
+	wantSub := &OpenRPCSubscription{Name: "someSubscription", Method: "nftest_subscribe", Unsubscribe: "nftest_unsubscribe"}
+	if !reflect.DeepEqual(sub.Subscription, wantSub) {
+		t.Errorf("wrong subscription marker %+v", sub.Subscription)
+	}
+	if len(sub.Params) != 3 || !reflect.DeepEqual(sub.Params[0].Schema.Enum, []string{"someSubscription"}) {
+		t.Errorf("wrong subscription params")
+	}
+}
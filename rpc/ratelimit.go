@@ -0,0 +1,204 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitPruneInterval = time.Minute
+
+	// rateLimitMaxClients is the number of clients tracked individually. Beyond
+	// it, new clients share the limits of a single overflow client until idle
+	// clients are pruned.
+	rateLimitMaxClients = 100000
+	rateLimitOverflow   = "overflow"
+)
+
+// RateLimitConfig configures the limits enforced by a RateLimiter. Limits are
+// expressed in cost units. Every call costs DefaultCost units, unless the method
+// has a weight in MethodCosts.
+type RateLimitConfig struct {
+	Rate        float64        // cost units per second a client may use, zero disables rate limiting
+	Burst       int            // maximum cost of calls in a burst, defaults to the larger of Rate and the highest method cost
+	DailyQuota  uint64         // cost units a client may use per UTC day, zero disables the quota
+	MethodCosts map[string]int // cost of individual methods
+	DefaultCost int            // cost of methods not in MethodCosts, defaults to 1
+}
+
+// Enabled reports whether the configuration limits anything.
+func (cfg RateLimitConfig) Enabled() bool {
+	return cfg.Rate > 0 || cfg.DailyQuota > 0
+}
+
+// RateLimiter is a middleware which limits the calls of every client with a token
+// bucket and a daily quota. Clients authenticated with a JWT token are identified
+// by the token subject, all others by their IPv4 address or IPv6 /64 prefix.
+type RateLimiter struct {
+	cfg        RateLimitConfig
+	now        func() time.Time
+	maxClients int
+
+	mu        sync.Mutex
+	clients   map[string]*rateLimitClient
+	lastPrune time.Time
+}
+
+// rateLimitClient is the limiter state of a single client.
+type rateLimitClient struct {
+	bucket   *rate.Limiter
+	day      time.Time // start of the day the quota usage refers to
+	used     uint64    // quota used on that day
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiting middleware.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.DefaultCost <= 0 {
+		cfg.DefaultCost = 1
+	}
+	if cfg.Rate > 0 && cfg.Burst <= 0 {
+		cfg.Burst = int(math.Ceil(cfg.Rate))
+		if cfg.DefaultCost > cfg.Burst {
+			cfg.Burst = cfg.DefaultCost
+		}
+		for _, cost := range cfg.MethodCosts {
+			if cost > cfg.Burst {
+				cfg.Burst = cost
+			}
+		}
+	}
+	return &RateLimiter{
+		cfg:        cfg,
+		now:        time.Now,
+		maxClients: rateLimitMaxClients,
+		clients:    make(map[string]*rateLimitClient),
+	}
+}
+
+// HandleCall implements Middleware.
+func (rl *RateLimiter) HandleCall(ctx context.Context, call *Call, next CallHandler) (interface{}, error) {
+	if err := rl.take(rateLimitKey(call.Peer), rl.cost(call.Method)); err != nil {
+		return nil, err
+	}
+	return next(ctx, call)
+}
+
+// cost returns the weight of a method.
+func (rl *RateLimiter) cost(method string) int {
+	if cost, ok := rl.cfg.MethodCosts[method]; ok {
+		return cost
+	}
+	return rl.cfg.DefaultCost
+}
+
+// take charges the given cost to a client, returning an error if the call
+// exceeds a limit.
+func (rl *RateLimiter) take(key string, cost int) error {
+	if cost <= 0 {
+		return nil
+	}
+	now := rl.now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastPrune) >= rateLimitPruneInterval {
+		rl.prune(now)
+	}
+	c := rl.clients[key]
+	if c == nil && len(rl.clients) >= rl.maxClients {
+		key = rateLimitOverflow
+		c = rl.clients[key]
+	}
+	if c == nil {
+		c = new(rateLimitClient)
+		if rl.cfg.Rate > 0 {
+			c.bucket = rate.NewLimiter(rate.Limit(rl.cfg.Rate), rl.cfg.Burst)
+		}
+		rl.clients[key] = c
+	}
+	c.lastSeen = now
+
+	// Check the quota first, it doesn't consume anything.
+	if rl.cfg.DailyQuota > 0 {
+		day := startOfDay(now)
+		if !c.day.Equal(day) {
+			c.day, c.used = day, 0
+		}
+		if c.used+uint64(cost) > rl.cfg.DailyQuota {
+			return &LimitExceededError{Reason: "daily request quota exceeded", RetryAfter: day.Add(24 * time.Hour).Sub(now)}
+		}
+	}
+	if c.bucket != nil {
+		if cost > rl.cfg.Burst {
+			return &LimitExceededError{Reason: "request cost exceeds rate limit burst"}
+		}
+		r := c.bucket.ReserveN(now, cost)
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			return &LimitExceededError{Reason: "rate limit exceeded", RetryAfter: delay}
+		}
+	}
+	c.used += uint64(cost)
+	return nil
+}
+
+// prune removes clients whose state is the same as the state of a new client.
+func (rl *RateLimiter) prune(now time.Time) {
+	rl.lastPrune = now
+	refill := time.Duration(0)
+	if rl.cfg.Rate > 0 {
+		refill = time.Duration(float64(rl.cfg.Burst) / rl.cfg.Rate * float64(time.Second))
+	}
+	today := startOfDay(now)
+	for key, c := range rl.clients {
+		if now.Sub(c.lastSeen) < refill {
+			continue // bucket not full yet
+		}
+		if rl.cfg.DailyQuota > 0 && c.day.Equal(today) {
+			continue // quota still in use
+		}
+		delete(rl.clients, key)
+	}
+}
+
+// rateLimitKey identifies the client of a call. IPv6 clients are identified by
+// their /64 prefix, which is usually assigned to a single host or network.
+func rateLimitKey(peer PeerInfo) string {
+	if peer.AuthSubject != "" {
+		return "sub:" + peer.AuthSubject
+	}
+	if host, _, err := net.SplitHostPort(peer.RemoteAddr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			return "ip:" + ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+		}
+		return "ip:" + host
+	}
+	return "addr:" + peer.RemoteAddr
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) run(d time.Duration) { c.t = c.t.Add(d) }
+func newFakeClock(start string) *fakeClock {
+	t, _ := time.Parse(time.RFC3339, start)
+	return &fakeClock{t}
+}
+
+func TestRateLimiterBucket(t *testing.T) {
+	clock := newFakeClock("2022-08-01T10:00:00Z")
+	rl := NewRateLimiter(RateLimitConfig{
+		Rate:        2,
+		Burst:       4,
+		MethodCosts: map[string]int{"eth_getLogs": 3, "eth_huge": 5, "eth_free": 0},
+	})
+	rl.now = clock.now
+
+	// The burst allows four calls at once.
+	for i := 0; i < 4; i++ {
+		if err := rl.take("ip:1.2.3.4", rl.cost("eth_blockNumber")); err != nil {
+			t.Fatalf("call %d rejected: %v", i, err)
+		}
+	}
+	err := rl.take("ip:1.2.3.4", rl.cost("eth_blockNumber"))
+	if le, ok := err.(*LimitExceededError); !ok || le.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("wrong error for exceeded rate: %v", err)
+	}
+	// Other clients are not affected, free calls always pass.
+	if err := rl.take("ip:5.6.7.8", rl.cost("eth_getLogs")); err != nil {
+		t.Fatal("other client rejected:", err)
+	}
+	if err := rl.take("ip:1.2.3.4", rl.cost("eth_free")); err != nil {
+		t.Fatal("free call rejected:", err)
+	}
+
+	// Expensive calls need more tokens to be refilled.
+	clock.run(time.Second)
+	err = rl.take("ip:1.2.3.4", rl.cost("eth_getLogs"))
+	if le, ok := err.(*LimitExceededError); !ok || le.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("wrong error for expensive call: %v", err)
+	}
+	clock.run(500 * time.Millisecond)
+	if err := rl.take("ip:1.2.3.4", rl.cost("eth_getLogs")); err != nil {
+		t.Fatal("expensive call rejected after refill:", err)
+	}
+
+	// Calls costing more than the burst can never succeed.
+	clock.run(time.Hour)
+	err = rl.take("ip:1.2.3.4", rl.cost("eth_huge"))
+	if le, ok := err.(*LimitExceededError); !ok || le.RetryAfter != 0 {
+		t.Fatalf("wrong error for call exceeding burst: %v", err)
+	}
+}
+
+func TestRateLimiterQuota(t *testing.T) {
+	clock := newFakeClock("2022-08-01T23:00:00Z")
+	rl := NewRateLimiter(RateLimitConfig{DailyQuota: 10, MethodCosts: map[string]int{"eth_call": 4}})
+	rl.now = clock.now
+
+	for i := 0; i < 2; i++ {
+		if err := rl.take("sub:alice", rl.cost("eth_call")); err != nil {
+			t.Fatalf("call %d rejected: %v", i, err)
+		}
+	}
+	err := rl.take("sub:alice", rl.cost("eth_call"))
+	if le, ok := err.(*LimitExceededError); !ok || le.RetryAfter != time.Hour {
+		t.Fatalf("wrong error for exceeded quota: %v", err)
+	}
+	// Cheaper calls fit into the remaining quota.
+	for i := 0; i < 2; i++ {
+		if err := rl.take("sub:alice", rl.cost("eth_chainId")); err != nil {
+			t.Fatalf("cheap call %d rejected: %v", i, err)
+		}
+	}
+	if err := rl.take("sub:alice", rl.cost("eth_chainId")); err == nil {
+		t.Fatal("call accepted with exhausted quota")
+	}
+
+	// The quota is reset at midnight and state is kept until then.
+	clock.run(50 * time.Minute)
+	rl.prune(clock.now())
+	if len(rl.clients) != 1 {
+		t.Fatal("client with used quota was pruned")
+	}
+	clock.run(10 * time.Minute)
+	if err := rl.take("sub:alice", rl.cost("eth_call")); err != nil {
+		t.Fatal("call rejected on the next day:", err)
+	}
+	clock.run(24 * time.Hour)
+	rl.prune(clock.now())
+	if len(rl.clients) != 0 {
+		t.Fatal("idle client not pruned")
+	}
+}
+
+func TestRateLimiterMaxClients(t *testing.T) {
+	clock := newFakeClock("2022-08-01T10:00:00Z")
+	rl := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 2})
+	rl.now = clock.now
+	rl.maxClients = 2
+
+	// Clients beyond the limit share the overflow client.
+	for _, key := range []string{"ip:1.1.1.1", "ip:2.2.2.2", "ip:3.3.3.3", "ip:4.4.4.4"} {
+		if err := rl.take(key, 1); err != nil {
+			t.Fatalf("client %s rejected: %v", key, err)
+		}
+	}
+	if err := rl.take("ip:5.5.5.5", 1); err == nil {
+		t.Fatal("overflow client not limited")
+	}
+	if len(rl.clients) != 3 || rl.clients[rateLimitOverflow] == nil {
+		t.Fatalf("wrong clients: %v", rl.clients)
+	}
+	// Once idle clients are pruned, new clients get their own limits again.
+	clock.run(time.Hour)
+	if err := rl.take("ip:5.5.5.5", 2); err != nil {
+		t.Fatal("client rejected after pruning:", err)
+	}
+	if len(rl.clients) != 1 || rl.clients["ip:5.5.5.5"] == nil {
+		t.Fatalf("wrong clients after pruning: %v", rl.clients)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		peer PeerInfo
+		key  string
+	}{
+		{PeerInfo{RemoteAddr: "1.2.3.4:5678"}, "ip:1.2.3.4"},
+		{PeerInfo{RemoteAddr: "[::1]:5678"}, "ip:::/64"},
+		{PeerInfo{RemoteAddr: "[2001:db8:1:2:aaaa::1]:5678"}, "ip:2001:db8:1:2::/64"},
+		{PeerInfo{RemoteAddr: "[::ffff:1.2.3.4]:5678"}, "ip:::ffff:1.2.3.4"},
+		{PeerInfo{RemoteAddr: "1.2.3.4:5678", AuthSubject: "alice"}, "sub:alice"},
+		{PeerInfo{Transport: "ipc"}, "addr:"},
+	}
+	for _, test := range tests {
+		if key := rateLimitKey(test.peer); key != test.key {
+			t.Errorf("wrong key %q for %+v, want %q", key, test.peer, test.key)
+		}
+	}
+}
+
+// This test checks that clients are limited by JWT subject over HTTP.
+func TestRateLimiterHTTP(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.Use(NewRateLimiter(RateLimitConfig{DailyQuota: 1}))
+
+	auth := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub := r.Header.Get("X-Subject")
+		server.ServeHTTP(w, r.WithContext(WithAuthSubject(r.Context(), sub)))
+	})
+	ts := httptest.NewServer(auth)
+	defer ts.Close()
+
+	call := func(subject string) error {
+		c, err := DialHTTP(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.SetHeader("X-Subject", subject)
+		var info PeerInfo
+		err = c.CallContext(context.Background(), &info, "test_peerInfo")
+		if err == nil && info.AuthSubject != subject {
+			t.Errorf("wrong AuthSubject %q, want %q", info.AuthSubject, subject)
+		}
+		return err
+	}
+	if err := call("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("alice"); err == nil || err.(Error).ErrorCode() != -32005 {
+		t.Fatalf("wrong error for exhausted quota: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// ReconnectConfig configures the reconnecting mode of a client.
+type ReconnectConfig struct {
+	MinBackoff time.Duration // delay before the first resubscription attempt, defaults to 100ms
+	MaxBackoff time.Duration // maximum delay between attempts, defaults to 30s
+}
+
+// Resumer restores the notifications a subscription missed while the client was
+// reconnecting.
+type Resumer interface {
+	// Accept is called with every notification before it is delivered. It returns
+	// false for notifications which have already been delivered.
+	Accept(msg json.RawMessage) bool
+
+	// Resume is called after the subscription has been re-established. It returns the
+	// notifications missed while the connection was down, which are delivered before
+	// any new notifications. If Resume fails, the subscription ends with its error.
+	Resume(ctx context.Context) ([]json.RawMessage, error)
+}
+
+// EnableReconnect switches the client to reconnecting mode. In this mode, subscriptions
+// survive the loss of the connection: the client reconnects with exponential backoff
+// and re-subscribes with the original arguments. Notifications sent while the
+// connection was down are lost, unless the subscription was created with
+// SubscribeWithResumer.
+//
+// EnableReconnect must be called before creating subscriptions. It has no effect on
+// HTTP and in-process clients.
+func (c *Client) EnableReconnect(cfg ReconnectConfig) {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	c.reconnectCfg = &cfg
+}
+
+// ReconnectEnabled reports whether subscriptions of the client are re-established
+// when the connection is lost.
+func (c *Client) ReconnectEnabled() bool {
+	return c.reconnectCfg != nil && c.reconnectFunc != nil
+}
+
+// SubscribeWithResumer works like Subscribe. If the client is in reconnecting mode, the
+// resumer is used to fill the gap in notifications after the subscription was
+// re-established.
+func (c *Client) SubscribeWithResumer(ctx context.Context, namespace string, channel interface{}, resumer Resumer, args ...interface{}) (*ClientSubscription, error) {
+	return c.subscribe(ctx, namespace, channel, resumer, args)
+}
+
+// resubscriber drives a subscription in reconnecting mode. It forwards notifications
+// of the current server subscription and replaces it when it fails.
+type resubscriber struct {
+	client    *Client
+	cfg       ReconnectConfig
+	namespace string
+	args      []interface{}
+	resumer   Resumer
+	sub       *ClientSubscription // subscription handed out to the caller
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+func (c *Client) subscribeReconnecting(ctx context.Context, namespace string, channel reflect.Value, resumer Resumer, args []interface{}) (*ClientSubscription, error) {
+	rs := &resubscriber{
+		client:    c,
+		cfg:       *c.reconnectCfg,
+		namespace: namespace,
+		args:      args,
+		resumer:   resumer,
+		sub:       newClientSubscription(c, namespace, channel),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	inner, raw, err := rs.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rs.sub.resub = rs
+	go rs.sub.run()
+	go rs.loop(inner, raw)
+	return rs.sub, nil
+}
+
+// subscribe creates the server subscription.
+func (rs *resubscriber) subscribe(ctx context.Context) (*ClientSubscription, chan json.RawMessage, error) {
+	raw := make(chan json.RawMessage)
+	inner, err := rs.client.newSubscription(ctx, rs.namespace, reflect.ValueOf(raw), rs.args)
+	return inner, raw, err
+}
+
+// stop ends the loop. It is called when the caller's subscription has ended.
+func (rs *resubscriber) stop() {
+	rs.stopOnce.Do(func() { close(rs.quit) })
+	<-rs.done
+}
+
+func (rs *resubscriber) loop(inner *ClientSubscription, raw chan json.RawMessage) {
+	defer close(rs.done)
+
+	for {
+		select {
+		case msg := <-raw:
+			if rs.resumer != nil && !rs.resumer.Accept(msg) {
+				continue
+			}
+			if !rs.sub.deliver(msg) {
+				inner.Unsubscribe()
+				return
+			}
+
+		case err := <-inner.Err():
+			if err == nil {
+				// The client was closed.
+				rs.sub.close(ErrClientQuit)
+				return
+			}
+			log.Debug("RPC subscription lost, resubscribing", "namespace", rs.namespace, "err", err)
+			if inner, raw, err = rs.resubscribe(); err != nil {
+				if err != errUnsubscribed {
+					rs.sub.close(err)
+				}
+				return
+			}
+
+		case <-rs.quit:
+			inner.Unsubscribe()
+			return
+		}
+	}
+}
+
+// resubscribe re-establishes the server subscription, retrying with exponential
+// backoff until it succeeds or fails with an error returned by the server.
+func (rs *resubscriber) resubscribe() (*ClientSubscription, chan json.RawMessage, error) {
+	backoff := rs.cfg.MinBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-rs.quit:
+			timer.Stop()
+			return nil, nil, errUnsubscribed
+		case <-rs.client.closing:
+			timer.Stop()
+			return nil, nil, ErrClientQuit
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+		inner, raw, err := rs.subscribe(ctx)
+		cancel()
+		if err == nil {
+			if err := rs.resume(); err != nil {
+				inner.Unsubscribe()
+				return nil, nil, err
+			}
+			return inner, raw, nil
+		}
+		if _, ok := err.(Error); ok || err == ErrClientQuit {
+			return nil, nil, err
+		}
+		log.Trace("RPC resubscription failed", "namespace", rs.namespace, "err", err, "backoff", backoff)
+		if backoff *= 2; backoff > rs.cfg.MaxBackoff {
+			backoff = rs.cfg.MaxBackoff
+		}
+	}
+}
+
+// resume delivers the notifications missed while the subscription was down.
+func (rs *resubscriber) resume() error {
+	if rs.resumer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-rs.quit:
+		case <-rs.client.closing:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	msgs, err := rs.resumer.Resume(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if rs.resumer.Accept(msg) && !rs.sub.deliver(msg) {
+			return errUnsubscribed
+		}
+	}
+	return nil
+}
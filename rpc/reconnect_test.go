@@ -0,0 +1,216 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// droppingDialer connects clients to a server through pipes, which can be
+// severed by the test.
+type droppingDialer struct {
+	server *Server
+	mu     sync.Mutex
+	conns  []net.Conn
+}
+
+func (d *droppingDialer) dial(ctx context.Context) (ServerCodec, error) {
+	p1, p2 := net.Pipe()
+	d.mu.Lock()
+	d.conns = append(d.conns, p2)
+	d.mu.Unlock()
+	go d.server.ServeCodec(NewCodec(p2), 0)
+	return NewCodec(p1), nil
+}
+
+// drop closes all connections.
+func (d *droppingDialer) drop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.conns {
+		c.Close()
+	}
+	d.conns = nil
+}
+
+func newReconnectingClient(t *testing.T) (*Client, *droppingDialer) {
+	server := newTestServer()
+	t.Cleanup(server.Stop)
+	d := &droppingDialer{server: server}
+	client, err := newClient(context.Background(), d.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	client.EnableReconnect(ReconnectConfig{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	return client, d
+}
+
+// expectNotifications reads the given values from the channel.
+func expectNotifications(t *testing.T, sub *ClientSubscription, ch <-chan int, want ...int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for _, w := range want {
+		select {
+		case v := <-ch:
+			if v != w {
+				t.Fatalf("wrong notification %d, want %d", v, w)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription ended: %v", err)
+		case <-timeout:
+			t.Fatalf("timeout waiting for notification %d", w)
+		}
+	}
+}
+
+func TestClientReconnectResubscribe(t *testing.T) {
+	client, dialer := newReconnectingClient(t)
+
+	ch := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", ch, "someSubscription", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNotifications(t, sub, ch, 1, 2)
+
+	// The subscription is re-established with the same arguments.
+	dialer.drop()
+	expectNotifications(t, sub, ch, 1, 2)
+	dialer.drop()
+	expectNotifications(t, sub, ch, 1, 2)
+
+	sub.Unsubscribe()
+	if _, ok := <-sub.Err(); ok {
+		t.Fatal("error channel not closed after unsubscribe")
+	}
+}
+
+func TestClientReconnectClose(t *testing.T) {
+	client, dialer := newReconnectingClient(t)
+
+	ch := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", ch, "someSubscription", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNotifications(t, sub, ch, 1)
+	dialer.drop()
+	client.Close()
+
+	select {
+	case err := <-sub.Err():
+		if err != nil {
+			t.Fatalf("wrong error after close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not ended by Close")
+	}
+}
+
+// intResumer delivers increasing integers. It drops values which have already been
+// delivered and backfills the values in missing.
+type intResumer struct {
+	mu      sync.Mutex
+	last    int
+	missing []int
+	err     error
+}
+
+func (r *intResumer) Accept(msg json.RawMessage) bool {
+	v, err := strconv.Atoi(string(msg))
+	if err != nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v <= r.last {
+		return false
+	}
+	r.last = v
+	return true
+}
+
+func (r *intResumer) Resume(ctx context.Context) ([]json.RawMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	var msgs []json.RawMessage
+	for _, v := range r.missing {
+		msgs = append(msgs, json.RawMessage(strconv.Itoa(v)))
+	}
+	return msgs, nil
+}
+
+func TestClientReconnectResume(t *testing.T) {
+	client, dialer := newReconnectingClient(t)
+
+	var (
+		ch      = make(chan int, 10)
+		resumer = &intResumer{missing: []int{4, 5}}
+	)
+	sub, err := client.SubscribeWithResumer(context.Background(), "nftest", ch, resumer, "someSubscription", 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	expectNotifications(t, sub, ch, 1, 2, 3)
+
+	// After reconnecting, the backfilled values are delivered and the repeated
+	// notifications of the new server subscription are dropped.
+	dialer.drop()
+	expectNotifications(t, sub, ch, 4, 5)
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected notification %d", v)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClientReconnectResumeError(t *testing.T) {
+	client, dialer := newReconnectingClient(t)
+
+	var (
+		ch      = make(chan int)
+		resumer = &intResumer{err: errors.New("gap")}
+	)
+	sub, err := client.SubscribeWithResumer(context.Background(), "nftest", ch, resumer, "someSubscription", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNotifications(t, sub, ch, 1)
+
+	dialer.drop()
+	select {
+	case err := <-sub.Err():
+		if !reflect.DeepEqual(err, resumer.err) {
+			t.Fatalf("wrong error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not ended by resume error")
+	}
+}
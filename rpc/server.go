@@ -67,6 +67,19 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetBatchLimits sets limits applied to batch requests. There are two limits: 'itemLimit'
+// is the maximum number of items in a batch. 'maxResponseSize' is the maximum number of
+// response bytes across all requests in a batch. Zero disables a limit.
+//
+// Every call of a batch exceeding the item limit is answered with an error, and none
+// of them is executed. Once the responses exceed the size limit, the remaining calls
+// of the batch are answered with an error instead of being executed.
+func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
+	s.services.mu.Lock()
+	defer s.services.mu.Unlock()
+	s.services.batchLimits = batchLimits{itemLimit: itemLimit, responseMaxSize: maxResponseSize}
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -169,9 +182,27 @@ type PeerInfo struct {
 		Origin    string
 		Host      string
 	}
+
+	// Subject of the JWT token the client authenticated with, if any.
+	AuthSubject string
 }
 
-type peerInfoContextKey struct{}
+type (
+	peerInfoContextKey    struct{}
+	authSubjectContextKey struct{}
+)
+
+// WithAuthSubject returns a copy of ctx carrying the authenticated identity of the
+// client. HTTP middlewares performing authentication use this on the request context
+// to make the subject available as PeerInfo.AuthSubject.
+func WithAuthSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, authSubjectContextKey{}, subject)
+}
+
+func authSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(authSubjectContextKey{}).(string)
+	return subject
+}
 
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
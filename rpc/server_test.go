@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"io"
 	"net"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -70,14 +71,23 @@ func TestServer(t *testing.T) {
 
 func runTestScript(t *testing.T, file string) {
 	server := newTestServer()
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewCodec(serverConn), 0)
+	runTestScriptOn(t, file, clientConn)
+}
+
+// runTestScriptOn runs a test script against the server at the other end of
+// clientConn. Read and write deadlines are set if the connection supports them.
+func runTestScriptOn(t *testing.T, file string, clientConn io.ReadWriter) {
 	content, err := os.ReadFile(file)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	clientConn, serverConn := net.Pipe()
-	defer clientConn.Close()
-	go server.ServeCodec(NewCodec(serverConn), 0)
+	deadlines, _ := clientConn.(interface {
+		SetReadDeadline(time.Time) error
+		SetWriteDeadline(time.Time) error
+	})
 	readbuf := bufio.NewReader(clientConn)
 	for _, line := range strings.Split(string(content), "\n") {
 		line = strings.TrimSpace(line)
@@ -88,7 +98,9 @@ func runTestScript(t *testing.T, file string) {
 		case strings.HasPrefix(line, "--> "):
 			t.Log(line)
 			// write to connection
-			clientConn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if deadlines != nil {
+				deadlines.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			}
 			if _, err := io.WriteString(clientConn, line[4:]+"\n"); err != nil {
 				t.Fatalf("write error: %v", err)
 			}
@@ -96,7 +108,9 @@ func runTestScript(t *testing.T, file string) {
 			t.Log(line)
 			want := line[4:]
 			// read line from connection and compare text
-			clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if deadlines != nil {
+				deadlines.SetReadDeadline(time.Now().Add(5 * time.Second))
+			}
 			sent, err := readbuf.ReadString('\n')
 			if err != nil {
 				t.Fatalf("read error: %v", err)
@@ -152,3 +166,58 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetBatchLimits(5, 60)
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	clients := map[string]func() (*Client, error){
+		"inproc": func() (*Client, error) { return DialInProc(server), nil },
+		"http":   func() (*Client, error) { return DialHTTP(httpsrv.URL) },
+	}
+	makeBatch := func(n int) []BatchElem {
+		batch := make([]BatchElem, n)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, nil}, Result: new(echoResult)}
+		}
+		return batch
+	}
+	for name, dial := range clients {
+		t.Run(name, func(t *testing.T) {
+			client, err := dial()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			// Batches with too many items are rejected entirely.
+			batch := makeBatch(6)
+			if err := client.BatchCall(batch); err != nil {
+				t.Fatal(err)
+			}
+			for i, elem := range batch {
+				if re, ok := elem.Error.(Error); !ok || re.ErrorCode() != -32600 || re.Error() != errMsgBatchTooLarge {
+					t.Errorf("item %d: wrong error %v", i, elem.Error)
+				}
+			}
+
+			// Calls are not executed after the response size limit is exceeded.
+			batch = makeBatch(5)
+			if err := client.BatchCall(batch); err != nil {
+				t.Fatal(err)
+			}
+			for i, elem := range batch {
+				if i < 2 {
+					if elem.Error != nil || elem.Result.(*echoResult).Int != i {
+						t.Errorf("item %d: wrong result %v, error %v", i, elem.Result, elem.Error)
+					}
+				} else if re, ok := elem.Error.(Error); !ok || re.ErrorCode() != -32003 || re.Error() != errMsgResponseTooLarge {
+					t.Errorf("item %d: wrong error %v", i, elem.Error)
+				}
+			}
+		})
+	}
+}
@@ -37,8 +37,16 @@ var (
 )
 
 type serviceRegistry struct {
-	mu       sync.Mutex
-	services map[string]service
+	mu          sync.Mutex
+	services    map[string]service
+	middlewares []Middleware
+	batchLimits batchLimits
+}
+
+// batchLimits bounds the resources used by a batch request.
+type batchLimits struct {
+	itemLimit       int // maximum number of requests in a batch
+	responseMaxSize int // maximum total size of the responses to a batch
 }
 
 // service represents a registered object.
@@ -94,6 +102,9 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 
 // callback returns the callback corresponding to the given RPC method name.
 func (r *serviceRegistry) callback(method string) *callback {
+	if method == discoverMethod {
+		method = MetadataApi + serviceMethodSeparator + "discover"
+	}
 	elem := strings.SplitN(method, serviceMethodSeparator, 2)
 	if len(elem) != 2 {
 		return nil
@@ -103,6 +114,20 @@ func (r *serviceRegistry) callback(method string) *callback {
 	return r.services[elem[0]].callbacks[elem[1]]
 }
 
+// middlewareList returns the middlewares installed on the server.
+func (r *serviceRegistry) middlewareList() []Middleware {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.middlewares
+}
+
+// limits returns the batch limits of the server.
+func (r *serviceRegistry) limits() batchLimits {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batchLimits
+}
+
 // subscription returns a subscription callback in the given service.
 func (r *serviceRegistry) subscription(service, name string) *callback {
 	r.mu.Lock()
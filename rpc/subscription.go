@@ -209,6 +209,10 @@ type ClientSubscription struct {
 	namespace string
 	subid     string
 
+	// Set in reconnecting mode, where the server subscription is managed by the
+	// resubscriber.
+	resub *resubscriber
+
 	// The in channel receives notification values from client dispatcher.
 	in chan json.RawMessage
 
@@ -370,6 +374,10 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 }
 
 func (sub *ClientSubscription) requestUnsubscribe() error {
+	if sub.resub != nil {
+		sub.resub.stop()
+		return nil
+	}
 	var result interface{}
 	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.subid)
 }
@@ -314,6 +314,32 @@ func (s *Sync) Pending() int {
 	return len(s.nodeReqs) + len(s.codeReqs)
 }
 
+// Frontier returns the number of trie nodes which are not yet retrieved, grouped
+// by their depth. Nodes of the account trie and of storage tries are counted
+// separately, the depth of storage trie nodes is relative to their storage root.
+// Nodes already retrieved but still waiting for their children are not included.
+func (s *Sync) Frontier() (accounts []int, storage []int) {
+	for _, req := range s.nodeReqs {
+		if req.data != nil {
+			continue
+		}
+		// Storage trie paths are prefixed with the account hash, see NewSyncPath.
+		if depth := len(req.path); depth < 64 {
+			for len(accounts) <= depth {
+				accounts = append(accounts, 0)
+			}
+			accounts[depth]++
+		} else {
+			depth -= 64
+			for len(storage) <= depth {
+				storage = append(storage, 0)
+			}
+			storage[depth]++
+		}
+	}
+	return accounts, storage
+}
+
 // schedule inserts a new state retrieval request into the fetch queue. If there
 // is already a pending request for this node, the new request will be discarded
 // and only a parent reference added to the old one.
@@ -18,6 +18,7 @@ package trie
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -476,3 +477,49 @@ func TestSyncOrdering(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the sync frontier reports the pending trie nodes by depth.
+func TestSyncFrontier(t *testing.T) {
+	// The test trie is rather large, use a smaller one with a full root node.
+	srcDb := NewDatabase(memorydb.New())
+	srcTrie, _ := NewSecure(common.Hash{}, common.Hash{}, srcDb)
+	for i := byte(0); i < 64; i++ {
+		srcTrie.Update([]byte{i}, []byte{i})
+	}
+	srcTrie.Commit(nil)
+
+	diskdb := memorydb.New()
+	sched := NewSync(srcTrie.Hash(), diskdb, nil)
+
+	// A storage trie hanging off an account leaf is counted separately.
+	storageTrie := NewEmpty(NewDatabase(memorydb.New()))
+	storageTrie.Update([]byte{0x01}, []byte{0x01})
+	sched.AddSubTrie(storageTrie.Hash(), make([]byte, 64), common.Hash{}, nil)
+
+	accounts, storage := sched.Frontier()
+	if !reflect.DeepEqual(accounts, []int{1}) || !reflect.DeepEqual(storage, []int{1}) {
+		t.Fatalf("wrong initial frontier: accounts %v, storage %v", accounts, storage)
+	}
+	// Retrieve the account trie root, its children become the frontier.
+	nodes, _, _ := sched.Missing(0)
+	for _, hash := range nodes {
+		if hash != srcTrie.Hash() {
+			continue
+		}
+		data, err := srcDb.Node(hash)
+		if err != nil {
+			t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+		}
+		if err := sched.Process(SyncResult{hash, data}); err != nil {
+			t.Fatalf("failed to process result %v", err)
+		}
+	}
+	children, _, _ := sched.Missing(0)
+	accounts, storage = sched.Frontier()
+	if len(accounts) != 2 || accounts[0] != 0 || accounts[1] != len(children) || len(children) == 0 {
+		t.Fatalf("wrong frontier after root: accounts %v, %d children", accounts, len(children))
+	}
+	if !reflect.DeepEqual(storage, []int{1}) {
+		t.Fatalf("wrong storage frontier after root: %v", storage)
+	}
+}